@@ -0,0 +1,326 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/go-openapi/runtime"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/clients/webhook/client/models"
+	"github.com/forta-network/forta-core-go/clients/webhook/client/operations"
+)
+
+// Default delivery settings
+const (
+	DefaultDeliveryInitialInterval = time.Second
+	DefaultDeliveryMaxInterval     = time.Second * 30
+	DefaultDeliveryMaxElapsedTime  = time.Minute * 2
+)
+
+const (
+	deadLetterExt   = ".json"
+	rejectedDirName = "rejected"
+)
+
+// DeliveryConfig contains the webhook delivery settings for a destination.
+type DeliveryConfig struct {
+	Destination   string
+	Authorization string
+	// SigningSecret enables the HMAC-SHA256 signature in SignatureHeader.
+	SigningSecret []byte
+
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	MaxElapsedTime  time.Duration
+
+	// DeadLetterDir is where the batches which could not be delivered are kept for replay.
+	// The batches which are rejected by the destination are kept in the "rejected" dir under it
+	// and they are never replayed. The failed batches are dropped if it's not set.
+	DeadLetterDir string
+}
+
+type deadLetter struct {
+	Destination string             `json:"destination"`
+	FailedAt    time.Time          `json:"failedAt"`
+	Error       string             `json:"error"`
+	Batch       *models.AlertBatch `json:"batch"`
+}
+
+// deliverer sends the alert batches to a webhook destination by retrying the
+// failures which can be temporary.
+type deliverer struct {
+	cfg    DeliveryConfig
+	name   string
+	client AlertWebhookClient
+
+	lastSuccess health.TimeTracker
+	lastErr     health.ErrorTracker
+	deadLetters health.NumberTracker
+	countMu     sync.Mutex
+	replayMu    sync.Mutex
+}
+
+// NewDeliverer creates a new deliverer.
+func NewDeliverer(cfg DeliveryConfig) (*deliverer, error) {
+	u, err := url.Parse(cfg.Destination)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook url: %v", err)
+	}
+	if cfg.InitialInterval <= 0 {
+		cfg.InitialInterval = DefaultDeliveryInitialInterval
+	}
+	if cfg.MaxInterval <= 0 {
+		cfg.MaxInterval = DefaultDeliveryMaxInterval
+	}
+	if cfg.MaxElapsedTime <= 0 {
+		cfg.MaxElapsedTime = DefaultDeliveryMaxElapsedTime
+	}
+
+	var rt http.RoundTripper
+	if len(cfg.SigningSecret) > 0 {
+		rt = &signingTransport{secret: cfg.SigningSecret, next: http.DefaultTransport}
+	}
+	client, err := newAlertWebhookClient(cfg.Destination, rt)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &deliverer{
+		cfg:    cfg,
+		name:   u.Host,
+		client: client,
+	}
+	if len(cfg.DeadLetterDir) > 0 {
+		if err := os.MkdirAll(cfg.DeadLetterDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create dead letter dir: %v", err)
+		}
+		files, err := d.deadLetterFiles()
+		if err != nil {
+			return nil, err
+		}
+		d.deadLetters.Set(float64(len(files)))
+	}
+	return d, nil
+}
+
+// Name returns the name of this implementation.
+func (d *deliverer) Name() string {
+	return "webhook-delivery"
+}
+
+// Health implements the health.Reporter interface.
+func (d *deliverer) Health() health.Reports {
+	return health.Reports{
+		d.lastSuccess.GetReport(fmt.Sprintf("%s.delivery.time", d.name)),
+		d.lastErr.GetReport(fmt.Sprintf("%s.delivery.error", d.name)),
+		d.deadLetters.GetReport(fmt.Sprintf("%s.dead-letters", d.name)),
+	}
+}
+
+// Deliver sends the batch with retries and puts it to the dead letter spool if it fails for good.
+// The batch is put aside as rejected instead if the destination refused it.
+func (d *deliverer) Deliver(ctx context.Context, batch *models.AlertBatch) error {
+	err := d.send(ctx, batch)
+	if err == nil {
+		return nil
+	}
+	if len(d.cfg.DeadLetterDir) == 0 || ctx.Err() != nil {
+		return err
+	}
+	dir := d.cfg.DeadLetterDir
+	if isPermanentDeliveryErr(err) {
+		dir = d.rejectedDir()
+	}
+	if spoolErr := d.spool(dir, batch, err); spoolErr != nil {
+		log.WithError(spoolErr).WithField("destination", d.name).Error("failed to spool dead letter")
+	}
+	return err
+}
+
+func (d *deliverer) send(ctx context.Context, batch *models.AlertBatch) error {
+	params := operations.NewSendAlertsParamsWithContext(ctx).WithPayload(batch)
+	if len(d.cfg.Authorization) > 0 {
+		params.SetAuthorization(&d.cfg.Authorization)
+	}
+
+	bo := backoff.NewExponentialBackOff()
+	bo.InitialInterval = d.cfg.InitialInterval
+	bo.MaxInterval = d.cfg.MaxInterval
+	bo.MaxElapsedTime = d.cfg.MaxElapsedTime
+	err := backoff.Retry(func() error {
+		_, err := d.client.SendAlerts(params)
+		if err != nil && isPermanentDeliveryErr(err) {
+			return backoff.Permanent(err)
+		}
+		if err != nil {
+			log.WithError(err).WithField("destination", d.name).Warn("failed to deliver alerts - retrying")
+		}
+		return err
+	}, backoff.WithContext(bo, ctx))
+	if err != nil {
+		err = fmt.Errorf("failed to deliver alerts to %s: %w", d.name, err)
+		d.lastErr.Set(err)
+		return err
+	}
+	d.lastSuccess.Set()
+	d.lastErr.Set(nil)
+	return nil
+}
+
+// isPermanentDeliveryErr tells if the destination rejected the request so that
+// sending it again can't help.
+func isPermanentDeliveryErr(err error) bool {
+	var badRequest *operations.SendAlertsBadRequest
+	if errors.As(err, &badRequest) {
+		return true
+	}
+	var apiErr *runtime.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code < http.StatusInternalServerError && apiErr.Code != http.StatusTooManyRequests
+	}
+	return false
+}
+
+func (d *deliverer) rejectedDir() string {
+	return filepath.Join(d.cfg.DeadLetterDir, rejectedDirName)
+}
+
+func (d *deliverer) spool(dir string, batch *models.AlertBatch, deliveryErr error) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(&deadLetter{
+		Destination: d.cfg.Destination,
+		FailedAt:    time.Now().UTC(),
+		Error:       deliveryErr.Error(),
+		Batch:       batch,
+	})
+	if err != nil {
+		return err
+	}
+	// write to a temp file first so that the replay never sees a partial file
+	tmpFile, err := os.CreateTemp(dir, "*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(b); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	// sorting the names gives the failure order
+	name := fmt.Sprintf("%020d-%s", time.Now().UnixNano(), strings.TrimSuffix(filepath.Base(tmpFile.Name()), ".tmp"))
+	if err := os.Rename(tmpFile.Name(), filepath.Join(dir, name+deadLetterExt)); err != nil {
+		return err
+	}
+	return d.countDeadLetters()
+}
+
+// reject moves the dead letter to the rejected dir so that it is not replayed again.
+func (d *deliverer) reject(file string) error {
+	dir := d.rejectedDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(file, filepath.Join(dir, filepath.Base(file))); err != nil {
+		return fmt.Errorf("failed to move rejected dead letter: %v", err)
+	}
+	return nil
+}
+
+func (d *deliverer) deadLetterFiles() ([]string, error) {
+	entries, err := os.ReadDir(d.cfg.DeadLetterDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter dir: %v", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), deadLetterExt) {
+			files = append(files, filepath.Join(d.cfg.DeadLetterDir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (d *deliverer) countDeadLetters() error {
+	d.countMu.Lock()
+	defer d.countMu.Unlock()
+
+	files, err := d.deadLetterFiles()
+	if err != nil {
+		return err
+	}
+	d.deadLetters.Set(float64(len(files)))
+	return nil
+}
+
+// ReplayDeadLetters sends the spooled batches again in the failure order and removes the
+// delivered ones. The batches which are rejected by the destination or can not be decoded
+// are moved to the rejected dir. It stops at the first temporary failure and returns
+// the number of delivered batches.
+func (d *deliverer) ReplayDeadLetters(ctx context.Context) (int, error) {
+	if len(d.cfg.DeadLetterDir) == 0 {
+		return 0, nil
+	}
+	// the new dead letters can still be spooled while replaying
+	d.replayMu.Lock()
+	defer d.replayMu.Unlock()
+
+	files, err := d.deadLetterFiles()
+	if err != nil {
+		return 0, err
+	}
+	defer d.countDeadLetters()
+
+	var replayed int
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return replayed, fmt.Errorf("failed to read dead letter: %v", err)
+		}
+		logger := log.WithFields(log.Fields{
+			"destination": d.name,
+			"deadLetter":  filepath.Base(file),
+		})
+		var dl deadLetter
+		if err := json.Unmarshal(b, &dl); err != nil {
+			logger.WithError(err).Error("failed to decode dead letter - rejecting")
+			if err := d.reject(file); err != nil {
+				return replayed, err
+			}
+			continue
+		}
+		if err := d.send(ctx, dl.Batch); err != nil {
+			if !isPermanentDeliveryErr(err) {
+				return replayed, err
+			}
+			logger.WithError(err).Error("dead letter is rejected by the destination")
+			if err := d.reject(file); err != nil {
+				return replayed, err
+			}
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			return replayed, fmt.Errorf("failed to remove dead letter: %v", err)
+		}
+		replayed++
+	}
+	return replayed, nil
+}
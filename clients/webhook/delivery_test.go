@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/clients/webhook/client/models"
+)
+
+var testSigningSecret = []byte("test-secret")
+
+func TestSignature(t *testing.T) {
+	r := require.New(t)
+
+	body := []byte(`{"alerts":[]}`)
+	header := SignBody(testSigningSecret, time.Now(), body)
+	r.NoError(VerifySignature(testSigningSecret, header, body, time.Minute))
+	r.ErrorIs(VerifySignature([]byte("other-secret"), header, body, time.Minute), ErrInvalidSignature)
+	r.ErrorIs(VerifySignature(testSigningSecret, header, []byte(`{}`), time.Minute), ErrInvalidSignature)
+
+	oldHeader := SignBody(testSigningSecret, time.Now().Add(-time.Hour), body)
+	r.ErrorIs(VerifySignature(testSigningSecret, oldHeader, body, time.Minute), ErrInvalidSignature)
+}
+
+// testDestination fails with the status code until the number of failures is reached.
+func testDestination(failures int32, status int, alerts *int32) *httptest.Server {
+	receiver := NewReceiver(ReceiverConfig{
+		Authorizer:    NewStaticTokenAuthorizer(testReceiverToken),
+		SigningSecret: testSigningSecret,
+		AlertHandler: func(ctx context.Context, alert *models.Alert) error {
+			atomic.AddInt32(alerts, 1)
+			return nil
+		},
+	})
+	var calls int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		receiver.ServeHTTP(w, r)
+	}))
+}
+
+func testDeliveryConfig(dest string) DeliveryConfig {
+	return DeliveryConfig{
+		Destination:     dest + AlertsPath,
+		Authorization:   testReceiverToken,
+		SigningSecret:   testSigningSecret,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     time.Millisecond * 10,
+		MaxElapsedTime:  time.Second * 5,
+	}
+}
+
+func testBatch() *models.AlertBatch {
+	return &models.AlertBatch{Alerts: models.AlertList{{Hash: "0x1"}}}
+}
+
+func TestDeliverer_RetryServerErrors(t *testing.T) {
+	r := require.New(t)
+
+	var alerts int32
+	server := testDestination(2, http.StatusBadGateway, &alerts)
+	defer server.Close()
+
+	d, err := NewDeliverer(testDeliveryConfig(server.URL))
+	r.NoError(err)
+	r.NoError(d.Deliver(context.Background(), testBatch()))
+	r.Equal(int32(1), alerts)
+
+	reports := d.Health()
+	report, ok := reports.NameContains("delivery.time")
+	r.True(ok)
+	r.Equal(health.StatusOK, report.Status)
+	report, ok = reports.NameContains("delivery.error")
+	r.True(ok)
+	r.Equal(health.StatusOK, report.Status)
+}
+
+func TestDeliverer_DeadLetters(t *testing.T) {
+	r := require.New(t)
+
+	var alerts int32
+	server := testDestination(1000, http.StatusServiceUnavailable, &alerts)
+	defer server.Close()
+
+	cfg := testDeliveryConfig(server.URL)
+	cfg.DeadLetterDir = t.TempDir()
+	cfg.MaxElapsedTime = time.Millisecond * 50
+	d, err := NewDeliverer(cfg)
+	r.NoError(err)
+
+	r.Error(d.Deliver(context.Background(), testBatch()))
+	r.Equal(int32(0), alerts)
+	report, ok := d.Health().NameContains("delivery.error")
+	r.True(ok)
+	r.Equal(health.StatusFailing, report.Status)
+	report, ok = d.Health().NameContains("dead-letters")
+	r.True(ok)
+	r.Equal("1", report.Details)
+
+	// the spool is kept across restarts
+	server.Close()
+	server = testDestination(0, 0, &alerts)
+	defer server.Close()
+	cfg.Destination = server.URL + AlertsPath
+	d, err = NewDeliverer(cfg)
+	r.NoError(err)
+	replayed, err := d.ReplayDeadLetters(context.Background())
+	r.NoError(err)
+	r.Equal(1, replayed)
+	r.Equal(int32(1), alerts)
+
+	entries, err := os.ReadDir(cfg.DeadLetterDir)
+	r.NoError(err)
+	r.Empty(entries)
+	report, ok = d.Health().NameContains("dead-letters")
+	r.True(ok)
+	r.Equal("0", report.Details)
+}
+
+func TestDeliverer_Rejected(t *testing.T) {
+	r := require.New(t)
+
+	var alerts int32
+	server := testDestination(1, http.StatusBadRequest, &alerts)
+	defer server.Close()
+
+	cfg := testDeliveryConfig(server.URL)
+	cfg.DeadLetterDir = t.TempDir()
+	d, err := NewDeliverer(cfg)
+	r.NoError(err)
+
+	// not retried or replayed on bad request
+	r.Error(d.Deliver(context.Background(), testBatch()))
+	r.Equal(int32(0), alerts)
+	report, ok := d.Health().NameContains("dead-letters")
+	r.True(ok)
+	r.Equal("0", report.Details)
+	rejected, err := os.ReadDir(filepath.Join(cfg.DeadLetterDir, rejectedDirName))
+	r.NoError(err)
+	r.Len(rejected, 1)
+}
+
+func TestDeliverer_ReplaySkipsRejected(t *testing.T) {
+	r := require.New(t)
+
+	var alerts int32
+	server := testDestination(1, http.StatusBadRequest, &alerts)
+	defer server.Close()
+
+	cfg := testDeliveryConfig(server.URL)
+	cfg.DeadLetterDir = t.TempDir()
+	d, err := NewDeliverer(cfg)
+	r.NoError(err)
+	for i := 0; i < 2; i++ {
+		r.NoError(d.spool(cfg.DeadLetterDir, testBatch(), errors.New("test error")))
+	}
+	r.NoError(os.WriteFile(filepath.Join(cfg.DeadLetterDir, "corrupt"+deadLetterExt), []byte("{"), 0644))
+
+	// the first one is rejected and the corrupt one can't be decoded
+	replayed, err := d.ReplayDeadLetters(context.Background())
+	r.NoError(err)
+	r.Equal(1, replayed)
+	r.Equal(int32(1), alerts)
+
+	files, err := d.deadLetterFiles()
+	r.NoError(err)
+	r.Empty(files)
+	rejected, err := os.ReadDir(filepath.Join(cfg.DeadLetterDir, rejectedDirName))
+	r.NoError(err)
+	r.Len(rejected, 2)
+	report, ok := d.Health().NameContains("dead-letters")
+	r.True(ok)
+	r.Equal("0", report.Details)
+}
@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-core-go/clients/webhook/client/models"
+	"github.com/forta-network/forta-core-go/clients/webhook/client/operations"
+	"github.com/forta-network/forta-core-go/security"
+	"github.com/forta-network/forta-core-go/utils/apiutils"
+)
+
+// AlertsPath is the path of the alerts endpoint in the webhook specification.
+const AlertsPath = "/forta/v1/alerts"
+
+const defaultMaxBodySize = 10 << 20
+
+// ErrUnauthorized is returned by the authorizers when the request is not authorized.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Authorizer checks the Authorization header of the webhook requests and can add
+// the authorized identity to the context.
+type Authorizer interface {
+	Authorize(ctx context.Context, authorization string) (context.Context, error)
+}
+
+// AuthorizerFunc is a func which implements the Authorizer interface.
+type AuthorizerFunc func(ctx context.Context, authorization string) (context.Context, error)
+
+// Authorize implements the Authorizer interface.
+func (f AuthorizerFunc) Authorize(ctx context.Context, authorization string) (context.Context, error) {
+	return f(ctx, authorization)
+}
+
+// NewStaticTokenAuthorizer creates an authorizer which expects the Authorization header
+// to be the same as the token.
+func NewStaticTokenAuthorizer(token string) Authorizer {
+	return AuthorizerFunc(func(ctx context.Context, authorization string) (context.Context, error) {
+		if subtle.ConstantTimeCompare([]byte(authorization), []byte(token)) != 1 {
+			return nil, ErrUnauthorized
+		}
+		return ctx, nil
+	})
+}
+
+// NewScannerJWTAuthorizer creates an authorizer which expects a scanner JWT as
+// "Bearer <token>" and puts the scanner address into the context by using apiutils.SetAddress.
+func NewScannerJWTAuthorizer(opts security.ScannerJWTVerifyOptions) Authorizer {
+	return AuthorizerFunc(func(ctx context.Context, authorization string) (context.Context, error) {
+		parts := strings.SplitN(authorization, " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+			return nil, ErrUnauthorized
+		}
+		scannerToken, err := security.VerifyScannerJWTWithOptions(strings.TrimSpace(parts[1]), opts)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+		}
+		return apiutils.SetAddress(ctx, scannerToken.Scanner), nil
+	})
+}
+
+// ReceiverConfig contains the webhook receiver settings.
+type ReceiverConfig struct {
+	// Authorizer is optional and all requests are accepted without it.
+	Authorizer Authorizer
+	// AlertHandler receives the alerts in the batch order.
+	AlertHandler func(ctx context.Context, alert *models.Alert) error
+	// MetricsHandler receives the bot metrics after the alerts.
+	MetricsHandler func(ctx context.Context, metrics *models.BotMetric) error
+	// SigningSecret enables checking the signature in SignatureHeader.
+	SigningSecret []byte
+	// SignatureTolerance is the max age of the signature timestamps.
+	SignatureTolerance time.Duration
+	// Path is where the batches are received. The default is AlertsPath.
+	Path string
+	// MaxBodySize is the max size of a request body in bytes.
+	MaxBodySize int64
+}
+
+type receiver struct {
+	cfg ReceiverConfig
+}
+
+// NewReceiver creates a handler which receives the alert batches.
+func NewReceiver(cfg ReceiverConfig) *receiver {
+	if len(cfg.Path) == 0 {
+		cfg.Path = AlertsPath
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = defaultMaxBodySize
+	}
+	if cfg.SignatureTolerance <= 0 {
+		cfg.SignatureTolerance = defaultSignatureTolerance
+	}
+	return &receiver{cfg: cfg}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// the webhook client can add a trailing slash
+	if r.URL.Path != rc.cfg.Path && r.URL.Path != rc.cfg.Path+"/" {
+		apiutils.NotFound(w, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	if rc.cfg.Authorizer != nil {
+		authCtx, err := rc.cfg.Authorizer.Authorize(ctx, r.Header.Get("Authorization"))
+		if err != nil {
+			log.WithError(err).Debug("webhook request is not authorized")
+			apiutils.Unauthorized(w, "unauthorized")
+			return
+		}
+		ctx = authCtx
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, rc.cfg.MaxBodySize))
+	if err != nil {
+		badRequest(w, fmt.Sprintf("could not read body: %v", err))
+		return
+	}
+	if len(rc.cfg.SigningSecret) > 0 {
+		err := VerifySignature(rc.cfg.SigningSecret, r.Header.Get(SignatureHeader), body, rc.cfg.SignatureTolerance)
+		if err != nil {
+			log.WithError(err).Debug("webhook request signature is not valid")
+			apiutils.Unauthorized(w, "invalid signature")
+			return
+		}
+	}
+
+	var batch models.AlertBatch
+	if err := json.Unmarshal(body, &batch); err != nil {
+		badRequest(w, fmt.Sprintf("could not parse body: %v", err))
+		return
+	}
+	if err := batch.Validate(strfmt.Default); err != nil {
+		badRequest(w, fmt.Sprintf("invalid alert batch: %v", err))
+		return
+	}
+
+	if err := rc.handleBatch(ctx, &batch); err != nil {
+		log.WithError(err).Error("failed to handle alert batch")
+		apiutils.InternalError(w, "failed to handle alert batch")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (rc *receiver) handleBatch(ctx context.Context, batch *models.AlertBatch) error {
+	if rc.cfg.AlertHandler != nil {
+		for _, alert := range batch.Alerts {
+			if alert == nil {
+				continue
+			}
+			if err := rc.cfg.AlertHandler(ctx, alert); err != nil {
+				return fmt.Errorf("failed to handle alert %s: %v", alert.Hash, err)
+			}
+		}
+	}
+	if rc.cfg.MetricsHandler != nil {
+		for _, metrics := range batch.Metrics {
+			if metrics == nil {
+				continue
+			}
+			if err := rc.cfg.MetricsHandler(ctx, metrics); err != nil {
+				return fmt.Errorf("failed to handle metrics of bot %s: %v", metrics.BotID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// badRequest writes the bad request response in the specification.
+func badRequest(w http.ResponseWriter, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	err := json.NewEncoder(w).Encode(&operations.SendAlertsBadRequestBody{Reason: reason})
+	if err != nil {
+		log.WithError(err).Error("could not write error to response")
+	}
+}
@@ -0,0 +1,159 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-core-go/clients/webhook/client/models"
+	"github.com/forta-network/forta-core-go/clients/webhook/client/operations"
+	"github.com/forta-network/forta-core-go/security"
+	"github.com/forta-network/forta-core-go/utils/apiutils"
+)
+
+const testReceiverToken = "test-token"
+
+func TestReceiver_SendAlerts(t *testing.T) {
+	r := require.New(t)
+
+	var (
+		alerts  []*models.Alert
+		metrics []*models.BotMetric
+	)
+	server := httptest.NewServer(NewReceiver(ReceiverConfig{
+		Authorizer: NewStaticTokenAuthorizer(testReceiverToken),
+		AlertHandler: func(ctx context.Context, alert *models.Alert) error {
+			alerts = append(alerts, alert)
+			return nil
+		},
+		MetricsHandler: func(ctx context.Context, m *models.BotMetric) error {
+			metrics = append(metrics, m)
+			return nil
+		},
+	}))
+	defer server.Close()
+
+	client, err := NewAlertWebhookClient(server.URL + AlertsPath)
+	r.NoError(err)
+
+	token := testReceiverToken
+	_, err = client.SendAlerts(&operations.SendAlertsParams{
+		Authorization: &token,
+		Payload: &models.AlertBatch{
+			Alerts: models.AlertList{
+				{Hash: "0x1", Severity: models.AlertSeverityHIGH},
+				{Hash: "0x2", Severity: models.AlertSeverityLOW},
+			},
+			Metrics: models.BotMetricsList{
+				{BotID: "0xbot"},
+			},
+		},
+		Context: context.Background(),
+	})
+	r.NoError(err)
+
+	r.Len(alerts, 2)
+	r.Equal("0x1", alerts[0].Hash)
+	r.Equal("0x2", alerts[1].Hash)
+	r.Len(metrics, 1)
+	r.Equal("0xbot", metrics[0].BotID)
+}
+
+func TestReceiver_Errors(t *testing.T) {
+	handlerErr := false
+	receiver := NewReceiver(ReceiverConfig{
+		Authorizer: NewStaticTokenAuthorizer(testReceiverToken),
+		AlertHandler: func(ctx context.Context, alert *models.Alert) error {
+			if handlerErr {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	})
+
+	for _, testCase := range []struct {
+		name          string
+		path          string
+		authorization string
+		body          string
+		handlerErr    bool
+		code          int
+	}{
+		{
+			name:          "unknown path",
+			path:          "/alerts",
+			authorization: testReceiverToken,
+			body:          `{}`,
+			code:          http.StatusNotFound,
+		},
+		{
+			name:          "wrong token",
+			path:          AlertsPath,
+			authorization: "wrong",
+			body:          `{}`,
+			code:          http.StatusUnauthorized,
+		},
+		{
+			name:          "invalid json",
+			path:          AlertsPath,
+			authorization: testReceiverToken,
+			body:          `{"alerts":`,
+			code:          http.StatusBadRequest,
+		},
+		{
+			name:          "invalid enum",
+			path:          AlertsPath,
+			authorization: testReceiverToken,
+			body:          `{"alerts":[{"severity":"VERY_HIGH"}]}`,
+			code:          http.StatusBadRequest,
+		},
+		{
+			name:          "handler error",
+			path:          AlertsPath,
+			authorization: testReceiverToken,
+			body:          `{"alerts":[{"hash":"0x1"}]}`,
+			handlerErr:    true,
+			code:          http.StatusInternalServerError,
+		},
+		{
+			name:          "ok",
+			path:          AlertsPath,
+			authorization: testReceiverToken,
+			body:          `{"alerts":[{"hash":"0x1"}]}`,
+			code:          http.StatusOK,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			handlerErr = testCase.handlerErr
+			req := httptest.NewRequest(http.MethodPost, testCase.path, strings.NewReader(testCase.body))
+			req.Header.Set("Authorization", testCase.authorization)
+			rec := httptest.NewRecorder()
+			receiver.ServeHTTP(rec, req)
+			require.Equal(t, testCase.code, rec.Code)
+		})
+	}
+}
+
+func TestScannerJWTAuthorizer(t *testing.T) {
+	r := require.New(t)
+
+	key, err := security.LoadKeyWithPassphrase("../../security/testkey", "Forta123")
+	r.NoError(err)
+	token, err := security.CreateScannerJWT(key, nil)
+	r.NoError(err)
+
+	authorizer := NewScannerJWTAuthorizer(security.ScannerJWTVerifyOptions{})
+	ctx, err := authorizer.Authorize(context.Background(), "Bearer "+token)
+	r.NoError(err)
+	r.Equal(key.Address.Hex(), apiutils.GetAddress(ctx))
+
+	_, err = authorizer.Authorize(context.Background(), token)
+	r.ErrorIs(err, ErrUnauthorized)
+	_, err = authorizer.Authorize(context.Background(), "Bearer invalid")
+	r.ErrorIs(err, ErrUnauthorized)
+}
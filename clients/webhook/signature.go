@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader is the header which contains the webhook request signature as
+// "t=<unix timestamp>,v1=<hex HMAC-SHA256 of '<unix timestamp>.<body>'>".
+const SignatureHeader = "X-Forta-Signature"
+
+const defaultSignatureTolerance = time.Minute * 5
+
+// ErrInvalidSignature is returned when the webhook request signature does not match.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// SignBody creates the signature header value for the request body.
+func SignBody(secret []byte, ts time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(bodyMAC(secret, timestamp, body)))
+}
+
+// VerifySignature checks the signature header value against the request body and
+// rejects the signatures which are older than the tolerance.
+func VerifySignature(secret []byte, header string, body []byte, tolerance time.Duration) error {
+	var timestamp, sig string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			timestamp = kv[1]
+		case "v1":
+			sig = kv[1]
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", ErrInvalidSignature)
+	}
+	sigBytes, err := hex.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("%w: bad signature encoding", ErrInvalidSignature)
+	}
+	if !hmac.Equal(sigBytes, bodyMAC(secret, timestamp, body)) {
+		return ErrInvalidSignature
+	}
+	age := time.Since(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp is out of tolerance", ErrInvalidSignature)
+	}
+	return nil
+}
+
+func bodyMAC(secret []byte, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// signingTransport adds the signature header to the requests.
+type signingTransport struct {
+	secret []byte
+	next   http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (st *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %v", err)
+		}
+		body = b
+	}
+	signed := req.Clone(req.Context())
+	signed.Body = io.NopCloser(bytes.NewReader(body))
+	signed.ContentLength = int64(len(body))
+	signed.Header.Set(SignatureHeader, SignBody(st.secret, time.Now(), body))
+	return st.next.RoundTrip(signed)
+}
@@ -2,11 +2,14 @@ package webhook
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
 
 	"github.com/forta-network/forta-core-go/clients/webhook/client"
 	"github.com/forta-network/forta-core-go/clients/webhook/client/operations"
 	"github.com/go-openapi/runtime"
+	httptransport "github.com/go-openapi/runtime/client"
+	"github.com/go-openapi/strfmt"
 )
 
 // AlertWebhookClient makes webhook requests.
@@ -26,16 +29,20 @@ func (awc *alertWebhookClient) SendAlerts(params *operations.SendAlertsParams, o
 
 // NewAlertWebhookClient creates a new webhook client to make requests to '/'.
 func NewAlertWebhookClient(dest string) (AlertWebhookClient, error) {
+	return newAlertWebhookClient(dest, nil)
+}
+
+// newAlertWebhookClient creates the client with an optional round tripper.
+func newAlertWebhookClient(dest string, rt http.RoundTripper) (*alertWebhookClient, error) {
 	u, err := url.Parse(dest)
 	if err != nil {
 		return nil, fmt.Errorf("invalid webhook url: %v", err)
 	}
-	client := client.NewHTTPClientWithConfig(nil, &client.TransportConfig{
-		Host:     u.Host,
-		BasePath: u.Path,
-		Schemes:  []string{u.Scheme},
-	}).Operations
+	transport := httptransport.New(u.Host, u.Path, []string{u.Scheme})
+	if rt != nil {
+		transport.Transport = rt
+	}
 	return &alertWebhookClient{
-		ClientService: client,
+		ClientService: client.New(transport, strfmt.Default).Operations,
 	}, nil
 }
@@ -97,8 +97,8 @@ func BlockFromBlockData(bd *protocol.BlockData) *Block {
 		ExcessBlobGas:         &bd.Block.ExcessBlobGas,
 	}
 
-	for _, uncle := range bd.Block.Uncles {
-		block.Uncles = append(block.Uncles, &uncle)
+	for i := range bd.Block.Uncles {
+		block.Uncles = append(block.Uncles, &bd.Block.Uncles[i])
 	}
 
 	for _, withdrawal := range bd.Block.Withdrawals {
@@ -265,8 +265,8 @@ func LogsFromBlockData(bd *protocol.BlockData) []LogEntry {
 			TransactionIndex: &log.TransactionIndex,
 		}
 
-		for _, topic := range log.Topics {
-			logs[i].Topics = append(logs[i].Topics, &topic)
+		for j := range log.Topics {
+			logs[i].Topics = append(logs[i].Topics, &log.Topics[j])
 		}
 	}
 
@@ -279,6 +279,7 @@ type TransactionReceipt struct {
 	BlockNumber       *string    `json:"blockNumber"`
 	ContractAddress   *string    `json:"contractAddress"`
 	CumulativeGasUsed *string    `json:"cumulativeGasUsed"`
+	EffectiveGasPrice *string    `json:"effectiveGasPrice"`
 	From              *string    `json:"from"`
 	GasUsed           *string    `json:"gasUsed"`
 	Logs              []LogEntry `json:"logs"`
@@ -287,6 +288,12 @@ type TransactionReceipt struct {
 	To                *string    `json:"to"`
 	TransactionHash   *string    `json:"transactionHash"`
 	TransactionIndex  *string    `json:"transactionIndex"`
+	Type              *string    `json:"type"`
+}
+
+// Reverted tells if the transaction has failed.
+func (tr *TransactionReceipt) Reverted() bool {
+	return tr.Status != nil && utils.HexToInt64(*tr.Status) == 0
 }
 
 // TraceCallTransaction contains the fields of the to-be-simulated transaction.
@@ -114,6 +114,35 @@ func TestTransaction_ToProto(t *testing.T) {
 	assert.Equal(t, expectedProto, actualProto)
 }
 
+func TestBlockFromBlockData_Uncles(t *testing.T) {
+	block := BlockFromBlockData(&protocol.BlockData{
+		Block: &protocol.BlockWithTransactions{
+			Number: "0x1",
+			Uncles: []string{"0xuncle1", "0xuncle2", "0xuncle3"},
+		},
+	})
+
+	assert.Len(t, block.Uncles, 3)
+	assert.Equal(t, "0xuncle1", *block.Uncles[0])
+	assert.Equal(t, "0xuncle2", *block.Uncles[1])
+	assert.Equal(t, "0xuncle3", *block.Uncles[2])
+}
+
+func TestLogsFromBlockData_Topics(t *testing.T) {
+	logs := LogsFromBlockData(&protocol.BlockData{
+		Logs: []*protocol.LogEntry{
+			{Address: "0xaddr1", Topics: []string{"0xtopic1", "0xtopic2", "0xtopic3"}},
+			{Address: "0xaddr2", Topics: []string{"0xtopic4", "0xtopic5"}},
+		},
+	})
+
+	assert.Len(t, logs, 2)
+	assert.Equal(t, "0xaddr1", *logs[0].Address)
+	assert.Equal(t, []string{"0xtopic1", "0xtopic2", "0xtopic3"}, strArr(logs[0].Topics))
+	assert.Equal(t, "0xaddr2", *logs[1].Address)
+	assert.Equal(t, []string{"0xtopic4", "0xtopic5"}, strArr(logs[1].Topics))
+}
+
 // Helper functions to create pointers to values
 func safeToPointer[T any](t T) *T {
 	return &t
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
 	"sort"
 	"strings"
@@ -18,6 +20,7 @@ type EventType string
 
 const (
 	EventTypeBlock EventType = "block"
+	EventTypeReorg EventType = "reorg"
 )
 
 const TimeTrackingTimestampFormat = time.RFC3339Nano
@@ -69,9 +72,20 @@ type BlockEvent struct {
 	Block      *Block
 	Logs       []LogEntry
 	Traces     []Trace
+	Receipts   []TransactionReceipt
 	Timestamps *TrackingTimestamps
 }
 
+// ReceiptFor finds the receipt of a transaction in the block, if the receipts were retrieved.
+func (t *BlockEvent) ReceiptFor(txHash string) *TransactionReceipt {
+	for i, receipt := range t.Receipts {
+		if receipt.TransactionHash != nil && strings.EqualFold(*receipt.TransactionHash, txHash) {
+			return &t.Receipts[i]
+		}
+	}
+	return nil
+}
+
 func str(val *string) string {
 	if val == nil {
 		return ""
@@ -218,6 +232,35 @@ func (t *BlockEvent) ToBlockData() *protocol.BlockData {
 	return combinedBlockEvent
 }
 
+// BlockEventFromBlockData creates the block event which contains the same block, logs and traces
+// with the block data. The block data does not contain the receipts.
+func BlockEventFromBlockData(bd *protocol.BlockData) (*BlockEvent, error) {
+	if bd.Block == nil {
+		return nil, errors.New("block data has no block")
+	}
+	traces, err := TracesFromBlockData(bd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read traces: %v", err)
+	}
+	block := BlockFromBlockData(bd)
+	// a block timestamp which can't be parsed is left as zero
+	var blockTime time.Time
+	if ts, err := block.GetTimestamp(); err == nil {
+		blockTime = ts.UTC()
+	}
+	return &BlockEvent{
+		EventType: EventTypeBlock,
+		ChainID:   new(big.Int).SetUint64(bd.ChainID),
+		Block:     block,
+		Logs:      LogsFromBlockData(bd),
+		Traces:    traces,
+		Timestamps: &TrackingTimestamps{
+			Block: blockTime,
+			Feed:  time.Now().UTC(),
+		},
+	}, nil
+}
+
 func safeInt(i *int) int {
 	if i == nil {
 		return 0
@@ -274,6 +317,19 @@ func (t *BlockEvent) ToMessage() (*protocol.BlockEvent, error) {
 	}, nil
 }
 
+// ReorgEvent is emitted by the block feed when the chain is reorganized. The orphaned blocks
+// were delivered earlier and are no longer canonical. The replacement blocks are the new canonical
+// blocks at the same heights and are delivered again as block events after this event.
+type ReorgEvent struct {
+	EventType      EventType
+	ChainID        *big.Int
+	CommonAncestor *Block // nil if the ancestor is older than the tracked window
+	Orphaned       []*Block
+	Replacement    []*Block
+	Depth          int
+	Timestamps     *TrackingTimestamps
+}
+
 type TransactionEvent struct {
 	BlockEvt    *BlockEvent
 	Transaction *Transaction
@@ -399,6 +455,15 @@ func (t *TransactionEvent) ToMessage() (*protocol.TransactionEvent, error) {
 		BlockNumber:       t.BlockEvt.Block.Number,
 		TransactionIndex:  t.Transaction.TransactionIndex,
 	}
+	if t.Receipt != nil {
+		receipt.Status = str(t.Receipt.Status)
+		receipt.CumulativeGasUsed = str(t.Receipt.CumulativeGasUsed)
+		receipt.LogsBloom = str(t.Receipt.LogsBloom)
+		receipt.GasUsed = str(t.Receipt.GasUsed)
+		if t.Receipt.ContractAddress != nil {
+			receipt.ContractAddress = strings.ToLower(*t.Receipt.ContractAddress)
+		}
+	}
 
 	nw := &protocol.TransactionEvent_Network{}
 	if t.BlockEvt.ChainID != nil {
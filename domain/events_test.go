@@ -188,3 +188,75 @@ func TestTransactionEvent_ToMessage_ContractDeploy(t *testing.T) {
 	assert.NoError(t, err, "error returned from json conversion")
 	assert.Equal(t, expected, str)
 }
+
+func TestTransactionEvent_ToMessage_Receipt(t *testing.T) {
+	txHash := "0x99ed5a4e541454219b444250c5c25d0306e73834b185f3aeee3f9627f0cd64c2"
+	to := "0x9C025948e61aeB2EF99503c81d682045f07344c2"
+
+	evt := &TransactionEvent{
+		BlockEvt: &BlockEvent{
+			EventType: "block",
+			ChainID:   big.NewInt(1),
+			Block: &Block{
+				Hash:   "0x1",
+				Number: "0x1",
+			},
+			Receipts: []TransactionReceipt{
+				{
+					TransactionHash:   &txHash,
+					Status:            strPtr("0x0"),
+					GasUsed:           strPtr("0x5208"),
+					CumulativeGasUsed: strPtr("0x6000"),
+					EffectiveGasPrice: strPtr("0x3"),
+				},
+			},
+		},
+		Transaction: &Transaction{
+			From:  "0xa7d8d9ef8D8Ce8992Df33D8b8CF4Aebabd5bD270",
+			Gas:   "0x10000",
+			Hash:  txHash,
+			Nonce: "0x1",
+			To:    &to,
+		},
+		Timestamps: &TrackingTimestamps{},
+	}
+	evt.Receipt = evt.BlockEvt.ReceiptFor(txHash)
+	assert.NotNil(t, evt.Receipt)
+	assert.True(t, evt.Receipt.Reverted())
+
+	msg, err := evt.ToMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "0x0", msg.Receipt.Status)
+	assert.Equal(t, "0x5208", msg.Receipt.GasUsed)
+	assert.Equal(t, "0x6000", msg.Receipt.CumulativeGasUsed)
+}
+
+func TestBlockEventFromBlockData(t *testing.T) {
+	evt, err := BlockEventFromBlockData(&protocol.BlockData{
+		ChainID: 1,
+		Block: &protocol.BlockWithTransactions{
+			Hash:         "0x1234",
+			Number:       "0x1",
+			Timestamp:    testTSHex,
+			Transactions: []*protocol.Transaction{{Hash: "0xtx"}},
+		},
+		Logs: []*protocol.LogEntry{{TransactionHash: "0xtx", Topics: []string{"0xtopic1", "0xtopic2"}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, EventTypeBlock, evt.EventType)
+	assert.Equal(t, int64(1), evt.ChainID.Int64())
+	assert.Equal(t, "0x1234", evt.Block.Hash)
+	assert.Len(t, evt.Block.Transactions, 1)
+	assert.Equal(t, []string{"0xtopic1", "0xtopic2"}, strArr(evt.Logs[0].Topics))
+	assert.Equal(t, testTSSeconds, evt.Timestamps.Block.Unix())
+
+	// a bad timestamp is tolerated
+	evt, err = BlockEventFromBlockData(&protocol.BlockData{
+		Block: &protocol.BlockWithTransactions{Number: "0x1", Timestamp: "bad"},
+	})
+	assert.NoError(t, err)
+	assert.True(t, evt.Timestamps.Block.IsZero())
+
+	_, err = BlockEventFromBlockData(&protocol.BlockData{})
+	assert.Error(t, err)
+}
@@ -74,6 +74,7 @@ const (
 	MetricJSONRPCCacheSize               = "jsonrpc.cache.size"
 	MetricJSONRPCCachePollError          = "jsonrpc.cache.poll.error"
 	MetricJSONRPCCachePollSuccess        = "jsonrpc.cache.poll.success"
+	MetricJSONRPCQuorumMismatch          = "jsonrpc.quorum.mismatch"
 	MetricPublicAPIProxyLatency          = "publicapi.latency"
 	MetricPublicAPIProxyRequest          = "publicapi.request"
 	MetricPublicAPIProxySuccess          = "publicapi.success"
@@ -156,6 +157,7 @@ var (
 		MetricJSONRPCCacheSize:               nil,
 		MetricJSONRPCCachePollError:          nil,
 		MetricJSONRPCCachePollSuccess:        nil,
+		MetricJSONRPCQuorumMismatch:          nil,
 		MetricPublicAPIProxyLatency:          nil,
 		MetricPublicAPIProxyRequest:          nil,
 		MetricPublicAPIProxySuccess:          nil,
@@ -87,6 +87,9 @@ func MessageTypeForAction(action string) (regmsg.Interface, bool) {
 
 	case UpdatePaymentSubscription:
 		return &UpdatePaymentSubscriptionMessage{}, true
+
+	case Retract:
+		return &RetractionMessage{}, true
 	}
 	return nil, false
 }
@@ -0,0 +1,48 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/forta-network/forta-core-go/domain/registry/regmsg"
+	"github.com/sirupsen/logrus"
+)
+
+const Retract = "Retract"
+
+// RetractionMessage tells that a message which was sent before is no longer valid because
+// its log was removed from the chain by a reorg. The source is the source of the retracted
+// message and the retracted message is included as it was sent.
+type RetractionMessage struct {
+	regmsg.Message
+	RetractedAction string          `json:"retractedAction"`
+	LogIndex        uint            `json:"logIndex"`
+	Retracted       json.RawMessage `json:"retracted"`
+}
+
+func (rm *RetractionMessage) LogFields() logrus.Fields {
+	return logrus.Fields{
+		"retractedAction": rm.RetractedAction,
+		"blockHash":       rm.Source.BlockHash,
+		"txHash":          rm.Source.TxHash,
+		"logIndex":        rm.LogIndex,
+	}
+}
+
+func NewRetractionMessage(msg regmsg.Interface, logIndex uint) (*RetractionMessage, error) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode retracted message: %v", err)
+	}
+	return &RetractionMessage{
+		Message: regmsg.Message{
+			Action:    Retract,
+			Timestamp: time.Now().UTC(),
+			Source:    msg.Info().Source,
+		},
+		RetractedAction: msg.ActionName(),
+		LogIndex:        logIndex,
+		Retracted:       b,
+	}, nil
+}
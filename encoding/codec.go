@@ -0,0 +1,196 @@
+package encoding
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec names
+const (
+	CodecGzip = "gzip"
+	CodecZstd = "zstd"
+	CodecNone = "none"
+)
+
+// The encoded payloads start with "<codec name>:" except the gzip ones, so that the
+// payloads encoded before the codecs can be decoded as gzip.
+const (
+	codecSeparator     = ':'
+	maxCodecNameLength = 16
+)
+
+// Codec compresses and decompresses the encoded payloads.
+type Codec interface {
+	Name() string
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	codecs   = make(map[string]Codec)
+	codecsMu sync.RWMutex
+)
+
+func init() {
+	RegisterCodec(gzipCodec{})
+	RegisterCodec(zstdCodec{})
+	RegisterCodec(noneCodec{})
+}
+
+// RegisterCodec registers a codec by its name so that the payloads can be encoded and
+// decoded with it. A codec with the same name is replaced.
+func RegisterCodec(codec Codec) {
+	name := codec.Name()
+	if len(name) == 0 || len(name) > maxCodecNameLength || strings.ContainsRune(name, codecSeparator) {
+		panic(fmt.Sprintf("invalid codec name: %s", name))
+	}
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[name] = codec
+}
+
+// GetCodec returns the codec with the name.
+func GetCodec(name string) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	codec, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown codec: %s", name)
+	}
+	return codec, nil
+}
+
+// EncodeProtoTo encodes the message to the writer by compressing with the codec.
+func EncodeProtoTo(w io.Writer, codecName string, msg proto.Message) error {
+	codec, err := GetCodec(codecName)
+	if err != nil {
+		return err
+	}
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal msg: %v", err)
+	}
+	if codecName != CodecGzip {
+		if _, err := io.WriteString(w, codecName+string(codecSeparator)); err != nil {
+			return err
+		}
+	}
+	encoder := base64.NewEncoder(base64.StdEncoding, w)
+	cw, err := codec.NewWriter(encoder)
+	if err != nil {
+		return err
+	}
+	if _, err := cw.Write(b); err != nil {
+		return fmt.Errorf("failed to %s msg: %v", codecName, err)
+	}
+	if err := cw.Close(); err != nil {
+		return fmt.Errorf("failed to %s msg: %v", codecName, err)
+	}
+	return encoder.Close()
+}
+
+// EncodeProto encodes the message to a string by compressing with the codec.
+func EncodeProto(codecName string, msg proto.Message) (string, error) {
+	var sb strings.Builder
+	if err := EncodeProtoTo(&sb, codecName, msg); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// DecodeProtoFrom decodes the message from the reader by detecting the codec.
+func DecodeProtoFrom(r io.Reader, target proto.Message) error {
+	br := bufio.NewReader(r)
+	codecName := CodecGzip
+	prefix, _ := br.Peek(maxCodecNameLength + 1)
+	if i := bytes.IndexByte(prefix, codecSeparator); i >= 0 {
+		codecName = string(prefix[:i])
+		_, _ = br.Discard(i + 1)
+	}
+	codec, err := GetCodec(codecName)
+	if err != nil {
+		return err
+	}
+	return decodeProtoWith(codec, br, target)
+}
+
+// DecodeProto decodes the message from the string by detecting the codec.
+func DecodeProto(encoded string, target proto.Message) error {
+	return DecodeProtoFrom(strings.NewReader(encoded), target)
+}
+
+func decodeProtoWith(codec Codec, r io.Reader, target proto.Message) error {
+	cr, err := codec.NewReader(base64.NewDecoder(base64.StdEncoding, r))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", codec.Name(), err)
+	}
+	defer cr.Close()
+	b, err := io.ReadAll(cr)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", codec.Name(), err)
+	}
+	return proto.Unmarshal(b, target)
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string {
+	return CodecGzip
+}
+
+func (gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type zstdCodec struct{}
+
+func (zstdCodec) Name() string {
+	return CodecZstd
+}
+
+func (zstdCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+func (zstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return zr.IOReadCloser(), nil
+}
+
+type noneCodec struct{}
+
+func (noneCodec) Name() string {
+	return CodecNone
+}
+
+func (noneCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return nopWriteCloser{w}, nil
+}
+
+func (noneCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(r), nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,50 @@
+package encoding_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/encoding"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeAndDecodeWithCodecs(t *testing.T) {
+	for _, codecName := range []string{encoding.CodecGzip, encoding.CodecZstd, encoding.CodecNone} {
+		t.Run(codecName, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, encoding.EncodeProtoTo(&buf, codecName, testBatch))
+
+			var decoded protocol.AlertBatch
+			assert.NoError(t, encoding.DecodeProtoFrom(&buf, &decoded))
+			assert.True(t, proto.Equal(&decoded, testBatch))
+		})
+	}
+}
+
+func TestEncodeProto_Prefix(t *testing.T) {
+	legacy, err := encoding.EncodeGzippedProto(testBatch)
+	assert.NoError(t, err)
+
+	// gzip is encoded the same way as before
+	res, err := encoding.EncodeProto(encoding.CodecGzip, testBatch)
+	assert.NoError(t, err)
+	assert.Equal(t, legacy, res)
+
+	res, err = encoding.EncodeProto(encoding.CodecZstd, testBatch)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(res, "zstd:"))
+
+	// only the legacy decoder rejects the new codecs
+	var decoded protocol.AlertBatch
+	assert.Error(t, encoding.DecodeGzippedProto(res, &decoded))
+	assert.NoError(t, encoding.DecodeProto(res, &decoded))
+	assert.NoError(t, encoding.DecodeProto(legacy, &decoded))
+	assert.True(t, proto.Equal(&decoded, testBatch))
+
+	assert.Error(t, encoding.DecodeProto("unknown:abcd", &decoded))
+	_, err = encoding.EncodeProto("unknown", testBatch)
+	assert.Error(t, err)
+}
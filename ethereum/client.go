@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	backoff "github.com/cenkalti/backoff/v4"
@@ -18,6 +19,7 @@ import (
 	"github.com/forta-network/forta-core-go/clients/health"
 	"github.com/forta-network/forta-core-go/domain"
 	"github.com/forta-network/forta-core-go/ethereum/provider"
+	"github.com/forta-network/forta-core-go/protocol/settings"
 	"github.com/forta-network/forta-core-go/utils"
 	"github.com/forta-network/forta-core-go/utils/httpclient"
 
@@ -44,6 +46,7 @@ type RPCClient interface {
 	Close()
 	Call(result interface{}, method string, args ...interface{}) error
 	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
 }
 
 // Subscriber subscribes to Ethereum namespaces.
@@ -60,8 +63,11 @@ type Client interface {
 
 	BlockByHash(ctx context.Context, hash string) (*domain.Block, error)
 	BlockByNumber(ctx context.Context, number *big.Int) (*domain.Block, error)
+	BlocksByNumbers(ctx context.Context, numbers []*big.Int) ([]*domain.Block, []error)
 	BlockNumber(ctx context.Context) (*big.Int, error)
 	TransactionReceipt(ctx context.Context, txHash string) (*domain.TransactionReceipt, error)
+	TransactionReceipts(ctx context.Context, txHashes []string) ([]*domain.TransactionReceipt, []error)
+	BlockReceipts(ctx context.Context, number *big.Int) ([]domain.TransactionReceipt, error)
 	ChainID(ctx context.Context) (*big.Int, error)
 	GetTransactionCount(ctx context.Context, address string, blockNumber any) (*big.Int, error)
 	TraceBlock(ctx context.Context, number *big.Int) ([]domain.Trace, error)
@@ -82,6 +88,7 @@ const (
 	blockNumber         = "eth_blockNumber"
 	getLogs             = "eth_getLogs"
 	transactionReceipt  = "eth_getTransactionReceipt"
+	blockReceipts       = "eth_getBlockReceipts"
 	traceBlock          = "trace_block"
 	debugTraceCall      = "debug_traceCall"
 	chainId             = "eth_chainId"
@@ -104,6 +111,15 @@ var permanentErrors = []string{
 	"receipt was empty",
 }
 
+// blockReceiptsPermanentErrors tell that the provider does not serve eth_getBlockReceipts
+// so that the caller can fall back to the transaction receipts.
+var blockReceiptsPermanentErrors = []string{
+	"does not exist/is not available",
+}
+
+// maxBatchSize is the max number of calls sent in a single batch request
+const maxBatchSize = 100
+
 var minBackoff = 1 * time.Second
 var maxBackoff = 1 * time.Minute
 
@@ -118,14 +134,26 @@ type streamEthClient struct {
 	lastBlockByNumberErr         health.ErrorTracker
 	lastGetTransactionReceiptReq health.TimeTracker
 	lastGetTransactionReceiptErr health.ErrorTracker
+	lastGetBlockReceiptsReq      health.TimeTracker
+	lastGetBlockReceiptsErr      health.ErrorTracker
 	lastTraceBlockReq            health.TimeTracker
 	lastTraceBlockErr            health.ErrorTracker
+
+	rateLimiter        *rateLimiter
+	throttled          atomic.Uint64
+	throttledTime      atomic.Int64
+	retries            atomic.Uint64
+	throttledCount     health.NumberTracker
+	throttledTimeTotal health.NumberTracker
+	retryCount         health.NumberTracker
 }
 
 type RetryOptions struct {
 	MaxElapsedTime *time.Duration
 	MinBackoff     *time.Duration
 	MaxBackoff     *time.Duration
+	// PermanentErrors are the errors which are not retried only for this request.
+	PermanentErrors []string
 }
 
 // Close invokes close on the underlying client
@@ -141,13 +169,46 @@ func (e *streamEthClient) IsWebsocket() bool {
 	return e.isWebsocket
 }
 
-func isPermanentError(err error) bool {
+// SetRateLimit makes the client wait for the token bucket of the method before sending each request
+// to a provider. It should be set before the client is used.
+func (e *streamEthClient) SetRateLimit(cfg RateLimitConfig) {
+	e.rateLimiter = newRateLimiter(cfg)
+}
+
+// ApplyChainSettings sets the rate limit and the retry interval from the chain settings.
+func (e *streamEthClient) ApplyChainSettings(chainSettings *settings.ChainSettings) {
+	e.SetRateLimit(RateLimitFromChainSettings(chainSettings))
+	if chainSettings.JSONRPCRetryIntervalSeconds > 0 {
+		e.SetRetryInterval(time.Duration(chainSettings.JSONRPCRetryIntervalSeconds) * time.Second)
+	}
+}
+
+// waitForRateLimit waits until the provider can be called n times with the method.
+func (e *streamEthClient) waitForRateLimit(ctx context.Context, rpcClient Subscriber, method string, n int) error {
+	if e.rateLimiter == nil {
+		return nil
+	}
+	waited, err := e.rateLimiter.Wait(ctx, rpcClient, method, n)
+	if err != nil {
+		return err
+	}
+	if waited > 0 {
+		e.throttledCount.Set(float64(e.throttled.Add(1)))
+		e.throttledTimeTotal.Set(time.Duration(e.throttledTime.Add(int64(waited))).Seconds())
+	}
+	return nil
+}
+
+func isPermanentError(err error, extra ...string) bool {
 	if err == nil {
 		return false
 	}
-	for _, pe := range permanentErrors {
-		if strings.Contains(strings.ToLower(err.Error()), pe) {
-			return true
+	msg := strings.ToLower(err.Error())
+	for _, errs := range [][]string{permanentErrors, extra} {
+		for _, pe := range errs {
+			if strings.Contains(msg, pe) {
+				return true
+			}
 		}
 	}
 	return false
@@ -158,6 +219,19 @@ func (e *streamEthClient) withBackoff(
 	ctx context.Context, name string,
 	operation func(ctx context.Context, rpcClient Subscriber) error, options RetryOptions,
 	timeTracker *health.TimeTracker, errorTracker *health.ErrorTracker,
+) error {
+	waitForRateLimit := func(ctx context.Context, rpcClient Subscriber) error {
+		return e.waitForRateLimit(ctx, rpcClient, methodOf(name), 1)
+	}
+	return e.withBackoffAndRateLimit(ctx, name, waitForRateLimit, operation, options, timeTracker, errorTracker)
+}
+
+// withBackoffAndRateLimit is like withBackoff but waits for the rate limit before each attempt
+// by using the given function.
+func (e *streamEthClient) withBackoffAndRateLimit(
+	ctx context.Context, name string, waitForRateLimit func(ctx context.Context, rpcClient Subscriber) error,
+	operation func(ctx context.Context, rpcClient Subscriber) error, options RetryOptions,
+	timeTracker *health.TimeTracker, errorTracker *health.ErrorTracker,
 ) error {
 	bo := backoff.NewExponentialBackOff()
 	bo.MaxInterval = maxBackoff
@@ -176,8 +250,15 @@ func (e *streamEthClient) withBackoff(
 			return backoff.Permanent(ctx.Err())
 		}
 
+		rpcClient := e.rpcClientProvider.Provide()
+		if err := waitForRateLimit(ctx, rpcClient); err != nil {
+			return backoff.Permanent(err)
+		}
+
 		tCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
-		err := operation(tCtx, e.rpcClientProvider.Provide())
+		start := time.Now()
+		err := operation(tCtx, rpcClient)
+		e.trackRequest(rpcClient, time.Since(start), err)
 		cancel()
 		if timeTracker != nil {
 			timeTracker.Set()
@@ -192,7 +273,7 @@ func (e *streamEthClient) withBackoff(
 		if err == nil {
 			//success, returning now avoids failing on context timeouts in certain edge cases
 			return nil
-		} else if isPermanentError(err) {
+		} else if isPermanentError(err, options.PermanentErrors...) {
 			log.Errorf("backoff permanent error: %s", err.Error())
 			return backoff.Permanent(err)
 		} else if ctx.Err() != nil {
@@ -200,6 +281,7 @@ func (e *streamEthClient) withBackoff(
 			return backoff.Permanent(ctx.Err())
 		} else {
 			log.Warnf("%s failed...retrying: %s", name, err.Error())
+			e.retryCount.Set(float64(e.retries.Add(1)))
 		}
 		return err
 	}, bo)
@@ -209,6 +291,119 @@ func (e *streamEthClient) withBackoff(
 	return err
 }
 
+// batchCall is a single call in a batch request.
+type batchCall struct {
+	method string
+	args   []interface{}
+	result interface{}
+	// check validates the result of a successful call
+	check func() error
+}
+
+// batchWithBackoff sends the calls in batch requests of maxBatchSize and retries the failed calls
+// of a batch with the same backoff rules as the single requests. The errors are returned per call.
+func (e *streamEthClient) batchWithBackoff(
+	ctx context.Context, name string, calls []*batchCall, options RetryOptions,
+	timeTracker *health.TimeTracker, errorTracker *health.ErrorTracker,
+) []error {
+	errs := make([]error, len(calls))
+	for start := 0; start < len(calls); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(calls) {
+			end = len(calls)
+		}
+		batchName := fmt.Sprintf("%s[%d:%d]", name, start, end)
+		e.sendBatch(ctx, batchName, calls[start:end], errs[start:end], options, timeTracker, errorTracker)
+	}
+	return errs
+}
+
+func (e *streamEthClient) sendBatch(
+	ctx context.Context, name string, calls []*batchCall, errs []error, options RetryOptions,
+	timeTracker *health.TimeTracker, errorTracker *health.ErrorTracker,
+) {
+	pending := make([]int, len(calls))
+	for i := range pending {
+		pending[i] = i
+	}
+	// each call in the batch takes a token of its method
+	waitForRateLimit := func(ctx context.Context, rpcClient Subscriber) error {
+		counts := make(map[string]int)
+		var methods []string
+		for _, callIndex := range pending {
+			method := calls[callIndex].method
+			if counts[method] == 0 {
+				methods = append(methods, method)
+			}
+			counts[method]++
+		}
+		for _, method := range methods {
+			if err := e.waitForRateLimit(ctx, rpcClient, method, counts[method]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err := e.withBackoffAndRateLimit(ctx, name, waitForRateLimit, func(ctx context.Context, rpcClient Subscriber) error {
+		elems := make([]rpc.BatchElem, len(pending))
+		for i, callIndex := range pending {
+			call := calls[callIndex]
+			elems[i] = rpc.BatchElem{Method: call.method, Args: call.args, Result: call.result}
+		}
+		if err := rpcClient.BatchCallContext(ctx, elems); err != nil {
+			return err
+		}
+		// retry only the calls which failed with a non-permanent error
+		var failed []int
+		for i, callIndex := range pending {
+			err := elems[i].Error
+			if err == nil && calls[callIndex].check != nil {
+				err = calls[callIndex].check()
+			}
+			errs[callIndex] = err
+			if err != nil && !isPermanentError(err, options.PermanentErrors...) {
+				failed = append(failed, callIndex)
+			}
+		}
+		pending = failed
+		if len(pending) > 0 {
+			return fmt.Errorf("%d calls in batch failed: %w", len(pending), errs[pending[0]])
+		}
+		return nil
+	}, options, timeTracker, errorTracker)
+	if err == nil {
+		return
+	}
+	// make sure that the calls which never got a response have an error
+	for _, callIndex := range pending {
+		if errs[callIndex] == nil {
+			errs[callIndex] = err
+		}
+	}
+}
+
+// trackRequest lets the provider know about the outcome of a request, if it keeps track of them.
+func (e *streamEthClient) trackRequest(rpcClient Subscriber, latency time.Duration, err error) {
+	tracker, ok := e.rpcClientProvider.(provider.Tracker[Subscriber])
+	if !ok {
+		return
+	}
+	// not found and cancellation errors are not a sign of an unhealthy endpoint
+	if errors.Is(err, ErrNotFound) || errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	tracker.TrackRequest(rpcClient, latency, err)
+}
+
+// trackHead lets the provider know about the latest block number of an endpoint.
+func (e *streamEthClient) trackHead(rpcClient Subscriber, blockNumber *big.Int) {
+	tracker, ok := e.rpcClientProvider.(provider.Tracker[Subscriber])
+	if !ok || blockNumber == nil {
+		return
+	}
+	tracker.TrackHead(rpcClient, blockNumber.Uint64())
+}
+
 func pointDur(d time.Duration) *time.Duration {
 	return &d
 }
@@ -333,6 +528,10 @@ func (e *streamEthClient) BlockByNumber(ctx context.Context, number *big.Int) (*
 		if result.Hash == "" {
 			return ErrNotFound
 		}
+		if number == nil {
+			head, _ := utils.HexToBigInt(result.Number)
+			e.trackHead(rpcClient, head)
+		}
 		return nil
 	}, RetryOptions{
 		MinBackoff:     pointDur(e.retryInterval),
@@ -342,12 +541,47 @@ func (e *streamEthClient) BlockByNumber(ctx context.Context, number *big.Int) (*
 	return &result, err
 }
 
+// BlocksByNumbers returns the blocks by numbers, using batch requests
+func (e *streamEthClient) BlocksByNumbers(ctx context.Context, numbers []*big.Int) ([]*domain.Block, []error) {
+	name := fmt.Sprintf("%s(batch=%d)", blocksByNumber, len(numbers))
+	log.Debugf(name)
+
+	results := make([]*domain.Block, len(numbers))
+	calls := make([]*batchCall, len(numbers))
+	for i, number := range numbers {
+		result := &domain.Block{}
+		results[i] = result
+		calls[i] = &batchCall{
+			method: blocksByNumber,
+			args:   []interface{}{utils.BigIntToHex(number), true},
+			result: result,
+			check: func() error {
+				if result.Hash == "" {
+					return ErrNotFound
+				}
+				return nil
+			},
+		}
+	}
+	errs := e.batchWithBackoff(ctx, name, calls, RetryOptions{
+		MinBackoff:     pointDur(e.retryInterval),
+		MaxElapsedTime: pointDur(12 * time.Hour),
+		MaxBackoff:     pointDur(e.retryInterval),
+	}, &e.lastBlockByNumberReq, &e.lastBlockByNumberErr)
+	return results, errs
+}
+
 // BlockNumber returns the latest block number
 func (e *streamEthClient) BlockNumber(ctx context.Context) (*big.Int, error) {
 	log.Debugf(blockNumber)
 	var result string
 	err := e.withBackoff(ctx, blockNumber, func(ctx context.Context, rpcClient Subscriber) error {
-		return rpcClient.CallContext(ctx, &result, blockNumber)
+		if err := rpcClient.CallContext(ctx, &result, blockNumber); err != nil {
+			return err
+		}
+		head, _ := utils.HexToBigInt(result)
+		e.trackHead(rpcClient, head)
+		return nil
 	}, RetryOptions{
 		MaxElapsedTime: pointDur(12 * time.Hour),
 	}, nil, nil)
@@ -391,6 +625,50 @@ func (e *streamEthClient) TransactionReceipt(ctx context.Context, txHash string)
 	return &result, err
 }
 
+// TransactionReceipts returns the receipts for the transactions, using batch requests
+func (e *streamEthClient) TransactionReceipts(ctx context.Context, txHashes []string) ([]*domain.TransactionReceipt, []error) {
+	name := fmt.Sprintf("%s(batch=%d)", transactionReceipt, len(txHashes))
+	log.Debugf(name)
+
+	results := make([]*domain.TransactionReceipt, len(txHashes))
+	calls := make([]*batchCall, len(txHashes))
+	for i, txHash := range txHashes {
+		result := &domain.TransactionReceipt{}
+		results[i] = result
+		calls[i] = &batchCall{
+			method: transactionReceipt,
+			args:   []interface{}{txHash},
+			result: result,
+			check: func() error {
+				if result.TransactionHash == nil {
+					return errors.New("receipt was empty")
+				}
+				return nil
+			},
+		}
+	}
+	errs := e.batchWithBackoff(ctx, name, calls, RetryOptions{
+		MaxElapsedTime: pointDur(5 * time.Minute),
+	}, &e.lastGetTransactionReceiptReq, &e.lastGetTransactionReceiptErr)
+	return results, errs
+}
+
+// BlockReceipts returns the receipts of all transactions in a block
+func (e *streamEthClient) BlockReceipts(ctx context.Context, number *big.Int) ([]domain.TransactionReceipt, error) {
+	name := fmt.Sprintf("%s(%s)", blockReceipts, number)
+	log.Debugf(name)
+	var result []domain.TransactionReceipt
+	err := e.withBackoff(ctx, name, func(ctx context.Context, rpcClient Subscriber) error {
+		return rpcClient.CallContext(ctx, &result, blockReceipts, utils.BigIntToHex(number))
+	}, RetryOptions{
+		MinBackoff:      pointDur(e.retryInterval),
+		MaxElapsedTime:  pointDur(1 * time.Minute),
+		MaxBackoff:      pointDur(e.retryInterval),
+		PermanentErrors: blockReceiptsPermanentErrors,
+	}, &e.lastGetBlockReceiptsReq, &e.lastGetBlockReceiptsErr)
+	return result, err
+}
+
 // GetTransactionCount returns the transaction count for an address
 func (e *streamEthClient) GetTransactionCount(ctx context.Context, address string, block any) (*big.Int, error) {
 	name := fmt.Sprintf("%s(%s, %s)", getTransactionCount, address, block)
@@ -472,14 +750,23 @@ func (e *streamEthClient) Name() string {
 
 // Health implements the health.Reporter interface.
 func (e *streamEthClient) Health() health.Reports {
-	return health.Reports{
+	reports := health.Reports{
 		e.lastBlockByNumberReq.GetReport("request.block-by-number.time"),
 		e.lastBlockByNumberErr.GetReport("request.block-by-number.error"),
 		e.lastGetTransactionReceiptReq.GetReport("request.get-transaction-receipt.time"),
 		e.lastGetTransactionReceiptErr.GetReport("request.get-transaction-receipt.error"),
+		e.lastGetBlockReceiptsReq.GetReport("request.get-block-receipts.time"),
+		e.lastGetBlockReceiptsErr.GetReport("request.get-block-receipts.error"),
 		e.lastTraceBlockReq.GetReport("request.trace-block.time"),
 		e.lastTraceBlockErr.GetReport("request.trace-block.error"),
+		e.throttledCount.GetReport("request.rate-limit.wait.count"),
+		e.throttledTimeTotal.GetReport("request.rate-limit.wait.seconds"),
+		e.retryCount.GetReport("request.retry.count"),
+	}
+	if reporter, ok := e.rpcClientProvider.(interface{ Health() health.Reports }); ok {
+		reports = append(reports, reporter.Health()...)
 	}
+	return reports
 }
 
 type rpcClient struct {
@@ -527,7 +814,8 @@ func newInternalRPCClient(ctx context.Context, apiURL string) (*rpcClient, error
 	return &rpcClient{Client: rClient}, nil
 }
 
-// NewStreamEthClientMulti creates a new ethereum client with multiple RPCs.
+// NewStreamEthClientMulti creates a new ethereum client with multiple RPCs. The requests are routed
+// to the healthiest RPC and the failing ones are avoided for a while.
 func NewStreamEthClientMulti(ctx context.Context, apiName string, apiURLs ...string) (*streamEthClient, error) {
 	if apiURLs == nil {
 		return nil, errors.New("no api urls provided")
@@ -535,20 +823,27 @@ func NewStreamEthClientMulti(ctx context.Context, apiName string, apiURLs ...str
 	if len(apiURLs) == 1 {
 		return NewStreamEthClient(ctx, apiName, apiURLs[0])
 	}
-	var rpcClients []Subscriber
-	for _, apiURL := range apiURLs {
+	var endpoints []provider.Endpoint[Subscriber]
+	for i, apiURL := range apiURLs {
 		c, err := newInternalRPCClient(ctx, apiURL)
 		if err != nil {
 			return nil, err
 		}
-		rpcClients = append(rpcClients, c)
+		endpoints = append(endpoints, provider.Endpoint[Subscriber]{
+			Name:    endpointName(i, apiURL),
+			Element: c,
+		})
 	}
+	return newStreamEthClientWithEndpoints(apiName, endpoints...), nil
+}
+
+func newStreamEthClientWithEndpoints(apiName string, endpoints ...provider.Endpoint[Subscriber]) *streamEthClient {
 	return &streamEthClient{
 		apiName:           apiName,
-		rpcClientProvider: provider.NewRingProvider(rpcClients...),
+		rpcClientProvider: provider.NewHealthProvider(endpoints...),
 		retryInterval:     defaultRetryInterval,
 		isWebsocket:       false, // TODO: Support multiple websockets later if necessary.
-	}, nil
+	}
 }
 
 // NewStreamEthClient creates a new ethereum client.
@@ -570,6 +865,15 @@ func NewStreamEthClientWithRPCClient(ctx context.Context, apiName string, isWs b
 	}, nil
 }
 
+// endpointName makes a name for the health reports without exposing any credentials in the URL.
+func endpointName(i int, apiURL string) string {
+	u, err := url.Parse(apiURL)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Sprintf("%d", i)
+	}
+	return fmt.Sprintf("%d-%s", i, u.Hostname())
+}
+
 func isWebsocket(apiURL string) bool {
 	u, err := url.Parse(apiURL)
 	if err != nil {
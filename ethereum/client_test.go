@@ -3,9 +3,11 @@ package ethereum
 import (
 	"context"
 	"errors"
+	"math/big"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/goccy/go-json"
 
 	"github.com/golang/mock/gomock"
@@ -90,3 +92,62 @@ func TestEthClient_SubscribeToHeader_Err(t *testing.T) {
 	}
 	r.True(blocked)
 }
+
+func TestEthClient_BlocksByNumbers(t *testing.T) {
+	r := require.New(t)
+
+	ethClient, client, ctx := initClient(t)
+	ethClient.SetRetryInterval(time.Millisecond)
+
+	setBlock := func(elem *rpc.BatchElem, hash string) {
+		b, _ := json.Marshal(domain.Block{Hash: hash})
+		elem.Error = json.Unmarshal(b, elem.Result)
+	}
+
+	// first block succeeds, second one fails with a retriable error and third one with a permanent error
+	client.EXPECT().BatchCallContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, b []rpc.BatchElem) error {
+		r.Len(b, 3)
+		r.Equal([]interface{}{"0x1", true}, b[0].Args)
+		setBlock(&b[0], "0x01")
+		b[1].Error = testErr
+		b[2].Error = errors.New("hash is not currently canonical")
+		return nil
+	}).Times(1)
+	// only the second block is retried
+	client.EXPECT().BatchCallContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, b []rpc.BatchElem) error {
+		r.Len(b, 1)
+		r.Equal([]interface{}{"0x2", true}, b[0].Args)
+		setBlock(&b[0], "0x02")
+		return nil
+	}).Times(1)
+
+	blocks, errs := ethClient.BlocksByNumbers(ctx, []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)})
+	r.Len(blocks, 3)
+	r.NoError(errs[0])
+	r.Equal("0x01", blocks[0].Hash)
+	r.NoError(errs[1])
+	r.Equal("0x02", blocks[1].Hash)
+	r.Error(errs[2])
+}
+
+func TestEthClient_BlockReceiptsUnsupported(t *testing.T) {
+	r := require.New(t)
+
+	ethClient, client, ctx := initClient(t)
+	ethClient.SetRetryInterval(time.Millisecond)
+	unsupportedErr := errors.New("the method eth_getBlockReceipts does not exist/is not available")
+
+	// not retried for the block receipts
+	client.EXPECT().CallContext(gomock.Any(), gomock.Any(), blockReceipts, "0x1").Return(unsupportedErr).Times(1)
+	_, err := ethClient.BlockReceipts(ctx, big.NewInt(1))
+	r.ErrorIs(err, unsupportedErr)
+
+	// retried for the other methods
+	client.EXPECT().CallContext(gomock.Any(), gomock.Any(), blocksByHash, testBlockHash).Return(unsupportedErr).Times(1)
+	client.EXPECT().CallContext(gomock.Any(), gomock.Any(), blocksByHash, testBlockHash).DoAndReturn(func(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+		b, _ := json.Marshal(domain.Block{Hash: testBlockHash})
+		return json.Unmarshal(b, result)
+	}).Times(1)
+	_, err = ethClient.BlockByHash(ctx, testBlockHash)
+	r.NoError(err)
+}
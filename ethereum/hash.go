@@ -0,0 +1,45 @@
+package ethereum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/forta-network/forta-core-go/domain"
+)
+
+// BlockResponseHash computes a hash by using the block hash and the transaction hashes.
+func BlockResponseHash(block *domain.Block) string {
+	hashConcat := block.Hash
+	for _, tx := range block.Transactions {
+		hashConcat += tx.Hash
+	}
+	return hashOf(hashConcat)
+}
+
+// TraceResponseHash computes a hash by using the transaction hashes of the traces.
+func TraceResponseHash(traces []domain.Trace) string {
+	var hashConcat string
+	for _, trace := range traces {
+		if trace.TransactionHash != nil {
+			hashConcat += *trace.TransactionHash
+		}
+	}
+	return hashOf(hashConcat)
+}
+
+// LogsResponseHash computes a hash by using the block hashes, the transaction hashes and the indexes of the logs.
+func LogsResponseHash(logs []types.Log) string {
+	var hashConcat string
+	for _, lg := range logs {
+		hashConcat += lg.BlockHash.Hex() + lg.TxHash.Hex() + strconv.FormatUint(uint64(lg.Index), 10)
+	}
+	return hashOf(hashConcat)
+}
+
+func hashOf(str string) string {
+	hash := sha256.Sum256([]byte(str))
+	return hex.EncodeToString(hash[:])
+}
@@ -13,6 +13,7 @@ import (
 	ethereum "github.com/ethereum/go-ethereum"
 	common "github.com/ethereum/go-ethereum/common"
 	types "github.com/ethereum/go-ethereum/core/types"
+	rpc "github.com/ethereum/go-ethereum/rpc"
 	health "github.com/forta-network/forta-core-go/clients/health"
 	domain "github.com/forta-network/forta-core-go/domain"
 	gomock "github.com/golang/mock/gomock"
@@ -332,6 +333,20 @@ func (m *MockRPCClient) EXPECT() *MockRPCClientMockRecorder {
 	return m.recorder
 }
 
+// BatchCallContext mocks base method.
+func (m *MockRPCClient) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchCallContext", ctx, b)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchCallContext indicates an expected call of BatchCallContext.
+func (mr *MockRPCClientMockRecorder) BatchCallContext(ctx, b interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchCallContext", reflect.TypeOf((*MockRPCClient)(nil).BatchCallContext), ctx, b)
+}
+
 // Call mocks base method.
 func (m *MockRPCClient) Call(result interface{}, method string, args ...interface{}) error {
 	m.ctrl.T.Helper()
@@ -405,6 +420,20 @@ func (m *MockSubscriber) EXPECT() *MockSubscriberMockRecorder {
 	return m.recorder
 }
 
+// BatchCallContext mocks base method.
+func (m *MockSubscriber) BatchCallContext(ctx context.Context, b []rpc.BatchElem) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchCallContext", ctx, b)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BatchCallContext indicates an expected call of BatchCallContext.
+func (mr *MockSubscriberMockRecorder) BatchCallContext(ctx, b interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchCallContext", reflect.TypeOf((*MockSubscriber)(nil).BatchCallContext), ctx, b)
+}
+
 // Call mocks base method.
 func (m *MockSubscriber) Call(result interface{}, method string, args ...interface{}) error {
 	m.ctrl.T.Helper()
@@ -543,6 +572,36 @@ func (mr *MockClientMockRecorder) BlockNumber(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockNumber", reflect.TypeOf((*MockClient)(nil).BlockNumber), ctx)
 }
 
+// BlockReceipts mocks base method.
+func (m *MockClient) BlockReceipts(ctx context.Context, number *big.Int) ([]domain.TransactionReceipt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockReceipts", ctx, number)
+	ret0, _ := ret[0].([]domain.TransactionReceipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BlockReceipts indicates an expected call of BlockReceipts.
+func (mr *MockClientMockRecorder) BlockReceipts(ctx, number interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockReceipts", reflect.TypeOf((*MockClient)(nil).BlockReceipts), ctx, number)
+}
+
+// BlocksByNumbers mocks base method.
+func (m *MockClient) BlocksByNumbers(ctx context.Context, numbers []*big.Int) ([]*domain.Block, []error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlocksByNumbers", ctx, numbers)
+	ret0, _ := ret[0].([]*domain.Block)
+	ret1, _ := ret[1].([]error)
+	return ret0, ret1
+}
+
+// BlocksByNumbers indicates an expected call of BlocksByNumbers.
+func (mr *MockClientMockRecorder) BlocksByNumbers(ctx, numbers interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlocksByNumbers", reflect.TypeOf((*MockClient)(nil).BlocksByNumbers), ctx, numbers)
+}
+
 // ChainID mocks base method.
 func (m *MockClient) ChainID(ctx context.Context) (*big.Int, error) {
 	m.ctrl.T.Helper()
@@ -712,3 +771,18 @@ func (mr *MockClientMockRecorder) TransactionReceipt(ctx, txHash interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransactionReceipt", reflect.TypeOf((*MockClient)(nil).TransactionReceipt), ctx, txHash)
 }
+
+// TransactionReceipts mocks base method.
+func (m *MockClient) TransactionReceipts(ctx context.Context, txHashes []string) ([]*domain.TransactionReceipt, []error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransactionReceipts", ctx, txHashes)
+	ret0, _ := ret[0].([]*domain.TransactionReceipt)
+	ret1, _ := ret[1].([]error)
+	return ret0, ret1
+}
+
+// TransactionReceipts indicates an expected call of TransactionReceipts.
+func (mr *MockClientMockRecorder) TransactionReceipts(ctx, txHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransactionReceipts", reflect.TypeOf((*MockClient)(nil).TransactionReceipts), ctx, txHashes)
+}
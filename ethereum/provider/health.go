@@ -0,0 +1,255 @@
+package provider
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+)
+
+// Tracker receives the outcome of the requests made with the provided elements.
+type Tracker[T Element] interface {
+	TrackRequest(el T, latency time.Duration, err error)
+	TrackHead(el T, blockNumber uint64)
+}
+
+// Endpoint is a named element.
+type Endpoint[T Element] struct {
+	Name    string
+	Element T
+}
+
+// scoring and quarantine settings
+const (
+	ewmaWeight         = 0.2
+	errorPenaltyMs     = 1000
+	headLagPenaltyMs   = 100
+	minQuarantine      = time.Second
+	maxQuarantine      = 5 * time.Minute
+	quarantineMultiple = 2
+	headExpiry         = time.Minute
+	errorRateHalfLife  = time.Minute
+)
+
+type endpointState[T Element] struct {
+	Endpoint[T]
+
+	latencyMs        float64
+	errorRate        float64
+	errorRateUpdated time.Time
+	head             uint64
+	headUpdated      time.Time
+	failures         int
+	quarantinedUntil time.Time
+
+	lastReq health.TimeTracker
+	lastErr health.ErrorTracker
+}
+
+// HealthProvider provides the healthiest element by scoring the latency, the error rate and
+// the head lag of each element. The failing elements are quarantined with exponential backoff.
+type HealthProvider[T Element] struct {
+	endpoints []*endpointState[T]
+	curr      int
+	mu        sync.RWMutex
+}
+
+// NewHealthProvider creates a new health provider.
+func NewHealthProvider[T Element](endpoints ...Endpoint[T]) *HealthProvider[T] {
+	if len(endpoints) == 0 {
+		panic("zero endpoints provided to health provider")
+	}
+	hp := &HealthProvider[T]{}
+	for _, endpoint := range endpoints {
+		hp.endpoints = append(hp.endpoints, &endpointState[T]{Endpoint: endpoint})
+	}
+	return hp
+}
+
+// Provide provides the healthiest element.
+func (hp *HealthProvider[T]) Provide() T {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+	hp.curr = hp.best()
+	return hp.endpoints[hp.curr].Element
+}
+
+// Next provides the healthiest element after the latest outcomes are taken into account.
+func (hp *HealthProvider[T]) Next() T {
+	return hp.Provide()
+}
+
+// Close closes all elements.
+func (hp *HealthProvider[T]) Close() {
+	for _, endpoint := range hp.endpoints {
+		endpoint.Element.Close()
+	}
+}
+
+// best finds the available element with the lowest score. If all elements are
+// quarantined, it finds the one which is released the earliest.
+func (hp *HealthProvider[T]) best() int {
+	now := time.Now()
+	maxHead := hp.maxHead(now)
+	best := -1
+	var bestScore float64
+	for i, endpoint := range hp.endpoints {
+		if endpoint.quarantinedUntil.After(now) {
+			continue
+		}
+		score := endpoint.score(maxHead, now)
+		if best < 0 || score < bestScore {
+			best = i
+			bestScore = score
+		}
+	}
+	if best >= 0 {
+		return best
+	}
+	best = 0
+	for i, endpoint := range hp.endpoints {
+		if endpoint.quarantinedUntil.Before(hp.endpoints[best].quarantinedUntil) {
+			best = i
+		}
+	}
+	return best
+}
+
+func (hp *HealthProvider[T]) maxHead(now time.Time) (maxHead uint64) {
+	for _, endpoint := range hp.endpoints {
+		if endpoint.hasHead(now) && endpoint.head > maxHead {
+			maxHead = endpoint.head
+		}
+	}
+	return
+}
+
+func (hp *HealthProvider[T]) find(el T) *endpointState[T] {
+	for _, endpoint := range hp.endpoints {
+		if any(endpoint.Element) == any(el) {
+			return endpoint
+		}
+	}
+	return nil
+}
+
+// TrackRequest implements the Tracker interface.
+func (hp *HealthProvider[T]) TrackRequest(el T, latency time.Duration, err error) {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	endpoint := hp.find(el)
+	if endpoint == nil {
+		return
+	}
+	now := time.Now()
+	endpoint.lastReq.Set()
+	endpoint.lastErr.Set(err)
+	endpoint.latencyMs = ewma(endpoint.latencyMs, float64(latency.Milliseconds()))
+	errorRate := endpoint.decayedErrorRate(now)
+	endpoint.errorRateUpdated = now
+	if err == nil {
+		endpoint.errorRate = ewma(errorRate, 0)
+		endpoint.failures = 0
+		endpoint.quarantinedUntil = time.Time{}
+		return
+	}
+	endpoint.errorRate = ewma(errorRate, 1)
+	endpoint.failures++
+	endpoint.quarantinedUntil = now.Add(quarantineDuration(endpoint.failures))
+}
+
+// TrackHead implements the Tracker interface.
+func (hp *HealthProvider[T]) TrackHead(el T, blockNumber uint64) {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	endpoint := hp.find(el)
+	if endpoint == nil {
+		return
+	}
+	now := time.Now()
+	if blockNumber >= endpoint.head || !endpoint.hasHead(now) {
+		endpoint.head = blockNumber
+		endpoint.headUpdated = now
+	}
+}
+
+// Health returns the health reports of each element.
+func (hp *HealthProvider[T]) Health() health.Reports {
+	hp.mu.RLock()
+	defer hp.mu.RUnlock()
+
+	now := time.Now()
+	maxHead := hp.maxHead(now)
+	var reports health.Reports
+	for _, endpoint := range hp.endpoints {
+		status := health.StatusOK
+		if endpoint.quarantinedUntil.After(now) {
+			status = health.StatusDown
+		}
+		reports = append(reports, &health.Report{
+			Name:   fmt.Sprintf("endpoint.%s.score", endpoint.Name),
+			Status: status,
+			Details: fmt.Sprintf(
+				"score=%.0f latency=%.0fms error-rate=%.2f head-lag=%d",
+				endpoint.score(maxHead, now), endpoint.latencyMs, endpoint.decayedErrorRate(now), endpoint.headLag(maxHead, now),
+			),
+		})
+		reports = append(reports,
+			endpoint.lastReq.GetReport(fmt.Sprintf("endpoint.%s.request.time", endpoint.Name)),
+			endpoint.lastErr.GetReport(fmt.Sprintf("endpoint.%s.request.error", endpoint.Name)),
+		)
+	}
+	return reports
+}
+
+// score is a latency-like number where lower is better.
+func (es *endpointState[T]) score(maxHead uint64, now time.Time) float64 {
+	return es.latencyMs + es.decayedErrorRate(now)*errorPenaltyMs + float64(es.headLag(maxHead, now))*headLagPenaltyMs
+}
+
+// decayedErrorRate halves the error rate in every half-life since the last request so that
+// an element which is not provided anymore after a few errors can recover.
+func (es *endpointState[T]) decayedErrorRate(now time.Time) float64 {
+	if es.errorRate == 0 {
+		return 0
+	}
+	halfLives := now.Sub(es.errorRateUpdated).Seconds() / errorRateHalfLife.Seconds()
+	return es.errorRate * math.Pow(0.5, halfLives)
+}
+
+// hasHead tells if the element reported a head recently.
+func (es *endpointState[T]) hasHead(now time.Time) bool {
+	return !es.headUpdated.IsZero() && now.Sub(es.headUpdated) < headExpiry
+}
+
+// headLag is zero if the head of the element is unknown or expired.
+func (es *endpointState[T]) headLag(maxHead uint64, now time.Time) uint64 {
+	if !es.hasHead(now) || es.head >= maxHead {
+		return 0
+	}
+	return maxHead - es.head
+}
+
+func ewma(prev, value float64) float64 {
+	return prev*(1-ewmaWeight) + value*ewmaWeight
+}
+
+func quarantineDuration(failures int) time.Duration {
+	d := minQuarantine
+	for i := 1; i < failures && d < maxQuarantine; i++ {
+		d *= quarantineMultiple
+	}
+	if d > maxQuarantine {
+		d = maxQuarantine
+	}
+	return d
+}
+
+// Ensuring type checks below.
+
+var _ Provider[*dummyElement] = &HealthProvider[*dummyElement]{}
+var _ Tracker[*dummyElement] = &HealthProvider[*dummyElement]{}
@@ -0,0 +1,100 @@
+package provider_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/ethereum/provider"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthProvider(t *testing.T) {
+	r := require.New(t)
+
+	el1 := &testElement{}
+	el2 := &testElement{}
+	el3 := &testElement{}
+
+	p := provider.NewHealthProvider(
+		provider.Endpoint[*testElement]{Name: "1", Element: el1},
+		provider.Endpoint[*testElement]{Name: "2", Element: el2},
+		provider.Endpoint[*testElement]{Name: "3", Element: el3},
+	)
+
+	// all equal at first
+	r.Equal(el1, p.Provide())
+
+	// slow elements score worse
+	p.TrackRequest(el1, 500*time.Millisecond, nil)
+	p.TrackRequest(el2, 100*time.Millisecond, nil)
+	p.TrackRequest(el3, 200*time.Millisecond, nil)
+	r.Equal(el2, p.Provide())
+
+	// failing element gets quarantined
+	p.TrackRequest(el2, 100*time.Millisecond, errors.New("failed"))
+	r.Equal(el3, p.Next())
+
+	// lagging element scores worse
+	p.TrackHead(el1, 100)
+	p.TrackHead(el3, 90)
+	r.Equal(el1, p.Provide())
+
+	reports := p.Health()
+	report, ok := reports.GetByName("endpoint.2.score")
+	r.True(ok)
+	r.Equal(health.StatusDown, report.Status)
+	report, ok = reports.GetByName("endpoint.2.request.error")
+	r.True(ok)
+	r.Equal(health.StatusFailing, report.Status)
+	report, ok = reports.GetByName("endpoint.3.score")
+	r.True(ok)
+	r.Equal(health.StatusOK, report.Status)
+	r.Contains(report.Details, "head-lag=10")
+
+	p.Close()
+	r.True(el1.closed)
+	r.True(el2.closed)
+	r.True(el3.closed)
+}
+
+func TestHealthProvider_AllQuarantined(t *testing.T) {
+	r := require.New(t)
+
+	el1 := &testElement{}
+	el2 := &testElement{}
+
+	p := provider.NewHealthProvider(
+		provider.Endpoint[*testElement]{Name: "1", Element: el1},
+		provider.Endpoint[*testElement]{Name: "2", Element: el2},
+	)
+
+	// the element which failed more is quarantined longer
+	p.TrackRequest(el1, time.Millisecond, errors.New("failed"))
+	p.TrackRequest(el1, time.Millisecond, errors.New("failed"))
+	p.TrackRequest(el2, time.Millisecond, errors.New("failed"))
+	r.Equal(el2, p.Provide())
+}
+
+func TestHealthProvider_UnknownHead(t *testing.T) {
+	r := require.New(t)
+
+	el1 := &testElement{}
+	el2 := &testElement{}
+
+	p := provider.NewHealthProvider(
+		provider.Endpoint[*testElement]{Name: "1", Element: el1},
+		provider.Endpoint[*testElement]{Name: "2", Element: el2},
+	)
+
+	// the element which never reported a head is not considered lagging
+	p.TrackRequest(el1, 100*time.Millisecond, nil)
+	p.TrackRequest(el2, 200*time.Millisecond, nil)
+	p.TrackHead(el2, 100)
+	r.Equal(el1, p.Provide())
+
+	report, ok := p.Health().GetByName("endpoint.1.score")
+	r.True(ok)
+	r.Contains(report.Details, "head-lag=0")
+}
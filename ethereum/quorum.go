@@ -0,0 +1,326 @@
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/ethereum/provider"
+	"github.com/forta-network/forta-core-go/protocol"
+)
+
+// ErrNoQuorum is returned when not enough providers agree on a response.
+var ErrNoQuorum = errors.New("no quorum")
+
+var errQuorumProviders = errors.New("quorum client needs at least two providers")
+
+const (
+	defaultQuorumTimeout       = time.Minute
+	defaultQuorumRetryInterval = time.Second
+)
+
+// QuorumConfig contains the quorum client settings.
+type QuorumConfig struct {
+	// Quorum is the number of providers which should return the same response.
+	// The default is the majority of the providers.
+	Quorum int
+	// Timeout is the max time to wait for the responses of the providers.
+	Timeout time.Duration
+	// RetryInterval is how often a provider which has not found the data yet is asked
+	// again within the timeout, since it can be lagging behind the others.
+	RetryInterval time.Duration
+	// MetricHandler receives a metric whenever the providers disagree.
+	MetricHandler func(metric *protocol.AgentMetric)
+}
+
+// quorumClient sends the block, log and trace requests to all providers and cross-checks
+// the responses. The rest of the requests are sent only to the primary client.
+type quorumClient struct {
+	Client
+	endpoints []provider.Endpoint[Client]
+	cfg       QuorumConfig
+
+	mismatches    uint64
+	mismatchCount health.NumberTracker
+	mismatchErr   health.ErrorTracker
+	mu            sync.Mutex
+}
+
+type quorumResponse struct {
+	index int
+	hash  string
+	value interface{}
+	err   error
+}
+
+// crossCheck makes the same call with all providers and returns the response as soon as enough
+// providers agree. The rest of the responses are still compared in the background. Only
+// different successful responses count as a mismatch.
+func (qc *quorumClient) crossCheck(
+	ctx context.Context, name string,
+	call func(ctx context.Context, client Client) (value interface{}, hash string, err error),
+) (interface{}, error) {
+	cCtx, cancel := context.WithTimeout(ctx, qc.cfg.Timeout)
+	responses := make(chan *quorumResponse, len(qc.endpoints))
+	for i, endpoint := range qc.endpoints {
+		go func(i int, client Client) {
+			value, hash, err := qc.callUntilFound(cCtx, client, call)
+			responses <- &quorumResponse{index: i, value: value, hash: hash, err: err}
+		}(i, endpoint.Element)
+	}
+
+	var (
+		received []*quorumResponse
+		accepted *quorumResponse
+		votes    = make(map[string]int)
+	)
+	for accepted == nil && len(received) < len(qc.endpoints) {
+		resp := <-responses
+		received = append(received, resp)
+		if resp.err != nil {
+			continue
+		}
+		votes[resp.hash]++
+		if votes[resp.hash] >= qc.cfg.Quorum {
+			accepted = resp
+		}
+	}
+
+	if accepted == nil {
+		cancel()
+		// failing to get any response is not a disagreement
+		if len(votes) == 0 {
+			return nil, received[0].err
+		}
+		err := fmt.Errorf("%s: %w: %s", name, ErrNoQuorum, qc.describe(received))
+		if len(votes) > 1 {
+			qc.mismatch(name, err)
+		}
+		return nil, err
+	}
+
+	go func() {
+		defer cancel()
+		for len(received) < len(qc.endpoints) {
+			received = append(received, <-responses)
+		}
+		for _, resp := range received {
+			if resp.err == nil && resp.hash != accepted.hash {
+				qc.mismatch(name, fmt.Errorf("%s: providers disagree: %s", name, qc.describe(received)))
+				return
+			}
+		}
+		qc.mismatchErr.Set(nil)
+	}()
+
+	return accepted.value, nil
+}
+
+// callUntilFound repeats the call while the provider does not find the data, until the context is done.
+func (qc *quorumClient) callUntilFound(
+	ctx context.Context, client Client,
+	call func(ctx context.Context, client Client) (value interface{}, hash string, err error),
+) (interface{}, string, error) {
+	for {
+		value, hash, err := call(ctx, client)
+		if !errors.Is(err, ErrNotFound) {
+			return value, hash, err
+		}
+		select {
+		case <-ctx.Done():
+			return value, hash, err
+		case <-time.After(qc.cfg.RetryInterval):
+		}
+	}
+}
+
+// describe lists the response hashes or the errors of the providers.
+func (qc *quorumClient) describe(responses []*quorumResponse) string {
+	var details []string
+	for _, resp := range responses {
+		result := resp.hash
+		if resp.err != nil {
+			result = resp.err.Error()
+		}
+		details = append(details, fmt.Sprintf("%s=%s", qc.endpoints[resp.index].Name, result))
+	}
+	return strings.Join(details, ", ")
+}
+
+func (qc *quorumClient) mismatch(name string, err error) {
+	qc.mu.Lock()
+	qc.mismatches++
+	qc.mismatchCount.Set(float64(qc.mismatches))
+	qc.mu.Unlock()
+	qc.mismatchErr.Set(err)
+	log.WithError(err).Warn("provider responses do not match")
+
+	if qc.cfg.MetricHandler != nil {
+		qc.cfg.MetricHandler(&protocol.AgentMetric{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Name:      domain.MetricJSONRPCQuorumMismatch,
+			Value:     1,
+			Details:   name,
+		})
+	}
+}
+
+// BlockByNumber returns the block by number which the providers agree on. The latest block
+// is not cross-checked because the providers can be at different heights.
+func (qc *quorumClient) BlockByNumber(ctx context.Context, number *big.Int) (*domain.Block, error) {
+	if number == nil {
+		return qc.Client.BlockByNumber(ctx, number)
+	}
+	name := fmt.Sprintf("%s(%s)", blocksByNumber, number)
+	value, err := qc.crossCheck(ctx, name, func(ctx context.Context, client Client) (interface{}, string, error) {
+		block, err := client.BlockByNumber(ctx, number)
+		if err != nil {
+			return nil, "", err
+		}
+		return block, BlockResponseHash(block), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*domain.Block), nil
+}
+
+// GetLogs returns the logs which the providers agree on. The queries which are not pinned
+// to a block range or a block hash are not cross-checked.
+func (qc *quorumClient) GetLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	if q.BlockHash == nil && q.ToBlock == nil {
+		return qc.Client.GetLogs(ctx, q)
+	}
+	name := fmt.Sprintf("%s(%s)", getLogs, logsQueryName(q))
+	value, err := qc.crossCheck(ctx, name, func(ctx context.Context, client Client) (interface{}, string, error) {
+		logs, err := client.GetLogs(ctx, q)
+		if err != nil {
+			return nil, "", err
+		}
+		return logs, LogsResponseHash(logs), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]types.Log), nil
+}
+
+// TraceBlock returns the traces which the providers agree on.
+func (qc *quorumClient) TraceBlock(ctx context.Context, number *big.Int) ([]domain.Trace, error) {
+	name := fmt.Sprintf("%s(%s)", traceBlock, number)
+	value, err := qc.crossCheck(ctx, name, func(ctx context.Context, client Client) (interface{}, string, error) {
+		traces, err := client.TraceBlock(ctx, number)
+		if err != nil {
+			return nil, "", err
+		}
+		return traces, TraceResponseHash(traces), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]domain.Trace), nil
+}
+
+func logsQueryName(q ethereum.FilterQuery) string {
+	if q.BlockHash != nil {
+		return q.BlockHash.Hex()
+	}
+	return fmt.Sprintf("%s-%s", q.FromBlock, q.ToBlock)
+}
+
+// Close closes the primary client and the clients of all providers.
+func (qc *quorumClient) Close() {
+	qc.Client.Close()
+	for _, endpoint := range qc.endpoints {
+		endpoint.Element.Close()
+	}
+}
+
+// Name returns the name of this implementation.
+func (qc *quorumClient) Name() string {
+	return fmt.Sprintf("quorum-%s", qc.Client.Name())
+}
+
+// Health implements the health.Reporter interface.
+func (qc *quorumClient) Health() health.Reports {
+	return append(qc.Client.Health(),
+		qc.mismatchCount.GetReport("quorum.mismatch.count"),
+		qc.mismatchErr.GetReport("quorum.mismatch.error"),
+	)
+}
+
+// NewQuorumClient creates a client which cross-checks the responses of given providers.
+// The requests which are not cross-checked are routed by using the health of the providers.
+// The providers use the same connections with the primary client.
+func NewQuorumClient(ctx context.Context, apiName string, cfg QuorumConfig, apiURLs ...string) (*quorumClient, error) {
+	if len(apiURLs) < 2 {
+		return nil, errQuorumProviders
+	}
+	var (
+		rpcEndpoints []provider.Endpoint[Subscriber]
+		endpoints    []provider.Endpoint[Client]
+	)
+	for i, apiURL := range apiURLs {
+		c, err := newInternalRPCClient(ctx, apiURL)
+		if err != nil {
+			for _, endpoint := range rpcEndpoints {
+				endpoint.Element.Close()
+			}
+			return nil, err
+		}
+		name := endpointName(i, apiURL)
+		rpcEndpoints = append(rpcEndpoints, provider.Endpoint[Subscriber]{Name: name, Element: c})
+		client, err := NewStreamEthClientWithRPCClient(ctx, fmt.Sprintf("%s-%s", apiName, name), isWebsocket(apiURL), sharedRPCClient{c})
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, provider.Endpoint[Client]{Name: name, Element: client})
+	}
+	return NewQuorumClientWithClients(newStreamEthClientWithEndpoints(apiName, rpcEndpoints...), cfg, endpoints...)
+}
+
+// sharedRPCClient is a connection which is closed by the primary client.
+type sharedRPCClient struct {
+	Subscriber
+}
+
+// Close does nothing.
+func (sharedRPCClient) Close() {}
+
+// NewQuorumClientWithClients creates a client which cross-checks the responses of given clients.
+func NewQuorumClientWithClients(primary Client, cfg QuorumConfig, endpoints ...provider.Endpoint[Client]) (*quorumClient, error) {
+	if len(endpoints) < 2 {
+		return nil, errQuorumProviders
+	}
+	if cfg.Quorum == 0 {
+		cfg.Quorum = len(endpoints)/2 + 1
+	}
+	if cfg.Quorum < 1 || cfg.Quorum > len(endpoints) {
+		return nil, fmt.Errorf("quorum must be between 1 and %d", len(endpoints))
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultQuorumTimeout
+	}
+	if cfg.RetryInterval == 0 {
+		cfg.RetryInterval = defaultQuorumRetryInterval
+	}
+	return &quorumClient{
+		Client:    primary,
+		endpoints: endpoints,
+		cfg:       cfg,
+	}, nil
+}
+
+// Ensuring type checks below.
+
+var _ Client = &quorumClient{}
@@ -0,0 +1,124 @@
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/domain"
+	mocks "github.com/forta-network/forta-core-go/ethereum/mocks"
+	"github.com/forta-network/forta-core-go/ethereum/provider"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/utils"
+)
+
+func initQuorumClient(t *testing.T, cfg QuorumConfig) (*quorumClient, []*mocks.MockClient) {
+	r := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	primary := mocks.NewMockClient(ctrl)
+	var clients []*mocks.MockClient
+	var endpoints []provider.Endpoint[Client]
+	for _, name := range []string{"1", "2", "3"} {
+		client := mocks.NewMockClient(ctrl)
+		clients = append(clients, client)
+		endpoints = append(endpoints, provider.Endpoint[Client]{Name: name, Element: client})
+	}
+	primary.EXPECT().Health().Return(nil).AnyTimes()
+
+	qc, err := NewQuorumClientWithClients(primary, cfg, endpoints...)
+	r.NoError(err)
+	return qc, clients
+}
+
+func TestQuorumClient_BlockByNumber(t *testing.T) {
+	r := require.New(t)
+
+	metrics := make(chan *protocol.AgentMetric, 1)
+	qc, clients := initQuorumClient(t, QuorumConfig{
+		MetricHandler: func(metric *protocol.AgentMetric) {
+			metrics <- metric
+		},
+	})
+
+	ctx := context.Background()
+	blockNum := big.NewInt(1)
+	block := &domain.Block{Hash: "0x01", Transactions: []domain.Transaction{{Hash: "0x0a"}}}
+	clients[0].EXPECT().BlockByNumber(gomock.Any(), blockNum).Return(block, nil)
+	clients[1].EXPECT().BlockByNumber(gomock.Any(), blockNum).Return(block, nil)
+	clients[2].EXPECT().BlockByNumber(gomock.Any(), blockNum).Return(&domain.Block{Hash: "0x02"}, nil)
+
+	result, err := qc.BlockByNumber(ctx, blockNum)
+	r.NoError(err)
+	r.Equal(block.Hash, result.Hash)
+
+	select {
+	case metric := <-metrics:
+		r.Equal(domain.MetricJSONRPCQuorumMismatch, metric.Name)
+	case <-time.After(time.Second):
+		r.FailNow("should have received the mismatch metric")
+	}
+	report, ok := qc.Health().GetByName("quorum.mismatch.error")
+	r.True(ok)
+	r.Equal(health.StatusFailing, report.Status)
+}
+
+func TestQuorumClient_NoQuorum(t *testing.T) {
+	r := require.New(t)
+
+	qc, clients := initQuorumClient(t, QuorumConfig{})
+
+	ctx := context.Background()
+	blockNum := big.NewInt(1)
+	clients[0].EXPECT().TraceBlock(gomock.Any(), blockNum).Return([]domain.Trace{{TransactionHash: utils.StringPtr("0x01")}}, nil)
+	clients[1].EXPECT().TraceBlock(gomock.Any(), blockNum).Return([]domain.Trace{{TransactionHash: utils.StringPtr("0x02")}}, nil)
+	clients[2].EXPECT().TraceBlock(gomock.Any(), blockNum).Return(nil, errors.New("failed"))
+
+	_, err := qc.TraceBlock(ctx, blockNum)
+	r.ErrorIs(err, ErrNoQuorum)
+}
+
+func TestQuorumClient_NotFound(t *testing.T) {
+	r := require.New(t)
+
+	metrics := make(chan *protocol.AgentMetric, 1)
+	qc, clients := initQuorumClient(t, QuorumConfig{
+		Quorum:        3,
+		Timeout:       time.Millisecond * 100,
+		RetryInterval: time.Millisecond,
+		MetricHandler: func(metric *protocol.AgentMetric) {
+			metrics <- metric
+		},
+	})
+
+	ctx := context.Background()
+	blockNum := big.NewInt(1)
+	block := &domain.Block{Hash: "0x01"}
+	clients[0].EXPECT().BlockByNumber(gomock.Any(), blockNum).Return(block, nil).Times(2)
+	clients[1].EXPECT().BlockByNumber(gomock.Any(), blockNum).Return(block, nil).Times(2)
+
+	// the lagging provider is asked again
+	gomock.InOrder(
+		clients[2].EXPECT().BlockByNumber(gomock.Any(), blockNum).Return(nil, ErrNotFound).Times(2),
+		clients[2].EXPECT().BlockByNumber(gomock.Any(), blockNum).Return(block, nil),
+	)
+	result, err := qc.BlockByNumber(ctx, blockNum)
+	r.NoError(err)
+	r.Equal(block.Hash, result.Hash)
+
+	// a provider which never finds the data is not a mismatch
+	clients[2].EXPECT().BlockByNumber(gomock.Any(), blockNum).Return(nil, ErrNotFound).MinTimes(1)
+	_, err = qc.BlockByNumber(ctx, blockNum)
+	r.ErrorIs(err, ErrNoQuorum)
+	select {
+	case <-metrics:
+		r.FailNow("should not have received a mismatch metric")
+	default:
+	}
+}
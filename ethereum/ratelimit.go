@@ -0,0 +1,142 @@
+package ethereum
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-core-go/protocol/settings"
+)
+
+// RateLimitConfig contains the token bucket limits which are applied per provider.
+type RateLimitConfig struct {
+	// Default is shared by the methods which do not have a specific limit.
+	Default *settings.RateLimit
+	// Methods contains the limits of specific JSON-RPC methods.
+	Methods map[string]*settings.RateLimit
+}
+
+// RateLimitFromChainSettings creates the rate limit config from the chain settings.
+func RateLimitFromChainSettings(chainSettings *settings.ChainSettings) RateLimitConfig {
+	return RateLimitConfig{Default: chainSettings.JsonRpcRateLimiting}
+}
+
+// tokenBucket refills at a constant rate up to the burst size.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+func newTokenBucket(limit *settings.RateLimit) *tokenBucket {
+	if limit == nil || limit.Rate <= 0 {
+		return nil
+	}
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// reserve takes n tokens and returns how long to wait before using them.
+func (tb *tokenBucket) reserve(n int) time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := time.Now()
+	tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	tb.last = now
+
+	tb.tokens -= float64(n)
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
+
+// cancel gives back n reserved tokens.
+func (tb *tokenBucket) cancel(n int) {
+	tb.mu.Lock()
+	tb.tokens += float64(n)
+	tb.mu.Unlock()
+}
+
+// Wait waits until n tokens are available.
+func (tb *tokenBucket) Wait(ctx context.Context, n int) (time.Duration, error) {
+	d := tb.reserve(n)
+	if d == 0 {
+		return 0, nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return d, nil
+	case <-ctx.Done():
+		tb.cancel(n)
+		return 0, ctx.Err()
+	}
+}
+
+// rateLimiter keeps a token bucket per provider and method.
+type rateLimiter struct {
+	cfg     RateLimitConfig
+	buckets map[Subscriber]map[string]*tokenBucket
+	mu      sync.Mutex
+}
+
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	return &rateLimiter{
+		cfg:     cfg,
+		buckets: make(map[Subscriber]map[string]*tokenBucket),
+	}
+}
+
+// Wait waits until the provider can be called n times with the method.
+func (rl *rateLimiter) Wait(ctx context.Context, rpcClient Subscriber, method string, n int) (time.Duration, error) {
+	bucket := rl.bucket(rpcClient, method)
+	if bucket == nil {
+		return 0, nil
+	}
+	return bucket.Wait(ctx, n)
+}
+
+func (rl *rateLimiter) bucket(rpcClient Subscriber, method string) *tokenBucket {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	limit, ok := rl.cfg.Methods[method]
+	if !ok {
+		// methods without a specific limit share the default bucket
+		limit = rl.cfg.Default
+		method = ""
+	}
+	providerBuckets, ok := rl.buckets[rpcClient]
+	if !ok {
+		providerBuckets = make(map[string]*tokenBucket)
+		rl.buckets[rpcClient] = providerBuckets
+	}
+	bucket, ok := providerBuckets[method]
+	if !ok {
+		bucket = newTokenBucket(limit)
+		providerBuckets[method] = bucket
+	}
+	return bucket
+}
+
+// methodOf extracts the method from the request names like "eth_getBlockByNumber(1)".
+func methodOf(name string) string {
+	return strings.SplitN(name, "(", 2)[0]
+}
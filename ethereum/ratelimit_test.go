@@ -0,0 +1,103 @@
+package ethereum
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/goccy/go-json"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/protocol/settings"
+)
+
+func TestTokenBucket(t *testing.T) {
+	r := require.New(t)
+
+	tb := newTokenBucket(&settings.RateLimit{Rate: 10, Burst: 2})
+	ctx := context.Background()
+
+	// burst is available right away
+	for i := 0; i < 2; i++ {
+		waited, err := tb.Wait(ctx, 1)
+		r.NoError(err)
+		r.Zero(waited)
+	}
+
+	// next one waits for the refill
+	waited, err := tb.Wait(ctx, 1)
+	r.NoError(err)
+	r.Greater(waited, 50*time.Millisecond)
+
+	// cancelled wait gives the token back
+	tb.tokens = -1
+	cCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = tb.Wait(cCtx, 1)
+	r.ErrorIs(err, context.Canceled)
+	r.Greater(tb.tokens, float64(-1))
+
+	r.Nil(newTokenBucket(&settings.RateLimit{}))
+}
+
+func TestEthClient_RateLimit(t *testing.T) {
+	r := require.New(t)
+
+	ethClient, client, ctx := initClient(t)
+	ethClient.SetRateLimit(RateLimitConfig{
+		Methods: map[string]*settings.RateLimit{
+			chainId: {Rate: 20, Burst: 1},
+		},
+	})
+
+	client.EXPECT().CallContext(gomock.Any(), gomock.Any(), chainId).Return(nil).Times(2)
+	client.EXPECT().CallContext(gomock.Any(), gomock.Any(), blockNumber).Return(nil).Times(1)
+
+	// the first call uses the burst and the second one waits
+	_, _ = ethClient.ChainID(ctx)
+	_, _ = ethClient.ChainID(ctx)
+	// no limit for the other methods
+	_, _ = ethClient.BlockNumber(ctx)
+
+	report, ok := ethClient.Health().GetByName("request.rate-limit.wait.count")
+	r.True(ok)
+	r.Equal("1", report.Details)
+}
+
+func TestEthClient_BatchRateLimit(t *testing.T) {
+	r := require.New(t)
+
+	ethClient, client, ctx := initClient(t)
+	ethClient.SetRateLimit(RateLimitConfig{
+		Methods: map[string]*settings.RateLimit{
+			blocksByNumber: {Rate: 20, Burst: 1},
+		},
+	})
+
+	client.EXPECT().BatchCallContext(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, b []rpc.BatchElem) error {
+		for i := range b {
+			block, _ := json.Marshal(domain.Block{Hash: "0x01"})
+			b[i].Error = json.Unmarshal(block, b[i].Result)
+		}
+		return nil
+	}).Times(2)
+
+	// each call in the batch takes a token: the first batch waits for two tokens
+	// and the second one waits for one more
+	start := time.Now()
+	_, errs := ethClient.BlocksByNumbers(ctx, []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)})
+	for _, err := range errs {
+		r.NoError(err)
+	}
+	_, errs = ethClient.BlocksByNumbers(ctx, []*big.Int{big.NewInt(4)})
+	r.NoError(errs[0])
+	r.GreaterOrEqual(time.Since(start), 140*time.Millisecond)
+
+	report, ok := ethClient.Health().GetByName("request.rate-limit.wait.count")
+	r.True(ok)
+	r.Equal("2", report.Details)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	eth "github.com/ethereum/go-ethereum"
@@ -15,11 +16,18 @@ import (
 	"github.com/forta-network/forta-core-go/clients/health"
 	"github.com/forta-network/forta-core-go/domain"
 	"github.com/forta-network/forta-core-go/ethereum"
+	"github.com/forta-network/forta-core-go/feeds/checkpoint"
 	"github.com/forta-network/forta-core-go/utils"
 )
 
 var ErrEndBlockReached = errors.New("end block reached")
 
+// DefaultBlockFeedCheckpoint is the default checkpoint name of the block feed.
+const DefaultBlockFeedCheckpoint = "block-feed"
+
+// DefaultPrefetchMaxBytes is the default max size of the prefetched data in memory.
+const DefaultPrefetchMaxBytes = 256 << 20
+
 type bfHandler struct {
 	Handler func(evt *domain.BlockEvent) error
 	ErrCh   chan<- error
@@ -36,15 +44,27 @@ type blockFeed struct {
 	chainID          *big.Int
 	tracing          bool
 	logs             bool
+	logsFromReceipts bool
 	started          bool
 	rateLimit        *time.Ticker
 	maxBlockAge      *time.Duration
 	subscriptionMode bool
+	checkpoint       checkpoint.Store
+	checkpointName   string
 
 	lastBlock health.MessageTracker
 
-	handlers   []bfHandler
-	handlersMu sync.RWMutex
+	handlers      []bfHandler
+	reorgHandlers []func(evt *domain.ReorgEvent) error
+	handlersMu    sync.RWMutex
+
+	window       *blockWindow
+	replayUntil  *big.Int
+	replacements map[int64]*domain.Block
+	prefetcher   *prefetcher
+
+	blockReceiptsUnsupported atomic.Bool
+	lastReorg                health.MessageTracker
 
 	chainLatestBlockNum *big.Int
 	chainLatestMu       sync.RWMutex
@@ -59,9 +79,36 @@ type BlockFeedConfig struct {
 	Tracing             bool
 	DisableLogs         bool
 	SkipBlocksOlderThan *time.Duration
+	// ReorgWindow is the number of recently delivered blocks kept for detecting
+	// chain reorganizations. Zero disables the detection. A value larger than the
+	// BlockThreshold of the chain settings is a good fit.
+	ReorgWindow int
+	// Checkpoint stores the last fully handled block. When it is set and Start is nil,
+	// the feed resumes from the block after the checkpoint.
+	Checkpoint     checkpoint.Store
+	CheckpointName string
+	// LogsFromReceipts makes the feed build the logs from the transaction receipts instead of
+	// using eth_getLogs. The receipts are attached to the block events as well.
+	LogsFromReceipts bool
+	// Prefetch is the number of blocks to fetch in parallel ahead of the block being delivered.
+	// The traces and the logs are fetched together with the block body. Zero disables prefetching.
+	Prefetch int
+	// PrefetchMaxBytes is the approximate max size of the prefetched data which is kept in memory.
+	// No more blocks are prefetched ahead while the budget is used up. The default is 256 MiB.
+	PrefetchMaxBytes int64
 }
 
 func (bf *blockFeed) initialize() error {
+	if bf.start == nil && bf.checkpoint != nil {
+		next, err := checkpoint.NextBlock(bf.checkpoint, bf.checkpointName)
+		if err != nil {
+			return fmt.Errorf("failed to load checkpoint: %v", err)
+		}
+		if next != nil {
+			log.WithField("checkpoint", bf.checkpointName).Infof("resuming from block %s", next)
+			bf.start = next.Add(next, big.NewInt(int64(bf.offset)))
+		}
+	}
 	if bf.start == nil {
 		res, err := bf.client.BlockByNumber(bf.ctx, nil)
 		if err != nil {
@@ -216,8 +263,8 @@ func (bf *blockFeed) Subscribe(handler func(evt *domain.BlockEvent) error) <-cha
 }
 
 // converts from types.Log to domain.LogEntry object
-func (bf *blockFeed) logsForBlock(blockNum *big.Int) ([]domain.LogEntry, error) {
-	logs, err := bf.client.GetLogs(bf.ctx, eth.FilterQuery{
+func (bf *blockFeed) logsForBlock(ctx context.Context, blockNum *big.Int) ([]domain.LogEntry, error) {
+	logs, err := bf.client.GetLogs(ctx, eth.FilterQuery{
 		FromBlock: blockNum,
 		ToBlock:   blockNum,
 	})
@@ -243,7 +290,8 @@ func (bf *blockFeed) forEachBlock() error {
 		if bf.ctx.Err() != nil {
 			return bf.ctx.Err()
 		}
-		if bf.rateLimit != nil {
+		// the prefetcher takes a tick for each job instead
+		if bf.rateLimit != nil && bf.prefetcher == nil {
 			<-bf.rateLimit.C
 		}
 
@@ -257,7 +305,7 @@ func (bf *blockFeed) forEachBlock() error {
 			logger.Info("end block reached - exiting")
 			return ErrEndBlockReached
 		}
-		if bf.cache.Exists(blockNumToAnalyze.String()) {
+		if bf.cache.Exists(blockNumToAnalyze.String()) && !bf.isReplaying(blockNumToAnalyze) {
 			logger.Info("already analyzed block - skipping")
 			currentBlockNum.Add(currentBlockNum, increment)
 			continue
@@ -269,19 +317,11 @@ func (bf *blockFeed) forEachBlock() error {
 			continue
 		}
 
-		block, err := bf.client.BlockByNumber(bf.ctx, currentBlockNum)
+		block, err := bf.nextBlock(currentBlockNum, blockNumToAnalyze)
 		if err != nil {
 			logger.WithError(err).Error("error getting block")
 			continue
 		}
-
-		if blockNumToAnalyze.Cmp(currentBlockNum) != 0 {
-			block, err = bf.client.BlockByNumber(bf.ctx, blockNumToAnalyze)
-			if err != nil {
-				logger.WithError(err).Error("error getting block")
-				continue
-			}
-		}
 		logger = logger.WithFields(log.Fields{
 			"blockHash":         block.Hash,
 			"blockToAnalyzeHex": block.Number,
@@ -298,11 +338,38 @@ func (bf *blockFeed) forEachBlock() error {
 			continue
 		}
 
+		if bf.window != nil {
+			reorgEvt, err := bf.detectReorg(blockNumToAnalyze, block)
+			if err != nil {
+				logger.WithError(err).Error("failed to check for chain reorg")
+				continue
+			}
+			if reorgEvt != nil {
+				resumeFrom := big.NewInt(utils.HexToInt64(reorgEvt.Replacement[0].Number))
+				logger.WithFields(log.Fields{
+					"depth":      reorgEvt.Depth,
+					"resumeFrom": resumeFrom.Uint64(),
+				}).Warn("chain reorg detected - redelivering canonical blocks")
+				bf.lastReorg.Set(fmt.Sprintf("depth=%d at block %s", reorgEvt.Depth, blockNumToAnalyze))
+				if err := bf.handleReorg(reorgEvt); err != nil {
+					return err
+				}
+				bf.saveCheckpoint(new(big.Int).Sub(resumeFrom, big.NewInt(1)))
+				if bf.prefetcher != nil {
+					bf.prefetcher.Reset()
+				}
+				bf.keepReplacement(reorgEvt.Replacement)
+				bf.replayUntil = new(big.Int).Sub(blockNumToAnalyze, big.NewInt(1))
+				currentBlockNum = resumeFrom.Add(resumeFrom, big.NewInt(int64(bf.offset)))
+				continue
+			}
+		}
+
 		bf.lastBlock.Set(blockNumToAnalyze.String())
 
 		var traces []domain.Trace
 		if bf.tracing {
-			traces, err = bf.traceClient.TraceBlock(bf.ctx, blockNumToAnalyze)
+			traces, err = bf.getTraces(blockNumToAnalyze)
 			if err != nil {
 				logger.WithError(err).Error("error tracing block")
 			}
@@ -315,9 +382,28 @@ func (bf *blockFeed) forEachBlock() error {
 			traces = nil
 		}
 
-		var logs []domain.LogEntry
-		if bf.logs {
-			logs, err = bf.logsForBlock(blockNumToAnalyze)
+		var (
+			logs     []domain.LogEntry
+			receipts []domain.TransactionReceipt
+		)
+		switch {
+		case bf.logs && bf.logsFromReceipts:
+			receipts, err = bf.getReceipts(blockNumToAnalyze, block)
+			if err != nil {
+				logger.WithError(err).Errorf("error getting receipts for block")
+				continue
+			}
+			if len(receipts) > 0 && block.Hash != utils.String(receipts[0].BlockHash) {
+				logger.WithFields(log.Fields{
+					"receiptBlockHash": utils.String(receipts[0].BlockHash),
+				}).Warn("receipt block hash != ethereum block hash, will retry")
+				bf.forget(blockNumToAnalyze)
+				continue
+			}
+			logs = logsFromReceipts(receipts)
+
+		case bf.logs:
+			logs, err = bf.getLogs(blockNumToAnalyze)
 			if err != nil {
 				logger.WithError(err).Errorf("error getting logs for block")
 				continue
@@ -336,6 +422,7 @@ func (bf *blockFeed) forEachBlock() error {
 			ChainID:   bf.chainID,
 			Traces:    traces,
 			Logs:      logs,
+			Receipts:  receipts,
 			Timestamps: &domain.TrackingTimestamps{
 				Block: *blockTs,
 				Feed:  time.Now().UTC(),
@@ -350,11 +437,32 @@ func (bf *blockFeed) forEachBlock() error {
 			}
 		}
 		bf.cache.Add(blockNumToAnalyze.String())
+		bf.saveCheckpoint(blockNumToAnalyze)
+		if bf.window != nil {
+			bf.window.Add(block)
+		}
+		if bf.isReplaying(blockNumToAnalyze) && blockNumToAnalyze.Cmp(bf.replayUntil) >= 0 {
+			bf.replayUntil = nil
+		}
 
 		currentBlockNum.Add(currentBlockNum, increment)
 	}
 }
 
+func (bf *blockFeed) saveCheckpoint(blockNum *big.Int) {
+	if bf.checkpoint == nil {
+		return
+	}
+	if err := bf.checkpoint.Save(bf.checkpointName, blockNum); err != nil {
+		log.WithError(err).WithField("block", blockNum.String()).Error("failed to save checkpoint")
+	}
+}
+
+// isReplaying tells if the block is being delivered again after a reorg.
+func (bf *blockFeed) isReplaying(blockNum *big.Int) bool {
+	return bf.replayUntil != nil && blockNum.Cmp(bf.replayUntil) <= 0
+}
+
 func blockIsTooOld(block *domain.Block, maxAge *time.Duration) (bool, *time.Duration) {
 	if maxAge == nil {
 		return false, nil
@@ -378,6 +486,7 @@ func (bf *blockFeed) Name() string {
 func (bf *blockFeed) Health() health.Reports {
 	return health.Reports{
 		bf.lastBlock.GetReport("last-block"),
+		bf.lastReorg.GetReport("last-reorg"),
 	}
 }
 
@@ -385,6 +494,12 @@ func NewBlockFeed(ctx context.Context, client ethereum.Client, traceClient ether
 	if cfg.Offset < 0 {
 		return nil, fmt.Errorf("offset cannot be below zero: offset=%d", cfg.Offset)
 	}
+	if cfg.Prefetch < 0 {
+		return nil, fmt.Errorf("prefetch cannot be below zero: prefetch=%d", cfg.Prefetch)
+	}
+	if cfg.ReorgWindow < 0 {
+		return nil, fmt.Errorf("reorg window cannot be below zero: reorgWindow=%d", cfg.ReorgWindow)
+	}
 	bf := &blockFeed{
 		start:            cfg.Start,
 		end:              cfg.End,
@@ -396,9 +511,21 @@ func NewBlockFeed(ctx context.Context, client ethereum.Client, traceClient ether
 		chainID:          cfg.ChainID,
 		tracing:          cfg.Tracing,
 		logs:             !cfg.DisableLogs,
+		logsFromReceipts: cfg.LogsFromReceipts,
 		rateLimit:        cfg.RateLimit,
 		maxBlockAge:      cfg.SkipBlocksOlderThan,
 		subscriptionMode: client.IsWebsocket(),
+		checkpoint:       cfg.Checkpoint,
+		checkpointName:   cfg.CheckpointName,
+	}
+	if bf.checkpointName == "" {
+		bf.checkpointName = DefaultBlockFeedCheckpoint
+	}
+	if cfg.ReorgWindow > 0 {
+		bf.window = newBlockWindow(cfg.ReorgWindow)
+	}
+	if cfg.Prefetch > 0 {
+		bf.prefetcher = newPrefetcher(bf, cfg.Prefetch, cfg.PrefetchMaxBytes)
 	}
 	return bf, nil
 }
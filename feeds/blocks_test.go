@@ -41,6 +41,9 @@ func (bf *mockBlockFeed) Subscribe(handler func(evt *domain.BlockEvent) error) <
 	return errCh
 }
 
+// SubscribeToReorgs implements the BlockFeed interface.
+func (bf *mockBlockFeed) SubscribeToReorgs(handler func(evt *domain.ReorgEvent) error) {}
+
 // Start implements the BlockFeed interface.
 func (bf *mockBlockFeed) Start() {}
 
@@ -315,3 +318,216 @@ func TestBlockFeed_ForEachBlock_WithOffset(t *testing.T) {
 	assert.Equal(t, 3, len(evts))
 	assertEvts(t, evts, blockEvent(block1), blockEvent(block2), blockEvent(block3))
 }
+
+func TestBlockFeed_ForEachBlock_Reorg(t *testing.T) {
+	bf, client, traceClient, ctx, _ := getTestBlockFeed(t)
+	bf.window = newBlockWindow(10)
+
+	block1 := blockWithParent(startHash, 1)
+	block2 := blockWithParent(block1.Hash, 2)
+	block3 := blockWithParent(block2.Hash, 3)
+
+	// block 2 and 3 get replaced by another fork
+	block2b := blockWithParent(block1.Hash, 2)
+	block2b.Hash = "0xfork2"
+	block3b := blockWithParent(block2b.Hash, 3)
+	block3b.Hash = "0xfork3"
+	block4b := blockWithParent(block3b.Hash, 4)
+
+	for _, blk := range []*domain.Block{block1, block2, block3} {
+		client.EXPECT().BlockByNumber(ctx, hexToBigInt(blk.Number)).Return(blk, nil).Times(1)
+		client.EXPECT().GetLogs(ctx, gomock.Any()).Return(nil, nil).Times(1)
+		traceClient.EXPECT().TraceBlock(ctx, hexToBigInt(blk.Number)).Return(nil, nil).Times(1)
+	}
+
+	// block 4 does not link to the delivered block 3, walking back to block 1
+	client.EXPECT().BlockByNumber(ctx, big.NewInt(4)).Return(block4b, nil).Times(1)
+	client.EXPECT().BlockByNumber(ctx, big.NewInt(3)).Return(block3b, nil).Times(1)
+	client.EXPECT().BlockByNumber(ctx, big.NewInt(2)).Return(block2b, nil).Times(1)
+
+	// redelivering the canonical chain without fetching the blocks again
+	for _, blk := range []*domain.Block{block2b, block3b, block4b} {
+		client.EXPECT().GetLogs(ctx, gomock.Any()).Return(nil, nil).Times(1)
+		traceClient.EXPECT().TraceBlock(ctx, hexToBigInt(blk.Number)).Return(nil, nil).Times(1)
+	}
+
+	var reorgs []*domain.ReorgEvent
+	bf.SubscribeToReorgs(func(evt *domain.ReorgEvent) error {
+		reorgs = append(reorgs, evt)
+		return nil
+	})
+
+	count := 0
+	var evts []*domain.BlockEvent
+	bf.Subscribe(func(evt *domain.BlockEvent) error {
+		count++
+		evts = append(evts, evt)
+		if count == 6 {
+			return testErr
+		}
+		return nil
+	})
+	res := bf.forEachBlock()
+	assert.Error(t, testErr, res)
+	assertEvts(t, evts,
+		blockEvent(block1), blockEvent(block2), blockEvent(block3),
+		blockEvent(block2b), blockEvent(block3b), blockEvent(block4b),
+	)
+
+	assert.Len(t, reorgs, 1)
+	assert.Equal(t, 2, reorgs[0].Depth)
+	assert.Equal(t, block1, reorgs[0].CommonAncestor)
+	assert.Equal(t, []*domain.Block{block2, block3}, reorgs[0].Orphaned)
+	assert.Equal(t, []*domain.Block{block2b, block3b, block4b}, reorgs[0].Replacement)
+}
+
+func TestBlockFeed_ForEachBlock_Prefetch(t *testing.T) {
+	bf, client, traceClient, _, _ := getTestBlockFeed(t)
+	bf.end = big.NewInt(3)
+	bf.prefetcher = newPrefetcher(bf, 2, 0)
+
+	block1 := blockWithParent(startHash, 1)
+	block2 := blockWithParent(block1.Hash, 2)
+	block3 := blockWithParent(block2.Hash, 3)
+
+	// fetched concurrently and ahead of time
+	for _, blk := range []*domain.Block{block1, block2, block3} {
+		client.EXPECT().BlockByNumber(gomock.Any(), hexToBigInt(blk.Number)).Return(blk, nil).Times(1)
+		traceClient.EXPECT().TraceBlock(gomock.Any(), hexToBigInt(blk.Number)).Return(nil, nil).Times(1)
+	}
+	client.EXPECT().GetLogs(gomock.Any(), gomock.Any()).Return(nil, nil).Times(3)
+
+	var evts []*domain.BlockEvent
+	bf.Subscribe(func(evt *domain.BlockEvent) error {
+		evts = append(evts, evt)
+		return nil
+	})
+	res := bf.forEachBlock()
+	assert.ErrorIs(t, res, ErrEndBlockReached)
+	assertEvts(t, evts, blockEvent(block1), blockEvent(block2), blockEvent(block3))
+}
+
+func TestBlockFeed_ForEachBlock_PrefetchOffsetRateLimit(t *testing.T) {
+	bf, client, traceClient, _, _ := getTestBlockFeed(t)
+	bf.start = big.NewInt(2)
+	bf.end = big.NewInt(2)
+	bf.offset = 1
+	bf.rateLimit = time.NewTicker(20 * time.Millisecond)
+	defer bf.rateLimit.Stop()
+	bf.prefetcher = newPrefetcher(bf, 5, 0)
+
+	block1 := blockWithParent(startHash, 1)
+	block2 := blockWithParent(block1.Hash, 2)
+	block3 := blockWithParent(block2.Hash, 3)
+
+	// only the analyzed blocks are prefetched in full
+	for _, blk := range []*domain.Block{block1, block2} {
+		client.EXPECT().BlockByNumber(gomock.Any(), hexToBigInt(blk.Number)).Return(blk, nil).Times(1)
+		traceClient.EXPECT().TraceBlock(gomock.Any(), hexToBigInt(blk.Number)).Return(nil, nil).Times(1)
+	}
+	client.EXPECT().GetLogs(gomock.Any(), gomock.Any()).Return(nil, nil).Times(2)
+
+	// the current blocks are fetched to check that they exist
+	client.EXPECT().BlockByNumber(gomock.Any(), hexToBigInt(block2.Number)).Return(block2, nil).Times(1)
+	client.EXPECT().BlockByNumber(gomock.Any(), hexToBigInt(block3.Number)).Return(block3, nil).Times(1)
+
+	var evts []*domain.BlockEvent
+	bf.Subscribe(func(evt *domain.BlockEvent) error {
+		evts = append(evts, evt)
+		return nil
+	})
+	start := time.Now()
+	res := bf.forEachBlock()
+	assert.ErrorIs(t, res, ErrEndBlockReached)
+	assertEvts(t, evts, blockEvent(block1), blockEvent(block2))
+	// each job waited for a tick
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestPrefetcher_MaxBytes(t *testing.T) {
+	bf, client, traceClient, _, _ := getTestBlockFeed(t)
+	bf.end = big.NewInt(10)
+	p := newPrefetcher(bf, 3, 1)
+
+	client.EXPECT().BlockByNumber(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, num *big.Int) (*domain.Block, error) {
+		return blockWithParent(startHash, int(num.Int64())), nil
+	}).AnyTimes()
+	traceClient.EXPECT().TraceBlock(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	client.EXPECT().GetLogs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	p.Get(1)
+	for _, job := range p.jobs {
+		<-job.done
+	}
+	assert.Len(t, p.jobs, 4)
+
+	// the fetched blocks use up the budget so nothing more is scheduled ahead
+	p.Get(2)
+	assert.Len(t, p.jobs, 3)
+	assert.NotContains(t, p.jobs, int64(5))
+}
+
+func TestBlockFeed_ForEachBlock_LogsFromReceipts(t *testing.T) {
+	bf, client, traceClient, ctx, _ := getTestBlockFeed(t)
+	bf.logsFromReceipts = true
+
+	block1 := blockWithParent(startHash, 1)
+	block1.Transactions = []domain.Transaction{{Hash: "0x1"}, {Hash: "0x2"}}
+	block2 := blockWithParent(block1.Hash, 2)
+	block2.Transactions = []domain.Transaction{{Hash: "0x3"}}
+
+	receipt := func(blk *domain.Block, txHash, txIndex, status string, logCount int) *domain.TransactionReceipt {
+		r := &domain.TransactionReceipt{
+			BlockHash:        utils.StringPtr(blk.Hash),
+			BlockNumber:      utils.StringPtr(blk.Number),
+			TransactionHash:  utils.StringPtr(txHash),
+			TransactionIndex: utils.StringPtr(txIndex),
+			Status:           utils.StringPtr(status),
+		}
+		for i := 0; i < logCount; i++ {
+			r.Logs = append(r.Logs, domain.LogEntry{Data: utils.StringPtr("0x")})
+		}
+		return r
+	}
+
+	// block receipts method is not available so the feed falls back to transaction receipts
+	client.EXPECT().BlockByNumber(ctx, big.NewInt(1)).Return(block1, nil).Times(1)
+	traceClient.EXPECT().TraceBlock(ctx, hexToBigInt(block1.Number)).Return(nil, nil).Times(1)
+	client.EXPECT().BlockReceipts(ctx, big.NewInt(1)).Return(nil, errors.New("the method eth_getBlockReceipts does not exist/is not available")).Times(1)
+	client.EXPECT().TransactionReceipts(ctx, []string{"0x1", "0x2"}).Return([]*domain.TransactionReceipt{
+		receipt(block1, "0x1", "0x0", "0x1", 2),
+		receipt(block1, "0x2", "0x1", "0x0", 1),
+	}, []error{nil, nil}).Times(1)
+
+	// should not try block receipts again
+	client.EXPECT().BlockByNumber(ctx, big.NewInt(2)).Return(block2, nil).Times(1)
+	traceClient.EXPECT().TraceBlock(ctx, hexToBigInt(block2.Number)).Return(nil, nil).Times(1)
+	client.EXPECT().TransactionReceipts(ctx, []string{"0x3"}).Return([]*domain.TransactionReceipt{
+		receipt(block2, "0x3", "0x0", "0x1", 1),
+	}, []error{nil}).Times(1)
+
+	var evts []*domain.BlockEvent
+	bf.Subscribe(func(evt *domain.BlockEvent) error {
+		evts = append(evts, evt)
+		if len(evts) == 2 {
+			return testErr
+		}
+		return nil
+	})
+	res := bf.forEachBlock()
+	assert.Error(t, testErr, res)
+	assert.Len(t, evts, 2)
+
+	assert.Len(t, evts[0].Receipts, 2)
+	assert.Len(t, evts[0].Logs, 3)
+	for i, logEntry := range evts[0].Logs {
+		assert.Equal(t, utils.BigIntToHex(big.NewInt(int64(i))), *logEntry.LogIndex)
+		assert.Equal(t, block1.Hash, *logEntry.BlockHash)
+	}
+	assert.Equal(t, "0x2", *evts[0].Logs[2].TransactionHash)
+	assert.True(t, evts[0].ReceiptFor("0x2").Reverted())
+	assert.False(t, evts[0].ReceiptFor("0x1").Reverted())
+
+	assert.Len(t, evts[1].Logs, 1)
+	assert.Equal(t, "0x0", *evts[1].Logs[0].LogIndex)
+}
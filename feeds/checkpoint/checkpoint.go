@@ -0,0 +1,161 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store persists the last fully handled block of a feed. Checkpoints are kept by name
+// so that a single store can be shared by multiple feeds.
+type Store interface {
+	// Load returns the last saved block number or nil if nothing was saved yet.
+	Load(name string) (*big.Int, error)
+	// Save records the block number as the last fully handled block.
+	Save(name string, blockNum *big.Int) error
+}
+
+// NextBlock returns the block number after the saved checkpoint. It returns nil if
+// there is no store or no checkpoint so that the caller can fall back to its own default.
+func NextBlock(store Store, name string) (*big.Int, error) {
+	if store == nil {
+		return nil, nil
+	}
+	blockNum, err := store.Load(name)
+	if err != nil || blockNum == nil {
+		return nil, err
+	}
+	return new(big.Int).Add(blockNum, big.NewInt(1)), nil
+}
+
+type fileStore struct {
+	path        string
+	checkpoints map[string]string
+	mu          sync.Mutex
+}
+
+// NewFileStore creates a store which keeps all checkpoints in a JSON file.
+func NewFileStore(path string) (*fileStore, error) {
+	fs := &fileStore{
+		path:        path,
+		checkpoints: make(map[string]string),
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file: %v", err)
+	}
+	if len(b) == 0 {
+		return fs, nil
+	}
+	if err := json.Unmarshal(b, &fs.checkpoints); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint file: %v", err)
+	}
+	return fs, nil
+}
+
+// Load implements the Store interface.
+func (fs *fileStore) Load(name string) (*big.Int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	numStr, ok := fs.checkpoints[name]
+	if !ok {
+		return nil, nil
+	}
+	blockNum, ok := new(big.Int).SetString(numStr, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid checkpoint for %s: %s", name, numStr)
+	}
+	return blockNum, nil
+}
+
+// Save implements the Store interface. The file is replaced atomically so that
+// a crash while writing does not leave a broken checkpoint behind.
+func (fs *fileStore) Save(name string, blockNum *big.Int) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.checkpoints[name] = blockNum.String()
+	b, err := json.Marshal(fs.checkpoints)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(fs.path, b)
+}
+
+// WriteFileAtomic writes to a temp file in the same dir first and renames it to the path.
+// The file and the dir are synced so that the new content survives a power loss.
+func WriteFileAtomic(path string, b []byte) error {
+	dir := filepath.Dir(path)
+	tmpFile, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(b); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write temp file: %v", err)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to sync temp file: %v", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir makes the renames in the dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open dir: %v", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync dir: %v", err)
+	}
+	return nil
+}
+
+type memoryStore struct {
+	checkpoints map[string]*big.Int
+	mu          sync.Mutex
+}
+
+// NewMemoryStore creates a store which keeps the checkpoints only in memory.
+func NewMemoryStore() *memoryStore {
+	return &memoryStore{checkpoints: make(map[string]*big.Int)}
+}
+
+// Load implements the Store interface.
+func (ms *memoryStore) Load(name string) (*big.Int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	blockNum, ok := ms.checkpoints[name]
+	if !ok {
+		return nil, nil
+	}
+	return new(big.Int).Set(blockNum), nil
+}
+
+// Save implements the Store interface.
+func (ms *memoryStore) Save(name string, blockNum *big.Int) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.checkpoints[name] = new(big.Int).Set(blockNum)
+	return nil
+}
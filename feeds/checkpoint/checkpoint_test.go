@@ -0,0 +1,45 @@
+package checkpoint
+
+import (
+	"math/big"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	r := require.New(t)
+
+	filePath := path.Join(t.TempDir(), "checkpoints.json")
+	store, err := NewFileStore(filePath)
+	r.NoError(err)
+
+	blockNum, err := store.Load("block-feed")
+	r.NoError(err)
+	r.Nil(blockNum)
+
+	r.NoError(store.Save("block-feed", big.NewInt(100)))
+	r.NoError(store.Save("log-feed", big.NewInt(50)))
+	r.NoError(store.Save("block-feed", big.NewInt(101)))
+
+	// should read back what was saved after reopening
+	store, err = NewFileStore(filePath)
+	r.NoError(err)
+
+	blockNum, err = store.Load("block-feed")
+	r.NoError(err)
+	r.Equal(int64(101), blockNum.Int64())
+
+	next, err := NextBlock(store, "log-feed")
+	r.NoError(err)
+	r.Equal(int64(51), next.Int64())
+
+	next, err = NextBlock(store, "unknown")
+	r.NoError(err)
+	r.Nil(next)
+
+	next, err = NextBlock(nil, "block-feed")
+	r.NoError(err)
+	r.Nil(next)
+}
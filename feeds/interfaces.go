@@ -14,6 +14,7 @@ type BlockFeed interface {
 	StartRange(start int64, end int64, rate int64)
 	IsStarted() bool
 	Subscribe(handler func(evt *domain.BlockEvent) error) <-chan error
+	SubscribeToReorgs(handler func(evt *domain.ReorgEvent) error)
 	health.Reporter
 }
 
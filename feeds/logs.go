@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/feeds/checkpoint"
 
 	"github.com/forta-network/forta-core-go/utils"
 
@@ -20,6 +21,9 @@ import (
 	eth "github.com/forta-network/forta-core-go/ethereum"
 )
 
+// DefaultLogFeedCheckpoint is the default checkpoint name of the log feed.
+const DefaultLogFeedCheckpoint = "log-feed"
+
 type logFeed struct {
 	ctx        context.Context
 	startBlock *big.Int
@@ -28,6 +32,9 @@ type logFeed struct {
 	client     eth.Client
 	offset     int
 
+	checkpoint     checkpoint.Store
+	checkpointName string
+
 	addresses []common.Address
 	addrsMu   sync.RWMutex
 }
@@ -86,6 +93,16 @@ func (l *logFeed) ForEachLog(handler func(blk *domain.Block, logEntry types.Log)
 	}
 
 	currentBlock := l.startBlock
+	if currentBlock == nil && l.checkpoint != nil {
+		next, err := checkpoint.NextBlock(l.checkpoint, l.checkpointName)
+		if err != nil {
+			return fmt.Errorf("failed to load checkpoint: %v", err)
+		}
+		if next != nil {
+			log.WithField("checkpoint", l.checkpointName).Infof("resuming logs from block %s", next)
+			currentBlock = next.Add(next, big.NewInt(int64(l.offset)))
+		}
+	}
 	increment := big.NewInt(1)
 	eg.Go(func() error {
 		for {
@@ -150,6 +167,11 @@ func (l *logFeed) ForEachLog(handler func(blk *domain.Block, logEntry types.Log)
 			if err := finishBlockHandler(blk); err != nil {
 				return err
 			}
+			if l.checkpoint != nil {
+				if err := l.checkpoint.Save(l.checkpointName, blockToRetrieve); err != nil {
+					log.WithError(err).WithField("block", blockToRetrieve.String()).Error("failed to save checkpoint")
+				}
+			}
 		}
 	})
 	log.Infof("subscribed to logs: address=%v, topics=%v, startBlock=%s, endBlock=%s", l.addresses, l.topics, l.startBlock, l.endBlock)
@@ -184,6 +206,10 @@ type LogFeedConfig struct {
 	StartBlock *big.Int
 	EndBlock   *big.Int
 	Offset     int
+	// Checkpoint stores the last block which had all of its logs handled. When it is set
+	// and StartBlock is nil, the feed resumes from the block after the checkpoint.
+	Checkpoint     checkpoint.Store
+	CheckpointName string
 }
 
 func NewLogFeed(ctx context.Context, client eth.Client, cfg LogFeedConfig) (*logFeed, error) {
@@ -194,13 +220,19 @@ func NewLogFeed(ctx context.Context, client eth.Client, cfg LogFeedConfig) (*log
 	for _, addr := range cfg.Addresses {
 		addrs = append(addrs, common.HexToAddress(addr))
 	}
+	checkpointName := cfg.CheckpointName
+	if checkpointName == "" {
+		checkpointName = DefaultLogFeedCheckpoint
+	}
 	return &logFeed{
-		ctx:        ctx,
-		client:     client,
-		topics:     cfg.Topics,
-		addresses:  addrs,
-		startBlock: cfg.StartBlock,
-		endBlock:   cfg.EndBlock,
-		offset:     cfg.Offset,
+		ctx:            ctx,
+		client:         client,
+		topics:         cfg.Topics,
+		addresses:      addrs,
+		startBlock:     cfg.StartBlock,
+		endBlock:       cfg.EndBlock,
+		offset:         cfg.Offset,
+		checkpoint:     cfg.Checkpoint,
+		checkpointName: checkpointName,
 	}, nil
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/feeds/checkpoint"
 
 	mocks "github.com/forta-network/forta-core-go/ethereum/mocks"
 	"github.com/forta-network/forta-core-go/testutils"
@@ -64,3 +65,45 @@ func TestLogFeed_ForEachLog(t *testing.T) {
 		assert.Equal(t, logs[idx].TxHash.Hex(), fl.TxHash.Hex())
 	}
 }
+
+func TestLogFeed_ForEachLog_Checkpoint(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	client := mocks.NewMockClient(ctrl)
+	logs := testutils.TestLogs(0, 1)
+
+	store := checkpoint.NewMemoryStore()
+	assert.NoError(t, store.Save(DefaultLogFeedCheckpoint, big.NewInt(4)))
+
+	blk := testutils.TestBlock()
+	client.EXPECT().BlockByNumber(gomock.Any(), big.NewInt(5)).Return(blk, nil).Times(1)
+	client.EXPECT().GetLogs(gomock.Any(), gomock.Any()).Return([]types.Log{logs[0]}, nil).Times(1)
+
+	client.EXPECT().BlockByNumber(gomock.Any(), big.NewInt(6)).Return(blk, nil).Times(1)
+	client.EXPECT().GetLogs(gomock.Any(), gomock.Any()).Return([]types.Log{logs[1]}, nil).Times(1)
+
+	lf, err := NewLogFeed(ctx, client, LogFeedConfig{
+		Topics:     [][]string{{testEventTopic}},
+		Checkpoint: store,
+	})
+	assert.NoError(t, err)
+
+	var foundLogs []types.Log
+	err = lf.ForEachLog(func(blk *domain.Block, logEntry types.Log) error {
+		foundLogs = append(foundLogs, logEntry)
+		// return early before finishing the second block
+		if len(foundLogs) == 2 {
+			return context.Canceled
+		}
+		return nil
+	}, func(blk *domain.Block) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, foundLogs, 2)
+
+	// only the first block was fully handled
+	blockNum, err := store.Load(DefaultLogFeedCheckpoint)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), blockNum.Int64())
+}
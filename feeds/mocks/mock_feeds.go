@@ -117,6 +117,18 @@ func (mr *MockBlockFeedMockRecorder) Subscribe(handler interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockBlockFeed)(nil).Subscribe), handler)
 }
 
+// SubscribeToReorgs mocks base method.
+func (m *MockBlockFeed) SubscribeToReorgs(handler func(*domain.ReorgEvent) error) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SubscribeToReorgs", handler)
+}
+
+// SubscribeToReorgs indicates an expected call of SubscribeToReorgs.
+func (mr *MockBlockFeedMockRecorder) SubscribeToReorgs(handler interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeToReorgs", reflect.TypeOf((*MockBlockFeed)(nil).SubscribeToReorgs), handler)
+}
+
 // MockTransactionFeed is a mock of TransactionFeed interface.
 type MockTransactionFeed struct {
 	ctrl     *gomock.Controller
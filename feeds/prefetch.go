@@ -0,0 +1,322 @@
+package feeds
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-core-go/domain"
+)
+
+// prefetchJob contains the block, traces and logs fetched ahead of time for a block number.
+type prefetchJob struct {
+	done chan struct{}
+
+	block       *domain.Block
+	blockErr    error
+	traces      []domain.Trace
+	tracesErr   error
+	logs        []domain.LogEntry
+	logsErr     error
+	receipts    []domain.TransactionReceipt
+	receiptsErr error
+
+	// size is the approximate size of the fetched data
+	size int64
+}
+
+// isDone tells if the job is finished without waiting.
+func (job *prefetchJob) isDone() bool {
+	select {
+	case <-job.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// prefetcher fetches the next blocks in parallel while the feed is delivering the current one.
+// The block body, the traces and the logs of a block are fetched concurrently. The jobs are
+// used only by the feed loop so the results are still delivered to the handlers in order.
+//
+// At most size blocks are fetched ahead and no more blocks are scheduled while the data
+// in memory is larger than maxBytes.
+type prefetcher struct {
+	bf       *blockFeed
+	size     int64
+	maxBytes int64
+	// lastSize is the size of the last fetched block data and the estimate for the running jobs
+	lastSize atomic.Int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	jobs   map[int64]*prefetchJob
+
+	head        int64
+	headChecked time.Time
+
+	// ticked is closed after the last started job took its rate limit tick
+	ticked chan struct{}
+}
+
+func newPrefetcher(bf *blockFeed, size int, maxBytes int64) *prefetcher {
+	if maxBytes <= 0 {
+		maxBytes = DefaultPrefetchMaxBytes
+	}
+	p := &prefetcher{bf: bf, size: int64(size), maxBytes: maxBytes}
+	p.Reset()
+	return p
+}
+
+// Reset cancels and drops all jobs, e.g. after a reorg.
+func (p *prefetcher) Reset() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.ctx, p.cancel = context.WithCancel(p.bf.ctx)
+	p.jobs = make(map[int64]*prefetchJob)
+	p.ticked = nil
+}
+
+// Forget drops the job of a block number so that it is fetched again next time.
+func (p *prefetcher) Forget(num int64) {
+	delete(p.jobs, num)
+}
+
+// Get returns the job for given block number after making sure that the next blocks
+// are scheduled within the memory budget. The jobs which the feed has moved past are
+// released.
+func (p *prefetcher) Get(num int64) *prefetchJob {
+	for n := range p.jobs {
+		if n < num {
+			delete(p.jobs, n)
+		}
+	}
+
+	budget := p.maxBytes - p.heldBytes()
+	limit := p.limit(num)
+	for n := num + 1; n <= num+p.size && n <= limit && budget > 0; n++ {
+		if _, ok := p.jobs[n]; !ok {
+			p.jobs[n] = p.start(n)
+			budget -= p.lastSize.Load()
+		}
+	}
+	job, ok := p.jobs[num]
+	if !ok {
+		job = p.start(num)
+		p.jobs[num] = job
+	}
+	return job
+}
+
+// heldBytes returns the size of the data of the jobs in memory. The size of the last
+// fetched block data is used for the running jobs.
+func (p *prefetcher) heldBytes() int64 {
+	var total int64
+	for _, job := range p.jobs {
+		if job.isDone() {
+			total += job.size
+		} else {
+			total += p.lastSize.Load()
+		}
+	}
+	return total
+}
+
+// limit returns the highest block number which is safe to prefetch. In the offset mode,
+// this is the block which is analyzed when the feed reaches the head.
+func (p *prefetcher) limit(num int64) int64 {
+	if p.bf.end != nil {
+		return p.bf.end.Int64()
+	}
+	offset := int64(p.bf.offset)
+	if num+p.size+offset <= p.head || time.Since(p.headChecked) < time.Second {
+		return p.head - offset
+	}
+	p.headChecked = time.Now()
+
+	if p.bf.subscriptionMode {
+		p.bf.chainLatestMu.RLock()
+		if p.bf.chainLatestBlockNum != nil {
+			p.head = p.bf.chainLatestBlockNum.Int64()
+		}
+		p.bf.chainLatestMu.RUnlock()
+		return p.head - offset
+	}
+
+	latest, err := p.bf.client.BlockNumber(p.ctx)
+	if err != nil {
+		log.WithError(err).Warn("failed to get latest block number for prefetching")
+		return p.head - offset
+	}
+	p.head = latest.Int64()
+	return p.head - offset
+}
+
+// start schedules a job which starts fetching after taking a tick from the rate limit
+// of the feed. The jobs take their ticks in the order they were scheduled.
+func (p *prefetcher) start(num int64) *prefetchJob {
+	job := &prefetchJob{done: make(chan struct{})}
+	ctx := p.ctx
+	prev := p.ticked
+	ticked := make(chan struct{})
+	p.ticked = ticked
+	go func() {
+		p.waitTick(ctx, prev)
+		close(ticked)
+		p.fetch(ctx, job, big.NewInt(num))
+	}()
+	return job
+}
+
+func (p *prefetcher) waitTick(ctx context.Context, prev <-chan struct{}) {
+	if prev != nil {
+		select {
+		case <-prev:
+		case <-ctx.Done():
+			return
+		}
+	}
+	if p.bf.rateLimit == nil {
+		return
+	}
+	select {
+	case <-p.bf.rateLimit.C:
+	case <-ctx.Done():
+	}
+}
+
+func (p *prefetcher) fetch(ctx context.Context, job *prefetchJob, blockNum *big.Int) {
+	var wg sync.WaitGroup
+	blockReady := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(blockReady)
+		job.block, job.blockErr = p.bf.client.BlockByNumber(ctx, blockNum)
+	}()
+	if p.bf.tracing {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.traces, job.tracesErr = p.bf.traceClient.TraceBlock(ctx, blockNum)
+		}()
+	}
+	switch {
+	case p.bf.logs && p.bf.logsFromReceipts:
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.receipts, job.receiptsErr = p.bf.receiptsForBlock(ctx, blockNum, func() (*domain.Block, error) {
+				<-blockReady
+				return job.block, job.blockErr
+			})
+		}()
+
+	case p.bf.logs:
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.logs, job.logsErr = p.bf.logsForBlock(ctx, blockNum)
+		}()
+	}
+	wg.Wait()
+	job.size = job.estimateSize()
+	p.lastSize.Store(job.size)
+	close(job.done)
+}
+
+// estimateSize returns the encoded size of the fetched data.
+func (job *prefetchJob) estimateSize() int64 {
+	var cw countingWriter
+	enc := json.NewEncoder(&cw)
+	for _, v := range []interface{}{job.block, job.traces, job.logs, job.receipts} {
+		_ = enc.Encode(v)
+	}
+	return int64(cw)
+}
+
+type countingWriter int64
+
+func (cw *countingWriter) Write(b []byte) (int, error) {
+	*cw += countingWriter(len(b))
+	return len(b), nil
+}
+
+// waitFor waits for the job of a block number to finish.
+func (bf *blockFeed) waitFor(blockNum *big.Int) (*prefetchJob, error) {
+	job := bf.prefetcher.Get(blockNum.Int64())
+	select {
+	case <-job.done:
+		return job, nil
+	case <-bf.ctx.Done():
+		return nil, bf.ctx.Err()
+	}
+}
+
+func (bf *blockFeed) getBlock(blockNum *big.Int) (*domain.Block, error) {
+	if bf.prefetcher == nil {
+		return bf.client.BlockByNumber(bf.ctx, blockNum)
+	}
+	job, err := bf.waitFor(blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if job.blockErr != nil {
+		bf.prefetcher.Forget(blockNum.Int64())
+	}
+	return job.block, job.blockErr
+}
+
+func (bf *blockFeed) getTraces(blockNum *big.Int) ([]domain.Trace, error) {
+	if bf.prefetcher == nil {
+		return bf.traceClient.TraceBlock(bf.ctx, blockNum)
+	}
+	job, err := bf.waitFor(blockNum)
+	if err != nil {
+		return nil, err
+	}
+	return job.traces, job.tracesErr
+}
+
+func (bf *blockFeed) getLogs(blockNum *big.Int) ([]domain.LogEntry, error) {
+	if bf.prefetcher == nil {
+		return bf.logsForBlock(bf.ctx, blockNum)
+	}
+	job, err := bf.waitFor(blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if job.logsErr != nil {
+		bf.prefetcher.Forget(blockNum.Int64())
+	}
+	return job.logs, job.logsErr
+}
+
+func (bf *blockFeed) getReceipts(blockNum *big.Int, block *domain.Block) ([]domain.TransactionReceipt, error) {
+	if bf.prefetcher == nil {
+		return bf.receiptsForBlock(bf.ctx, blockNum, func() (*domain.Block, error) {
+			return block, nil
+		})
+	}
+	job, err := bf.waitFor(blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if job.receiptsErr != nil {
+		bf.prefetcher.Forget(blockNum.Int64())
+	}
+	return job.receipts, job.receiptsErr
+}
+
+// forget makes sure that the prefetched data of a block is not used again.
+func (bf *blockFeed) forget(blockNum *big.Int) {
+	if bf.prefetcher != nil {
+		bf.prefetcher.Forget(blockNum.Int64())
+	}
+}
@@ -0,0 +1,106 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/utils"
+)
+
+// errors which tell that the provider does not serve eth_getBlockReceipts
+var blockReceiptsUnsupportedErrors = []string{
+	"method not found",
+	"does not exist/is not available",
+	"not supported",
+}
+
+func isBlockReceiptsUnsupported(err error) bool {
+	for _, unsupportedErr := range blockReceiptsUnsupportedErrors {
+		if strings.Contains(strings.ToLower(err.Error()), unsupportedErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// receiptsForBlock gets the receipts of all transactions in a block with eth_getBlockReceipts
+// and falls back to getting them in a batch of transaction receipt requests if the provider does not support it.
+func (bf *blockFeed) receiptsForBlock(ctx context.Context, blockNum *big.Int, getBlock func() (*domain.Block, error)) ([]domain.TransactionReceipt, error) {
+	if !bf.blockReceiptsUnsupported.Load() {
+		receipts, err := bf.client.BlockReceipts(ctx, blockNum)
+		if err == nil {
+			return receipts, nil
+		}
+		if !isBlockReceiptsUnsupported(err) {
+			return nil, err
+		}
+		log.WithError(err).Warn("block receipts are not supported - falling back to transaction receipts")
+		bf.blockReceiptsUnsupported.Store(true)
+	}
+
+	block, err := getBlock()
+	if err != nil {
+		return nil, err
+	}
+	if len(block.Transactions) == 0 {
+		return nil, nil
+	}
+	txHashes := make([]string, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		txHashes[i] = tx.Hash
+	}
+	results, errs := bf.client.TransactionReceipts(ctx, txHashes)
+	receipts := make([]domain.TransactionReceipt, 0, len(results))
+	for i, receipt := range results {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("failed to get receipt for tx %s: %v", txHashes[i], errs[i])
+		}
+		receipts = append(receipts, *receipt)
+	}
+	return receipts, nil
+}
+
+// logsFromReceipts rebuilds the logs of a block from the receipts, in transaction order.
+func logsFromReceipts(receipts []domain.TransactionReceipt) []domain.LogEntry {
+	sorted := make([]domain.TransactionReceipt, len(receipts))
+	copy(sorted, receipts)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return hexOrZero(sorted[i].TransactionIndex) < hexOrZero(sorted[j].TransactionIndex)
+	})
+
+	var logs []domain.LogEntry
+	for _, receipt := range sorted {
+		for _, logEntry := range receipt.Logs {
+			if logEntry.TransactionHash == nil {
+				logEntry.TransactionHash = receipt.TransactionHash
+			}
+			if logEntry.TransactionIndex == nil {
+				logEntry.TransactionIndex = receipt.TransactionIndex
+			}
+			if logEntry.BlockHash == nil {
+				logEntry.BlockHash = receipt.BlockHash
+			}
+			if logEntry.BlockNumber == nil {
+				logEntry.BlockNumber = receipt.BlockNumber
+			}
+			logIndex := hexutil.EncodeUint64(uint64(len(logs)))
+			logEntry.LogIndex = &logIndex
+			logs = append(logs, logEntry)
+		}
+	}
+	return logs
+}
+
+func hexOrZero(hex *string) int64 {
+	if hex == nil {
+		return 0
+	}
+	return utils.HexToInt64(*hex)
+}
@@ -0,0 +1,532 @@
+package feeds
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/encoding"
+	"github.com/forta-network/forta-core-go/protocol"
+)
+
+// Block record file settings
+const (
+	BlockRecordFileExt              = ".blocks"
+	DefaultBlockRecordCodec         = encoding.CodecZstd
+	DefaultBlockRecordBlocksPerFile = 1000
+
+	blockRecordMagic      = "FBLK"
+	maxBlockRecordLength  = 256 << 20
+	blockRecordReorgDepth = 128
+)
+
+// BlockRecorderConfig contains the block recorder settings.
+type BlockRecorderConfig struct {
+	Dir string
+	// Codec compresses each record. The default is zstd.
+	Codec string
+	// BlocksPerFile is the number of blocks in a file before starting a new file.
+	BlocksPerFile int
+}
+
+// blockRecorder writes the block events to the record files. The files are named after
+// their first block and each file starts with a header which contains the codec name.
+// The records are the compressed block data with a length prefix.
+//
+// After a restart, the recorder continues the last file and skips the blocks which are
+// already recorded. If one of the recent blocks is received again with another hash, it is
+// replaced by a reorg and the records are cut off from that block before recording it.
+type blockRecorder struct {
+	cfg   BlockRecorderConfig
+	codec encoding.Codec
+
+	file *os.File
+	// size is the size of the header and the complete records in the file
+	size     int64
+	blocks   int
+	recorded bool
+	last     uint64
+	// recent blocks from the oldest to the newest
+	recent []blockRecordPosition
+
+	lastBlock health.MessageTracker
+	lastErr   health.ErrorTracker
+	mu        sync.Mutex
+}
+
+// NewBlockRecorder creates a new block recorder.
+func NewBlockRecorder(cfg BlockRecorderConfig) (*blockRecorder, error) {
+	if len(cfg.Codec) == 0 {
+		cfg.Codec = DefaultBlockRecordCodec
+	}
+	if cfg.BlocksPerFile <= 0 {
+		cfg.BlocksPerFile = DefaultBlockRecordBlocksPerFile
+	}
+	codec, err := encoding.GetCodec(cfg.Codec)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create record dir: %v", err)
+	}
+	br := &blockRecorder{cfg: cfg, codec: codec}
+	if err := br.resume(); err != nil {
+		return nil, err
+	}
+	return br, nil
+}
+
+// resume finds the last recorded block and opens the last file for appending. The
+// incomplete last record is cut off and the files without any records are removed.
+func (br *blockRecorder) resume() error {
+	files, err := blockRecordFiles(br.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		path := files[i]
+		info, err := scanBlockRecordFile(path)
+		if err != nil {
+			return err
+		}
+		if info.blocks == 0 {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove empty record file: %v", err)
+			}
+			continue
+		}
+		br.recorded = true
+		br.last = info.last
+		if err := br.loadRecent(files[:i], info); err != nil {
+			return err
+		}
+		// the next block starts a new file if this one is full or uses another codec
+		if info.blocks >= br.cfg.BlocksPerFile || info.codec != br.codec.Name() {
+			return nil
+		}
+		file, err := os.OpenFile(path, os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open record file: %v", err)
+		}
+		br.file = file
+		br.size = info.size
+		br.blocks = info.blocks
+		if err := br.truncate(); err != nil {
+			br.closeFile()
+			return err
+		}
+		return nil
+	}
+	return nil
+}
+
+// loadRecent finds the recent records by scanning the previous files if needed.
+func (br *blockRecorder) loadRecent(prevFiles []string, info *blockRecordFileInfo) error {
+	recent := info.records
+	for i := len(prevFiles) - 1; i >= 0 && len(recent) < blockRecordReorgDepth; i-- {
+		prev, err := scanBlockRecordFile(prevFiles[i])
+		if err != nil {
+			return err
+		}
+		recent = append(prev.records, recent...)
+	}
+	br.remember(recent...)
+	return nil
+}
+
+func (br *blockRecorder) remember(positions ...blockRecordPosition) {
+	br.recent = append(br.recent, positions...)
+	if len(br.recent) > blockRecordReorgDepth {
+		br.recent = br.recent[len(br.recent)-blockRecordReorgDepth:]
+	}
+}
+
+// Record subscribes to the feed and records all block events.
+func (br *blockRecorder) Record(feed BlockFeed) <-chan error {
+	return feed.Subscribe(br.RecordBlock)
+}
+
+// RecordBlock writes the block event to the current record file.
+func (br *blockRecorder) RecordBlock(evt *domain.BlockEvent) error {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	err := br.recordBlock(evt)
+	br.lastErr.Set(err)
+	if err != nil {
+		return fmt.Errorf("failed to record block %s: %v", evt.Block.Number, err)
+	}
+	br.lastBlock.Set(evt.Block.Number)
+	return nil
+}
+
+func (br *blockRecorder) recordBlock(evt *domain.BlockEvent) error {
+	num, err := hexutil.DecodeUint64(evt.Block.Number)
+	if err != nil {
+		return fmt.Errorf("invalid block number: %v", err)
+	}
+	if br.recorded && num <= br.last {
+		i := br.findRecent(num)
+		if i < 0 || br.recent[i].hash == evt.Block.Hash {
+			log.WithField("block", num).Debug("skipping the block which is already recorded")
+			return nil
+		}
+		log.WithFields(log.Fields{
+			"block":        num,
+			"hash":         evt.Block.Hash,
+			"recordedHash": br.recent[i].hash,
+		}).Warn("recorded block is replaced by a reorg - rewriting the records from it")
+		if err := br.rewind(i); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	cw, err := br.codec.NewWriter(&buf)
+	if err != nil {
+		return err
+	}
+	b, err := proto.Marshal(evt.ToBlockData())
+	if err != nil {
+		return err
+	}
+	if _, err := cw.Write(b); err != nil {
+		return err
+	}
+	if err := cw.Close(); err != nil {
+		return err
+	}
+
+	if br.file == nil || br.blocks >= br.cfg.BlocksPerFile {
+		if err := br.rotate(num); err != nil {
+			return err
+		}
+	}
+	// write every record at once so that a crash loses the last block at most
+	record := make([]byte, 4, 4+buf.Len())
+	binary.BigEndian.PutUint32(record, uint32(buf.Len()))
+	record = append(record, buf.Bytes()...)
+	pos := blockRecordPosition{
+		number: num,
+		hash:   evt.Block.Hash,
+		path:   br.file.Name(),
+		offset: br.size,
+		index:  br.blocks,
+	}
+	if _, err := br.file.Write(record); err != nil {
+		// cut off the partial record so that the next record can be appended
+		if err := br.truncate(); err != nil {
+			log.WithError(err).Warn("failed to truncate the partial block record - starting a new file")
+			br.closeFile()
+		}
+		return err
+	}
+	br.size += int64(len(record))
+	br.blocks++
+	br.recorded = true
+	br.last = num
+	br.remember(pos)
+	return nil
+}
+
+func (br *blockRecorder) findRecent(num uint64) int {
+	for i := len(br.recent) - 1; i >= 0; i-- {
+		if br.recent[i].number == num {
+			return i
+		}
+	}
+	return -1
+}
+
+// rewind removes the records from the recent block at given index, so that the next block
+// starts a new file.
+func (br *blockRecorder) rewind(i int) error {
+	pos := br.recent[i]
+	if err := br.closeFile(); err != nil {
+		return err
+	}
+	files, err := blockRecordFiles(br.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range files {
+		if filepath.Base(path) <= filepath.Base(pos.path) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove orphaned record file: %v", err)
+		}
+	}
+	if pos.index == 0 {
+		err = os.Remove(pos.path)
+	} else {
+		err = os.Truncate(pos.path, pos.offset)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove orphaned records: %v", err)
+	}
+	br.recent = br.recent[:i]
+	br.recorded = pos.number > 0
+	br.last = pos.number - 1
+	return nil
+}
+
+func (br *blockRecorder) rotate(num uint64) error {
+	if err := br.closeFile(); err != nil {
+		return err
+	}
+	// sorting the names gives the block order
+	path := filepath.Join(br.cfg.Dir, fmt.Sprintf("%020d%s", num, BlockRecordFileExt))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create record file: %v", err)
+	}
+	header := append([]byte(blockRecordMagic), byte(len(br.codec.Name())))
+	header = append(header, br.codec.Name()...)
+	if _, err := file.Write(header); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	br.file = file
+	br.size = int64(len(header))
+	br.blocks = 0
+	return nil
+}
+
+// truncate cuts the file back to the complete records.
+func (br *blockRecorder) truncate() error {
+	if err := br.file.Truncate(br.size); err != nil {
+		return fmt.Errorf("failed to truncate record file: %v", err)
+	}
+	if _, err := br.file.Seek(br.size, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek record file: %v", err)
+	}
+	return nil
+}
+
+func (br *blockRecorder) closeFile() error {
+	if br.file == nil {
+		return nil
+	}
+	err := br.file.Close()
+	br.file = nil
+	return err
+}
+
+// Close closes the current record file.
+func (br *blockRecorder) Close() error {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	return br.closeFile()
+}
+
+// Name returns the name of this implementation.
+func (br *blockRecorder) Name() string {
+	return "block-recorder"
+}
+
+// Health implements the health.Reporter interface.
+func (br *blockRecorder) Health() health.Reports {
+	return health.Reports{
+		br.lastBlock.GetReport("last-block"),
+		br.lastErr.GetReport("error"),
+	}
+}
+
+// blockRecordReader reads the block data from a record file.
+type blockRecordReader struct {
+	r     *bufio.Reader
+	codec encoding.Codec
+	// size is the size of the header and the records which are read
+	size int64
+}
+
+func newBlockRecordReader(r io.Reader) (*blockRecordReader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(blockRecordMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("failed to read record header: %w", err)
+	}
+	if string(header[:len(blockRecordMagic)]) != blockRecordMagic {
+		return nil, errors.New("not a block record file")
+	}
+	codecName := make([]byte, header[len(blockRecordMagic)])
+	if _, err := io.ReadFull(br, codecName); err != nil {
+		return nil, fmt.Errorf("failed to read record codec: %w", err)
+	}
+	codec, err := encoding.GetCodec(string(codecName))
+	if err != nil {
+		return nil, err
+	}
+	return &blockRecordReader{r: br, codec: codec, size: int64(len(header) + len(codecName))}, nil
+}
+
+// Next reads the next block data. It returns io.EOF at the end of the file and
+// io.ErrUnexpectedEOF if the last record is incomplete.
+func (rr *blockRecordReader) Next() (*protocol.BlockData, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(rr.r, length[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(length[:])
+	if n > maxBlockRecordLength {
+		return nil, fmt.Errorf("record is too large: %d bytes", n)
+	}
+	record := make([]byte, n)
+	if _, err := io.ReadFull(rr.r, record); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	rr.size += int64(len(length)) + int64(n)
+	cr, err := rr.codec.NewReader(bytes.NewReader(record))
+	if err != nil {
+		return nil, err
+	}
+	defer cr.Close()
+	b, err := io.ReadAll(cr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", rr.codec.Name(), err)
+	}
+	var bd protocol.BlockData
+	if err := proto.Unmarshal(b, &bd); err != nil {
+		return nil, err
+	}
+	return &bd, nil
+}
+
+// blockRecordFiles returns the record files in the block order.
+func blockRecordFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read record dir: %v", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), BlockRecordFileExt) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+type blockRecordFileInfo struct {
+	codec   string
+	size    int64
+	blocks  int
+	last    uint64
+	records []blockRecordPosition
+}
+
+// blockRecordPosition is where a block is recorded.
+type blockRecordPosition struct {
+	number uint64
+	hash   string
+	path   string
+	offset int64
+	// index is the index of the record in the file
+	index int
+}
+
+// scanBlockRecordFile reads a record file to find its last block and the size of its
+// complete records. A file with an incomplete header has no records.
+func scanBlockRecordFile(path string) (*blockRecordFileInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open record file: %v", err)
+	}
+	defer file.Close()
+	rr, err := newBlockRecordReader(file)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &blockRecordFileInfo{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	info := &blockRecordFileInfo{codec: rr.codec.Name()}
+	for {
+		offset := rr.size
+		bd, err := rr.Next()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			info.size = rr.size
+			return info, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to read block record: %v", filepath.Base(path), err)
+		}
+		num, err := hexutil.DecodeUint64(bd.GetBlock().GetNumber())
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid recorded block number: %v", filepath.Base(path), err)
+		}
+		info.records = append(info.records, blockRecordPosition{
+			number: num,
+			hash:   bd.GetBlock().GetHash(),
+			path:   path,
+			offset: offset,
+			index:  info.blocks,
+		})
+		info.blocks++
+		info.last = num
+	}
+}
+
+// ReadBlockRecords reads all recorded blocks in a dir. An incomplete last record in a
+// file is skipped because the recorder can stop while writing it.
+func ReadBlockRecords(dir string, handler func(bd *protocol.BlockData) error) error {
+	files, err := blockRecordFiles(dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range files {
+		if err := readBlockRecordFile(path, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readBlockRecordFile(path string, handler func(bd *protocol.BlockData) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open record file: %v", err)
+	}
+	defer file.Close()
+	rr, err := newBlockRecordReader(file)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filepath.Base(path), err)
+	}
+	for {
+		bd, err := rr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err == io.ErrUnexpectedEOF {
+			log.WithField("file", filepath.Base(path)).Warn("skipping incomplete block record")
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: failed to read block record: %v", filepath.Base(path), err)
+		}
+		if err := handler(bd); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/utils"
+)
+
+func testRecordedBlockEvent(num int64) *domain.BlockEvent {
+	txHash := fmt.Sprintf("0x%x0", num)
+	return &domain.BlockEvent{
+		EventType: domain.EventTypeBlock,
+		ChainID:   big.NewInt(1),
+		Block: &domain.Block{
+			Hash:      fmt.Sprintf("0x%x", num),
+			Number:    utils.BigIntToHex(big.NewInt(num)),
+			Timestamp: "0x5",
+			Transactions: []domain.Transaction{
+				{Hash: txHash, To: utils.StringPtr("0xto")},
+			},
+		},
+		Logs: []domain.LogEntry{
+			{
+				TransactionHash: utils.StringPtr(txHash),
+				Topics:          []*string{utils.StringPtr("0xtopic1"), utils.StringPtr("0xtopic2")},
+			},
+		},
+	}
+}
+
+func recordTestBlocks(t *testing.T, dir string, nums ...int64) {
+	recorder, err := NewBlockRecorder(BlockRecorderConfig{Dir: dir, BlocksPerFile: 2})
+	require.NoError(t, err)
+	for _, num := range nums {
+		require.NoError(t, recorder.RecordBlock(testRecordedBlockEvent(num)))
+	}
+	require.NoError(t, recorder.Close())
+}
+
+func replayTestBlocks(t *testing.T, feed *replayBlockFeed, start func()) []*domain.BlockEvent {
+	var blocks []*domain.BlockEvent
+	errCh := feed.Subscribe(func(evt *domain.BlockEvent) error {
+		blocks = append(blocks, evt)
+		return nil
+	})
+	start()
+	require.ErrorIs(t, <-errCh, ErrEndBlockReached)
+	return blocks
+}
+
+func TestBlockRecorder_Replay(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	recordTestBlocks(t, dir, 1, 2, 3)
+
+	files, err := blockRecordFiles(dir)
+	r.NoError(err)
+	r.Len(files, 2)
+	r.Equal(fmt.Sprintf("%020d%s", 3, BlockRecordFileExt), filepath.Base(files[1]))
+
+	// the recorder can stop while writing a record
+	f, err := os.OpenFile(files[1], os.O_APPEND|os.O_WRONLY, 0644)
+	r.NoError(err)
+	_, err = f.Write([]byte{0, 0, 1, 0, 1, 2})
+	r.NoError(err)
+	r.NoError(f.Close())
+
+	feed, err := NewReplayBlockFeed(context.Background(), ReplayBlockFeedConfig{Dir: dir})
+	r.NoError(err)
+	blocks := replayTestBlocks(t, feed, feed.Start)
+	r.Len(blocks, 3)
+	for i, evt := range blocks {
+		r.Equal(utils.BigIntToHex(big.NewInt(int64(i+1))), evt.Block.Number)
+		r.Equal(int64(1), evt.ChainID.Int64())
+		r.Len(evt.Block.Transactions, 1)
+		r.Len(evt.Logs, 1)
+		r.Equal("0xtopic1", *evt.Logs[0].Topics[0])
+		r.Equal("0xtopic2", *evt.Logs[0].Topics[1])
+
+		txEvt := &domain.TransactionEvent{
+			BlockEvt:    evt,
+			Transaction: &evt.Block.Transactions[0],
+			Timestamps:  evt.Timestamps,
+		}
+		msg, err := txEvt.ToMessage()
+		r.NoError(err)
+		r.Len(msg.Logs, 1)
+	}
+}
+
+func TestReplayBlockFeed_StartRange(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	recordTestBlocks(t, dir, 1, 2, 3, 4)
+
+	feed, err := NewReplayBlockFeed(context.Background(), ReplayBlockFeedConfig{Dir: dir})
+	r.NoError(err)
+	blocks := replayTestBlocks(t, feed, func() {
+		feed.StartRange(2, 3, 0)
+	})
+	r.Len(blocks, 2)
+	r.Equal("0x2", blocks[0].Block.Number)
+	r.Equal("0x3", blocks[1].Block.Number)
+	report, ok := feed.Health().NameContains("last-block")
+	r.True(ok)
+	r.Equal("3", report.Details)
+}
+
+func TestBlockRecorder_Restart(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	recordTestBlocks(t, dir, 1, 2, 3)
+
+	// the recorder stops while writing a record
+	files, err := blockRecordFiles(dir)
+	r.NoError(err)
+	r.Len(files, 2)
+	f, err := os.OpenFile(files[1], os.O_APPEND|os.O_WRONLY, 0644)
+	r.NoError(err)
+	_, err = f.Write([]byte{0, 0, 1, 0, 1, 2})
+	r.NoError(err)
+	r.NoError(f.Close())
+
+	// the recorded blocks are skipped and the last file is continued
+	recordTestBlocks(t, dir, 2, 3, 4, 5)
+	files, err = blockRecordFiles(dir)
+	r.NoError(err)
+	r.Len(files, 3)
+	r.Equal(fmt.Sprintf("%020d%s", 5, BlockRecordFileExt), filepath.Base(files[2]))
+
+	var numbers []string
+	r.NoError(ReadBlockRecords(dir, func(bd *protocol.BlockData) error {
+		numbers = append(numbers, bd.Block.Number)
+		return nil
+	}))
+	r.Equal([]string{"0x1", "0x2", "0x3", "0x4", "0x5"}, numbers)
+}
+
+func TestBlockRecorder_Reorg(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	recorder, err := NewBlockRecorder(BlockRecorderConfig{Dir: dir, BlocksPerFile: 2})
+	r.NoError(err)
+	recordBlock := func(num int64, hash string) {
+		evt := testRecordedBlockEvent(num)
+		evt.Block.Hash = hash
+		r.NoError(recorder.RecordBlock(evt))
+	}
+	recordBlock(1, "0x1")
+	recordBlock(2, "0x2")
+	recordBlock(3, "0x3")
+
+	// block 2 and 3 are replaced
+	recordBlock(2, "0x2b")
+	recordBlock(3, "0x3b")
+	recordBlock(4, "0x4b")
+	r.NoError(recorder.Close())
+
+	// the recent blocks are known after a restart
+	recorder, err = NewBlockRecorder(BlockRecorderConfig{Dir: dir, BlocksPerFile: 2})
+	r.NoError(err)
+	recordBlock(3, "0x3b")
+	recordBlock(4, "0x4c")
+	r.NoError(recorder.Close())
+
+	files, err := blockRecordFiles(dir)
+	r.NoError(err)
+	var names []string
+	for _, file := range files {
+		names = append(names, filepath.Base(file))
+	}
+	r.Equal([]string{
+		fmt.Sprintf("%020d%s", 1, BlockRecordFileExt),
+		fmt.Sprintf("%020d%s", 2, BlockRecordFileExt),
+		fmt.Sprintf("%020d%s", 4, BlockRecordFileExt),
+	}, names)
+
+	var hashes []string
+	r.NoError(ReadBlockRecords(dir, func(bd *protocol.BlockData) error {
+		hashes = append(hashes, bd.Block.Hash)
+		return nil
+	}))
+	r.Equal([]string{"0x1", "0x2b", "0x3b", "0x4c"}, hashes)
+}
@@ -0,0 +1,154 @@
+package feeds
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/utils"
+)
+
+// blockWindow keeps the recently delivered blocks in ascending order so that
+// parent hash mismatches can be resolved back to a common ancestor.
+type blockWindow struct {
+	size   int
+	blocks []*domain.Block
+}
+
+func newBlockWindow(size int) *blockWindow {
+	return &blockWindow{size: size}
+}
+
+// Add appends the block to the window. Any blocks at the same or higher heights
+// are dropped first and the oldest block is evicted when the window is full.
+func (w *blockWindow) Add(block *domain.Block) {
+	w.Truncate(utils.HexToInt64(block.Number) - 1)
+	w.blocks = append(w.blocks, block)
+	if len(w.blocks) > w.size {
+		w.blocks = w.blocks[len(w.blocks)-w.size:]
+	}
+}
+
+// Get returns the delivered block at given height, if it is in the window.
+func (w *blockWindow) Get(num int64) *domain.Block {
+	for _, block := range w.blocks {
+		if utils.HexToInt64(block.Number) == num {
+			return block
+		}
+	}
+	return nil
+}
+
+// After returns the blocks which are higher than given height.
+func (w *blockWindow) After(num int64) []*domain.Block {
+	var blocks []*domain.Block
+	for _, block := range w.blocks {
+		if utils.HexToInt64(block.Number) > num {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// Truncate drops the blocks which are higher than given height.
+func (w *blockWindow) Truncate(num int64) {
+	for i, block := range w.blocks {
+		if utils.HexToInt64(block.Number) > num {
+			w.blocks = w.blocks[:i]
+			return
+		}
+	}
+}
+
+// SubscribeToReorgs registers a handler which is invoked with a reorg event before
+// the replacement blocks are delivered to the block handlers. Reorg detection
+// is disabled unless BlockFeedConfig.ReorgWindow is set.
+func (bf *blockFeed) SubscribeToReorgs(handler func(evt *domain.ReorgEvent) error) {
+	bf.handlersMu.Lock()
+	defer bf.handlersMu.Unlock()
+
+	bf.reorgHandlers = append(bf.reorgHandlers, handler)
+}
+
+// detectReorg compares the parent hash of the block with the previously delivered block and,
+// if they do not match, walks back until the common ancestor is found.
+func (bf *blockFeed) detectReorg(blockNum *big.Int, block *domain.Block) (*domain.ReorgEvent, error) {
+	num := blockNum.Int64()
+	parent := bf.window.Get(num - 1)
+	if parent == nil || parent.Hash == block.ParentHash {
+		return nil, nil
+	}
+
+	replacement := []*domain.Block{block}
+	expectedParentHash := block.ParentHash
+	var ancestor *domain.Block
+	for n := num - 1; n >= 0; n-- {
+		delivered := bf.window.Get(n)
+		if delivered == nil {
+			break // the common ancestor is older than the window
+		}
+		if delivered.Hash == expectedParentHash {
+			ancestor = delivered
+			break
+		}
+		canonical, err := bf.client.BlockByNumber(bf.ctx, big.NewInt(n))
+		if err != nil {
+			return nil, err
+		}
+		replacement = append([]*domain.Block{canonical}, replacement...)
+		expectedParentHash = canonical.ParentHash
+	}
+
+	orphaned := bf.window.After(utils.HexToInt64(replacement[0].Number) - 1)
+	bf.window.Truncate(utils.HexToInt64(replacement[0].Number) - 1)
+
+	return &domain.ReorgEvent{
+		EventType:      domain.EventTypeReorg,
+		ChainID:        bf.chainID,
+		CommonAncestor: ancestor,
+		Orphaned:       orphaned,
+		Replacement:    replacement,
+		Depth:          len(orphaned),
+		Timestamps: &domain.TrackingTimestamps{
+			Feed: time.Now().UTC(),
+		},
+	}, nil
+}
+
+func (bf *blockFeed) handleReorg(evt *domain.ReorgEvent) error {
+	bf.handlersMu.RLock()
+	handlers := bf.reorgHandlers
+	bf.handlersMu.RUnlock()
+	for _, handler := range handlers {
+		if err := handler(evt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keepReplacement keeps the replacement blocks which were fetched while walking back
+// to the common ancestor, so that they are redelivered without fetching them again.
+func (bf *blockFeed) keepReplacement(blocks []*domain.Block) {
+	bf.replacements = make(map[int64]*domain.Block)
+	for _, block := range blocks {
+		bf.replacements[utils.HexToInt64(block.Number)] = block
+	}
+}
+
+// nextBlock returns the block to analyze. A kept replacement block is used only once. In
+// the offset mode, the current block is fetched first to make sure that it exists. It is
+// not prefetched since its traces and logs are not needed.
+func (bf *blockFeed) nextBlock(currentBlockNum, blockNumToAnalyze *big.Int) (*domain.Block, error) {
+	if block, ok := bf.replacements[blockNumToAnalyze.Int64()]; ok {
+		delete(bf.replacements, blockNumToAnalyze.Int64())
+		return block, nil
+	}
+	if blockNumToAnalyze.Cmp(currentBlockNum) == 0 {
+		return bf.getBlock(currentBlockNum)
+	}
+	if _, err := bf.client.BlockByNumber(bf.ctx, currentBlockNum); err != nil {
+		return nil, err
+	}
+	return bf.getBlock(blockNumToAnalyze)
+}
@@ -0,0 +1,167 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-core-go/clients/health"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/utils"
+)
+
+var errReplayStopped = errors.New("replay stopped")
+
+// ReplayBlockFeedConfig contains the replay block feed settings.
+type ReplayBlockFeedConfig struct {
+	// Dir contains the record files of the block recorder.
+	Dir       string
+	Start     *big.Int
+	End       *big.Int
+	RateLimit *time.Ticker
+}
+
+// replayBlockFeed delivers the recorded blocks like the live block feed. It stops with
+// ErrEndBlockReached after the end block or after the last recorded block. The recorded
+// blocks do not contain the receipts and the reorgs are not detected again.
+type replayBlockFeed struct {
+	ctx       context.Context
+	dir       string
+	start     *big.Int
+	end       *big.Int
+	rateLimit *time.Ticker
+	started   bool
+
+	lastBlock health.MessageTracker
+
+	handlers   []bfHandler
+	handlersMu sync.RWMutex
+}
+
+// NewReplayBlockFeed creates a new replay block feed.
+func NewReplayBlockFeed(ctx context.Context, cfg ReplayBlockFeedConfig) (*replayBlockFeed, error) {
+	if _, err := blockRecordFiles(cfg.Dir); err != nil {
+		return nil, err
+	}
+	return &replayBlockFeed{
+		ctx:       ctx,
+		dir:       cfg.Dir,
+		start:     cfg.Start,
+		end:       cfg.End,
+		rateLimit: cfg.RateLimit,
+	}, nil
+}
+
+func (rf *replayBlockFeed) IsStarted() bool {
+	return rf.started
+}
+
+func (rf *replayBlockFeed) Start() {
+	if !rf.started {
+		go rf.loop()
+	}
+}
+
+// StartRange replays a specific set of blocks
+func (rf *replayBlockFeed) StartRange(start int64, end int64, rate int64) {
+	if !rf.started {
+		if rate > 0 {
+			rf.rateLimit = time.NewTicker(time.Duration(rate) * time.Millisecond)
+		}
+		rf.start = big.NewInt(start)
+		rf.end = big.NewInt(end)
+		go rf.loop()
+	}
+}
+
+func (rf *replayBlockFeed) loop() {
+	rf.started = true
+	defer func() {
+		rf.started = false
+	}()
+	err := rf.forEachBlock()
+	if err == nil || err == errReplayStopped {
+		err = ErrEndBlockReached
+	}
+	if err != ErrEndBlockReached {
+		log.WithError(err).Warn("failed while replaying blocks")
+	}
+	rf.handlersMu.RLock()
+	handlers := rf.handlers
+	rf.handlersMu.RUnlock()
+	for _, handler := range handlers {
+		handler.ErrCh <- err
+	}
+}
+
+func (rf *replayBlockFeed) forEachBlock() error {
+	return ReadBlockRecords(rf.dir, func(bd *protocol.BlockData) error {
+		if rf.ctx.Err() != nil {
+			return rf.ctx.Err()
+		}
+		blockNum, err := utils.HexToBigInt(bd.GetBlock().GetNumber())
+		if err != nil {
+			log.WithError(err).Error("skipping recorded block with invalid number")
+			return nil
+		}
+		if rf.start != nil && blockNum.Cmp(rf.start) < 0 {
+			return nil
+		}
+		if rf.end != nil && blockNum.Cmp(rf.end) > 0 {
+			log.WithField("block", blockNum.Uint64()).Info("end block reached - exiting")
+			return errReplayStopped
+		}
+		if rf.rateLimit != nil {
+			<-rf.rateLimit.C
+		}
+
+		evt, err := domain.BlockEventFromBlockData(bd)
+		if err != nil {
+			log.WithError(err).WithField("block", blockNum.Uint64()).Error("skipping invalid recorded block")
+			return nil
+		}
+		rf.lastBlock.Set(blockNum.String())
+
+		rf.handlersMu.RLock()
+		handlers := rf.handlers
+		rf.handlersMu.RUnlock()
+		for _, handler := range handlers {
+			if err := handler.Handler(evt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (rf *replayBlockFeed) Subscribe(handler func(evt *domain.BlockEvent) error) <-chan error {
+	rf.handlersMu.Lock()
+	defer rf.handlersMu.Unlock()
+
+	errCh := make(chan error)
+	rf.handlers = append(rf.handlers, bfHandler{
+		Handler: handler,
+		ErrCh:   errCh,
+	})
+	return errCh
+}
+
+// SubscribeToReorgs does nothing since the replay does not detect the reorgs.
+func (rf *replayBlockFeed) SubscribeToReorgs(handler func(evt *domain.ReorgEvent) error) {}
+
+// Name returns the name of this implementation.
+func (rf *replayBlockFeed) Name() string {
+	return "replay-block-feed"
+}
+
+// Health implements the health.Reporter interface.
+func (rf *replayBlockFeed) Health() health.Reports {
+	return health.Reports{
+		rf.lastBlock.GetReport("last-block"),
+	}
+}
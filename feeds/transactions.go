@@ -3,6 +3,9 @@ package feeds
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math/big"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -10,9 +13,13 @@ import (
 
 	"github.com/forta-network/forta-core-go/domain"
 	"github.com/forta-network/forta-core-go/ethereum"
+	"github.com/forta-network/forta-core-go/feeds/checkpoint"
 	"github.com/forta-network/forta-core-go/utils"
 )
 
+// DefaultTransactionFeedCheckpoint is the default checkpoint name of the transaction feed.
+const DefaultTransactionFeedCheckpoint = "transaction-feed"
+
 type transactionFeed struct {
 	ctx         context.Context
 	cache       utils.Cache
@@ -22,6 +29,115 @@ type transactionFeed struct {
 	blockCh     chan *domain.BlockEvent
 	txCh        chan *domain.TransactionEvent
 	maxBlockAge *time.Duration
+
+	checkpoint     checkpoint.Store
+	checkpointName string
+	skipUntil      *big.Int
+	progress       *blockProgress
+}
+
+// blockProgress tracks the transactions of the streamed blocks in order and saves
+// the latest block which had its block handler and all of its transactions (and the
+// previous blocks' transactions) handled.
+type blockProgress struct {
+	store   checkpoint.Store
+	name    string
+	pending []*pendingBlock
+	saved   *big.Int
+	mu      sync.Mutex
+}
+
+type pendingBlock struct {
+	evt       *domain.BlockEvent
+	remaining int
+	streamed  bool
+	handled   bool
+}
+
+// Add starts tracking a block.
+func (bp *blockProgress) Add(evt *domain.BlockEvent) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	bp.pending = append(bp.pending, &pendingBlock{evt: evt})
+}
+
+// Streamed marks that the transactions of a block are being sent to the workers. It does
+// not save the checkpoint because the block and the transaction handlers can still fail.
+func (bp *blockProgress) Streamed(evt *domain.BlockEvent, txs int) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	if pb := bp.find(evt); pb != nil {
+		pb.remaining += txs
+		pb.streamed = true
+	}
+}
+
+// Handled marks that the block handler succeeded for a block.
+func (bp *blockProgress) Handled(evt *domain.BlockEvent) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	if pb := bp.find(evt); pb != nil {
+		pb.handled = true
+	}
+	bp.save(bp.popCompleted())
+}
+
+// Done marks one transaction of a block as handled.
+func (bp *blockProgress) Done(evt *domain.BlockEvent) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	if pb := bp.find(evt); pb != nil {
+		pb.remaining--
+	}
+	bp.save(bp.popCompleted())
+}
+
+func (bp *blockProgress) find(evt *domain.BlockEvent) *pendingBlock {
+	for _, pb := range bp.pending {
+		if pb.evt == evt {
+			return pb
+		}
+	}
+	return nil
+}
+
+// popCompleted drops the completed blocks from the front and returns the latest one.
+func (bp *blockProgress) popCompleted() *domain.BlockEvent {
+	var completed *domain.BlockEvent
+	for len(bp.pending) > 0 && bp.pending[0].streamed && bp.pending[0].handled && bp.pending[0].remaining <= 0 {
+		completed = bp.pending[0].evt
+		bp.pending = bp.pending[1:]
+	}
+	return completed
+}
+
+// save is called with the lock held so that the concurrent workers cannot move the
+// checkpoint backwards.
+func (bp *blockProgress) save(blockEvt *domain.BlockEvent) {
+	if blockEvt == nil {
+		return
+	}
+	blockNum := big.NewInt(utils.HexToInt64(blockEvt.Block.Number))
+	if bp.saved != nil && blockNum.Cmp(bp.saved) <= 0 {
+		return
+	}
+	if err := bp.store.Save(bp.name, blockNum); err != nil {
+		log.WithError(err).WithField("block", blockNum.String()).Error("failed to save checkpoint")
+		return
+	}
+	bp.saved = blockNum
+}
+
+// SetCheckpoint makes the feed save the latest block which had all of its transactions handled
+// and skip the blocks up to the saved checkpoint. The block feed can be started from the next block
+// by using checkpoint.NextBlock() with the same store and name.
+func (tf *transactionFeed) SetCheckpoint(store checkpoint.Store, name string) {
+	if name == "" {
+		name = DefaultTransactionFeedCheckpoint
+	}
+	tf.checkpoint = store
+	tf.checkpointName = name
+	tf.progress = &blockProgress{store: store, name: name}
 }
 
 func (tf *transactionFeed) streamTransactions() error {
@@ -42,32 +158,53 @@ func (tf *transactionFeed) streamTransactions() error {
 		tooOld, age := blockIsTooOld(blockEvt.Block, tf.maxBlockAge)
 		if tooOld {
 			logger.WithField("age", age).Warn("dropping block for being too old")
+			tf.streamed(blockEvt, 0)
 			continue
 		}
 
-		logger.Infof("tx-iterator: processing block")
+		if tf.skipUntil != nil && utils.HexToInt64(blockEvt.Block.Number) <= tf.skipUntil.Int64() {
+			logger.Info("block was handled before the checkpoint - skipping")
+			tf.streamed(blockEvt, 0)
+			continue
+		}
+
+		var txs []domain.Transaction
 		for _, tx := range blockEvt.Block.Transactions {
+			if !tf.cache.ExistsAndAdd(tx.Hash) {
+				txs = append(txs, tx)
+			}
+		}
+		// counting the transactions before sending them makes the last handled one complete the block
+		tf.streamed(blockEvt, len(txs))
+
+		logger.Infof("tx-iterator: processing block")
+		for _, tx := range txs {
 			txTemp := tx
 			select {
 			case <-tf.ctx.Done():
 				return tf.ctx.Err()
 			default:
-				if !tf.cache.ExistsAndAdd(tx.Hash) {
-					log.Debugf("tx-iterator: block(%s), txs <- %s", blockEvt.Block.Number, tx.Hash)
-					tf.txCh <- &domain.TransactionEvent{
-						BlockEvt:    blockEvt,
-						Transaction: &txTemp,
-						Timestamps: &domain.TrackingTimestamps{
-							Block: blockEvt.Timestamps.Block,
-							Feed:  time.Now().UTC(),
-						},
-					}
+				log.Debugf("tx-iterator: block(%s), txs <- %s", blockEvt.Block.Number, tx.Hash)
+				tf.txCh <- &domain.TransactionEvent{
+					BlockEvt:    blockEvt,
+					Transaction: &txTemp,
+					Receipt:     blockEvt.ReceiptFor(tx.Hash),
+					Timestamps: &domain.TrackingTimestamps{
+						Block: blockEvt.Timestamps.Block,
+						Feed:  time.Now().UTC(),
+					},
 				}
 			}
 		}
 	}
 }
 
+func (tf *transactionFeed) streamed(blockEvt *domain.BlockEvent, txs int) {
+	if tf.progress != nil {
+		tf.progress.Streamed(blockEvt, txs)
+	}
+}
+
 func (tf *transactionFeed) getWorker(workerID int, handler func(evt *domain.TransactionEvent) error) func() error {
 	return func() error {
 		for tx := range tf.txCh {
@@ -81,6 +218,9 @@ func (tf *transactionFeed) getWorker(workerID int, handler func(evt *domain.Tran
 					log.Errorf("tx-processor(%d): block(%s) tx(%s) handler returned error, cancelling: %s", workerID, tx.BlockEvt.Block.Number, tx.Transaction.Hash, err.Error())
 					return err
 				}
+				if tf.progress != nil {
+					tf.progress.Done(tx.BlockEvt)
+				}
 			}
 		}
 		return nil
@@ -89,17 +229,32 @@ func (tf *transactionFeed) getWorker(workerID int, handler func(evt *domain.Tran
 
 // ForEachTransaction invokes a handler for each transactions on a network until cancelled or handler returns error
 func (tf *transactionFeed) ForEachTransaction(blockHandler func(evt *domain.BlockEvent) error, txHandler func(evt *domain.TransactionEvent) error) error {
+	if tf.checkpoint != nil {
+		skipUntil, err := tf.checkpoint.Load(tf.checkpointName)
+		if err != nil {
+			return fmt.Errorf("failed to load checkpoint: %v", err)
+		}
+		tf.skipUntil = skipUntil
+		tf.progress.saved = skipUntil
+	}
+
 	grp, _ := errgroup.WithContext(tf.ctx)
 
 	// iterate over blocks
 	grp.Go(func() error {
 		errCh := tf.blockFeed.Subscribe(func(evt *domain.BlockEvent) error {
 			log.Debugf("block-iterator: blocks <- %s", evt.Block.Number)
+			if tf.progress != nil {
+				tf.progress.Add(evt)
+			}
 			tf.blockCh <- evt
 			var blockHandlerErr error
 			if blockHandler != nil {
 				blockHandlerErr = blockHandler(evt)
 			}
+			if blockHandlerErr == nil && tf.progress != nil {
+				tf.progress.Handled(evt)
+			}
 			return blockHandlerErr
 		})
 		err := <-errCh
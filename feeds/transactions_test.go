@@ -2,6 +2,9 @@ package feeds
 
 import (
 	"context"
+	"math/big"
+	"math/rand"
+	"sync"
 	"testing"
 	"time"
 
@@ -10,6 +13,7 @@ import (
 
 	"github.com/forta-network/forta-core-go/domain"
 	clients "github.com/forta-network/forta-core-go/ethereum/mocks"
+	"github.com/forta-network/forta-core-go/feeds/checkpoint"
 	"github.com/forta-network/forta-core-go/testutils"
 	"github.com/forta-network/forta-core-go/utils"
 )
@@ -95,3 +99,105 @@ func TestTransactionFeed_ToMessage(t *testing.T) {
 	assert.Error(t, err, endOfBlocks)
 	assert.Equal(t, endOfBlocks, err)
 }
+
+// checkpointRecorder records the saved checkpoints.
+type checkpointRecorder struct {
+	checkpoint.Store
+	saved []int64
+	mu    sync.Mutex
+}
+
+func (cr *checkpointRecorder) Save(name string, blockNum *big.Int) error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.saved = append(cr.saved, blockNum.Int64())
+	return cr.Store.Save(name, blockNum)
+}
+
+// testCheckpointBlocks creates blocks from 1 to n with unique transactions.
+func testCheckpointBlocks(n, txsPerBlock int) []*domain.BlockEvent {
+	var blockEvents []*domain.BlockEvent
+	for i := 1; i <= n; i++ {
+		var nonces []int
+		for j := 0; j < txsPerBlock; j++ {
+			nonces = append(nonces, i*1000+j)
+		}
+		block := testutils.TestBlock(nonces...)
+		block.Number = utils.BigIntToHex(big.NewInt(int64(i)))
+		blockEvents = append(blockEvents, &domain.BlockEvent{
+			EventType:  domain.EventTypeBlock,
+			Block:      block,
+			Timestamps: &domain.TrackingTimestamps{Block: time.Now().UTC()},
+		})
+	}
+	return blockEvents
+}
+
+func TestTransactionFeed_CheckpointResume(t *testing.T) {
+	store := checkpoint.NewMemoryStore()
+	blockEvents := testCheckpointBlocks(5, 2)
+
+	txFeed, _ := getTestTransactionFeed(t, NewMockBlockFeed(blockEvents[:3]))
+	txFeed.SetCheckpoint(store, "")
+	err := txFeed.ForEachTransaction(nil, func(evt *domain.TransactionEvent) error { return nil })
+	assert.Equal(t, endOfBlocks, err)
+
+	saved, err := store.Load(DefaultTransactionFeedCheckpoint)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), saved.Int64())
+
+	// the blocks up to the checkpoint are skipped after restarting from an earlier block
+	txFeed, _ = getTestTransactionFeed(t, NewMockBlockFeed(blockEvents))
+	txFeed.SetCheckpoint(store, "")
+	var blocks []string
+	err = txFeed.ForEachTransaction(nil, func(evt *domain.TransactionEvent) error {
+		blocks = append(blocks, evt.BlockEvt.Block.Number)
+		return nil
+	})
+	assert.Equal(t, endOfBlocks, err)
+	assert.Equal(t, []string{"0x4", "0x4", "0x5", "0x5"}, blocks)
+
+	saved, err = store.Load(DefaultTransactionFeedCheckpoint)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), saved.Int64())
+}
+
+func TestTransactionFeed_CheckpointBlockHandlerError(t *testing.T) {
+	store := checkpoint.NewMemoryStore()
+	blockEvents := testCheckpointBlocks(4, 2)
+
+	txFeed, _ := getTestTransactionFeed(t, NewMockBlockFeed(blockEvents))
+	txFeed.SetCheckpoint(store, "")
+	err := txFeed.ForEachTransaction(func(evt *domain.BlockEvent) error {
+		if evt.Block.Number == "0x3" {
+			return testErr
+		}
+		return nil
+	}, func(evt *domain.TransactionEvent) error { return nil })
+	assert.Equal(t, testErr, err)
+
+	// the transactions of the failed block were handled but the block was not
+	saved, err := store.Load(DefaultTransactionFeedCheckpoint)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), saved.Int64())
+}
+
+func TestTransactionFeed_CheckpointConcurrentWorkers(t *testing.T) {
+	store := &checkpointRecorder{Store: checkpoint.NewMemoryStore()}
+	blockEvents := testCheckpointBlocks(50, 5)
+
+	txFeed, _ := getTestTransactionFeed(t, NewMockBlockFeed(blockEvents))
+	txFeed.workers = 8
+	txFeed.SetCheckpoint(store, "")
+	err := txFeed.ForEachTransaction(nil, func(evt *domain.TransactionEvent) error {
+		time.Sleep(time.Duration(rand.Intn(500)) * time.Microsecond)
+		return nil
+	})
+	assert.Equal(t, endOfBlocks, err)
+
+	assert.NotEmpty(t, store.saved)
+	for i := 1; i < len(store.saved); i++ {
+		assert.Greater(t, store.saved[i], store.saved[i-1], "checkpoint moved backwards")
+	}
+	assert.Equal(t, int64(50), store.saved[len(store.saved)-1])
+}
@@ -0,0 +1,222 @@
+package ipfs
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultCacheMaxSize = 100 * 1024 * 1024
+	cacheTempPrefix     = ".tmp-"
+)
+
+// CacheConfig contains the on-disk cache settings.
+type CacheConfig struct {
+	Dir          string
+	MaxSizeBytes int64
+}
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+// cachedClient keeps the fetched content on disk by the CID and evicts the least recently used
+// content when the size limit is exceeded. The content is verified against the CID before it is
+// cached, so it is never served from a gateway which returns something else.
+type cachedClient struct {
+	*verifiedClient
+	cfg CacheConfig
+
+	lru     *list.List
+	entries map[string]*list.Element
+	size    int64
+	mu      sync.Mutex
+}
+
+// GetBytes gets the content from the cache or from the gateway.
+func (cc *cachedClient) GetBytes(ctx context.Context, reference string) ([]byte, error) {
+	// a path is cached by the cid which it resolves to
+	c, err := cc.resolve(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+	key := c.String()
+	if b, ok := cc.get(key); ok {
+		return b, nil
+	}
+
+	b, err := cc.fetch(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if err := cc.put(key, b); err != nil {
+		log.WithError(err).WithField("reference", reference).Warn("failed to cache content from ipfs")
+	}
+	return b, nil
+}
+
+// UnmarshalJson gets the content from the cache or from the gateway and unmarshals it.
+func (cc *cachedClient) UnmarshalJson(ctx context.Context, reference string, target interface{}) error {
+	b, err := cc.GetBytes(ctx, reference)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, target)
+}
+
+func (cc *cachedClient) path(key string) string {
+	return filepath.Join(cc.cfg.Dir, key)
+}
+
+func (cc *cachedClient) get(key string) ([]byte, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	el, ok := cc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	b, err := os.ReadFile(cc.path(key))
+	if err != nil {
+		log.WithError(err).WithField("cid", key).Warn("failed to read cached content")
+		cc.remove(el)
+		return nil, false
+	}
+	cc.lru.MoveToFront(el)
+	// keep the access order across restarts
+	now := time.Now()
+	_ = os.Chtimes(cc.path(key), now, now)
+	return b, true
+}
+
+func (cc *cachedClient) put(key string, b []byte) error {
+	size := int64(len(b))
+	if size > cc.cfg.MaxSizeBytes {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(cc.cfg.Dir, cacheTempPrefix)
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), cc.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if el, ok := cc.entries[key]; ok {
+		cc.lru.MoveToFront(el)
+		return nil
+	}
+	cc.add(key, size)
+	cc.evict()
+	return nil
+}
+
+func (cc *cachedClient) add(key string, size int64) {
+	cc.entries[key] = cc.lru.PushFront(&cacheEntry{key: key, size: size})
+	cc.size += size
+}
+
+func (cc *cachedClient) remove(el *list.Element) {
+	entry := cc.lru.Remove(el).(*cacheEntry)
+	delete(cc.entries, entry.key)
+	cc.size -= entry.size
+}
+
+// evict removes the least recently used content until the cache fits the size limit.
+func (cc *cachedClient) evict() {
+	for cc.size > cc.cfg.MaxSizeBytes && cc.lru.Len() > 0 {
+		el := cc.lru.Back()
+		key := el.Value.(*cacheEntry).key
+		cc.remove(el)
+		if err := os.Remove(cc.path(key)); err != nil && !os.IsNotExist(err) {
+			log.WithError(err).WithField("cid", key).Warn("failed to remove cached content")
+		}
+	}
+}
+
+// load finds the content cached before, in the order of access.
+func (cc *cachedClient) load() error {
+	dirEntries, err := os.ReadDir(cc.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	var infos []os.FileInfo
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() {
+			continue
+		}
+		name := dirEntry.Name()
+		if strings.HasPrefix(name, cacheTempPrefix) {
+			_ = os.Remove(cc.path(name))
+			continue
+		}
+		if _, err := cid.Decode(name); err != nil {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		cc.add(info.Name(), info.Size())
+	}
+	cc.evict()
+	return nil
+}
+
+// NewCachedClient creates a client which caches the content fetched with given client on disk.
+// The blocks are used for verifying the content which was not added with the default settings.
+func NewCachedClient(client Client, blocks BlockGetter, cfg CacheConfig) (*cachedClient, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("cache dir is not set")
+	}
+	if cfg.MaxSizeBytes <= 0 {
+		cfg.MaxSizeBytes = defaultCacheMaxSize
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %v", err)
+	}
+	vc, err := NewVerifiedClient(client, blocks)
+	if err != nil {
+		return nil, err
+	}
+	cc := &cachedClient{
+		verifiedClient: vc,
+		cfg:            cfg,
+		lru:            list.New(),
+		entries:        make(map[string]*list.Element),
+	}
+	if err := cc.load(); err != nil {
+		return nil, fmt.Errorf("failed to load cache: %v", err)
+	}
+	return cc, nil
+}
@@ -0,0 +1,93 @@
+package ipfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+
+	mock_ipfs "github.com/forta-network/forta-core-go/ipfs/mocks"
+)
+
+const (
+	testCacheContent = "test // data && \\\n"
+	testCacheCID     = "QmUyscxixDckkTnAxxzYQFC9yce3Weruss2ZPZ41jmB3ht"
+)
+
+func TestCachedClient_GetBytes(t *testing.T) {
+	r := require.New(t)
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	ctrl := gomock.NewController(t)
+	ic := mock_ipfs.NewMockClient(ctrl)
+	blocks := mock_ipfs.NewMockBlockGetter(ctrl)
+	cc, err := NewCachedClient(ic, blocks, CacheConfig{Dir: dir})
+	r.NoError(err)
+
+	// fetched only once
+	ic.EXPECT().GetBytes(ctx, testCacheCID).Return([]byte(testCacheContent), nil).Times(1)
+	for i := 0; i < 2; i++ {
+		b, err := cc.GetBytes(ctx, testCacheCID)
+		r.NoError(err)
+		r.Equal(testCacheContent, string(b))
+	}
+	_, err = os.Stat(filepath.Join(dir, testCacheCID))
+	r.NoError(err)
+
+	// a new client should find the cached content
+	cc, err = NewCachedClient(ic, blocks, CacheConfig{Dir: dir})
+	r.NoError(err)
+	b, err := cc.GetBytes(ctx, testCacheCID)
+	r.NoError(err)
+	r.Equal(testCacheContent, string(b))
+}
+
+func TestCachedClient_CIDMismatch(t *testing.T) {
+	r := require.New(t)
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	ctrl := gomock.NewController(t)
+	ic := mock_ipfs.NewMockClient(ctrl)
+	blocks := mock_ipfs.NewMockBlockGetter(ctrl)
+	cc, err := NewCachedClient(ic, blocks, CacheConfig{Dir: dir})
+	r.NoError(err)
+
+	ic.EXPECT().GetBytes(ctx, testCacheCID).Return([]byte("forged"), nil).Times(1)
+	// the root block can not be forged either
+	blocks.EXPECT().GetBlock(ctx, cid.MustParse(testCacheCID)).Return([]byte("forged"), nil).Times(1)
+	_, err = cc.GetBytes(ctx, testCacheCID)
+	r.ErrorIs(err, ErrCIDMismatch)
+
+	_, err = os.Stat(filepath.Join(dir, testCacheCID))
+	r.True(os.IsNotExist(err))
+}
+
+func TestCachedClient_Evict(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	cc, err := NewCachedClient(nil, nil, CacheConfig{Dir: dir, MaxSizeBytes: 10})
+	r.NoError(err)
+
+	r.NoError(cc.put("a", []byte("12345")))
+	r.NoError(cc.put("b", []byte("12345")))
+	// make "a" the most recently used one
+	_, ok := cc.get("a")
+	r.True(ok)
+
+	r.NoError(cc.put("c", []byte("12345")))
+	_, ok = cc.get("b")
+	r.False(ok)
+	_, err = os.Stat(filepath.Join(dir, "b"))
+	r.True(os.IsNotExist(err))
+	_, ok = cc.get("a")
+	r.True(ok)
+	_, ok = cc.get("c")
+	r.True(ok)
+}
@@ -8,10 +8,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
+	"github.com/forta-network/forta-core-go/clients/health"
 	"github.com/forta-network/forta-core-go/utils/httpclient"
+	"github.com/ipfs/go-cid"
 	ipfsapi "github.com/ipfs/go-ipfs-api"
 	files "github.com/ipfs/go-ipfs-files"
 	coreiface "github.com/ipfs/interface-go-ipfs-core"
@@ -24,7 +27,10 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-const ipfsTimeout = 10 * time.Second
+const (
+	ipfsTimeout    = 10 * time.Second
+	rawBlockFormat = "raw"
+)
 
 var ErrInternalErr = errors.New("internal error")
 var ErrRateLimit = errors.New("rate limited")
@@ -37,14 +43,42 @@ type Client interface {
 	UnmarshalJson(ctx context.Context, reference string, target interface{}) error
 }
 
+// BlockGetter gets the raw blocks of the content.
+type BlockGetter interface {
+	GetBlock(ctx context.Context, c cid.Cid) ([]byte, error)
+}
+
+// gateway is an IPFS gateway with its own health.
+type gateway struct {
+	url     string
+	name    string
+	lastReq health.TimeTracker
+	lastErr health.ErrorTracker
+}
+
+func (gw *gateway) buildUrl(reference, format string) string {
+	if format != "" {
+		return fmt.Sprintf("%s/ipfs/%s?format=%s", gw.url, reference, format)
+	}
+	return fmt.Sprintf("%s/ipfs/%s", gw.url, reference)
+}
+
 type client struct {
-	ipfsGateway string
-	coreApi     coreiface.CoreAPI
+	gateways       []*gateway
+	notFoundQuorum int
+	coreApi        coreiface.CoreAPI
 	*ipfsapi.Shell
 }
 
-func (c *client) buildUrl(reference string) string {
-	return fmt.Sprintf("%s/ipfs/%s", c.ipfsGateway, reference)
+// SetNotFoundQuorum sets how many gateways should fail with ErrNotFound before giving up early.
+func (c *client) SetNotFoundQuorum(n int) {
+	if n < 1 {
+		n = 1
+	}
+	if n > len(c.gateways) {
+		n = len(c.gateways)
+	}
+	c.notFoundQuorum = n
 }
 
 func createFileBytes(payload []byte) []byte {
@@ -83,11 +117,52 @@ func (c *client) UnmarshalJson(ctx context.Context, reference string, target int
 	return json.Unmarshal(b, target)
 }
 
+// GetBytes gets the content from the gateways in order. It moves onto the next gateway if a gateway
+// fails and gives up early only after enough gateways report that the content is not found.
 func (c *client) GetBytes(ctx context.Context, reference string) ([]byte, error) {
+	return c.getFromGateways(ctx, reference, "")
+}
+
+// GetBlock gets the raw block of given CID from the gateways in order.
+func (c *client) GetBlock(ctx context.Context, id cid.Cid) ([]byte, error) {
+	return c.getFromGateways(ctx, id.String(), rawBlockFormat)
+}
+
+func (c *client) getFromGateways(ctx context.Context, reference, format string) ([]byte, error) {
+	var (
+		notFound int
+		lastErr  error
+	)
+	for _, gw := range c.gateways {
+		b, err := c.getBytes(ctx, gw, reference, format)
+		gw.lastReq.Set()
+		gw.lastErr.Set(err)
+		if err == nil {
+			return b, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		lastErr = err
+		if errors.Is(err, ErrNotFound) {
+			notFound++
+			if notFound >= c.notFoundQuorum {
+				return nil, ErrNotFound
+			}
+		}
+		log.WithError(err).WithFields(log.Fields{
+			"reference": reference,
+			"gateway":   gw.name,
+		}).Warn("failed to get from ipfs gateway - trying next")
+	}
+	return nil, lastErr
+}
+
+func (c *client) getBytes(ctx context.Context, gw *gateway, reference, format string) ([]byte, error) {
 	ctx, cncl := context.WithTimeout(ctx, ipfsTimeout)
 	defer cncl()
 
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, c.buildUrl(reference), nil)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, gw.buildUrl(reference, format), nil)
 	resp, err := httpclient.Default.Do(req)
 	if err != nil {
 		return nil, err
@@ -122,7 +197,8 @@ func (c *client) GetBytes(ctx context.Context, reference string) ([]byte, error)
 	return b, nil
 }
 
-func NewClient(ipfsGateway string) (*client, error) {
+// newCoreAPI creates a core API which is only useful for calculating the hashes.
+func newCoreAPI() (coreiface.CoreAPI, error) {
 	coreApi, err := coreapi.NewCoreAPI(&core.IpfsNode{
 		Repo: &repo.Mock{
 			C: config.Config{},
@@ -131,10 +207,61 @@ func NewClient(ipfsGateway string) (*client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create core api: %v", err)
 	}
+	return coreApi, nil
+}
+
+// Name returns the name of this implementation.
+func (c *client) Name() string {
+	return "ipfs-client"
+}
+
+// Health implements the health.Reporter interface.
+func (c *client) Health() health.Reports {
+	var reports health.Reports
+	for _, gw := range c.gateways {
+		reports = append(reports,
+			gw.lastReq.GetReport(fmt.Sprintf("gateway.%s.request.time", gw.name)),
+			gw.lastErr.GetReport(fmt.Sprintf("gateway.%s.request.error", gw.name)),
+		)
+	}
+	return reports
+}
+
+var _ Client = &client{}
+var _ health.Reporter = &client{}
+var _ BlockGetter = &client{}
+
+func NewClient(ipfsGateway string) (*client, error) {
+	return NewClientMulti(ipfsGateway)
+}
 
-	return &client{
-		ipfsGateway: ipfsGateway,
-		coreApi:     coreApi,
-		Shell:       ipfsapi.NewShell(ipfsGateway),
-	}, nil
+// NewClientMulti creates a client which gets the content from the gateways in given order.
+// The files are added by using the first gateway.
+func NewClientMulti(ipfsGateways ...string) (*client, error) {
+	if len(ipfsGateways) == 0 {
+		return nil, errors.New("no ipfs gateways provided")
+	}
+	coreApi, err := newCoreAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{
+		coreApi: coreApi,
+		Shell:   ipfsapi.NewShell(ipfsGateways[0]),
+	}
+	for i, ipfsGateway := range ipfsGateways {
+		c.gateways = append(c.gateways, &gateway{url: ipfsGateway, name: gatewayName(i, ipfsGateway)})
+	}
+	// a single not found answer is enough only if there is one gateway
+	c.SetNotFoundQuorum(2)
+	return c, nil
+}
+
+func gatewayName(i int, ipfsGateway string) string {
+	u, err := url.Parse(ipfsGateway)
+	if err != nil || u.Hostname() == "" {
+		return fmt.Sprintf("%d", i)
+	}
+	return fmt.Sprintf("%d-%s", i, u.Hostname())
 }
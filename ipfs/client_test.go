@@ -5,15 +5,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/forta-network/forta-core-go/clients/health"
 	"github.com/forta-network/forta-core-go/protocol"
 	"github.com/golang/protobuf/jsonpb"
+	"github.com/ipfs/go-cid"
 
 	"github.com/forta-network/forta-core-go/testutils/testhttp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type testObj struct {
@@ -145,3 +150,62 @@ func TestCalculateFileHash(t *testing.T) {
 		t.Fatal("created cid does not match the expected")
 	}
 }
+
+func TestClient_GetBytes_Fallback(t *testing.T) {
+	r := require.New(t)
+
+	var notFoundCalls int
+	rateLimited := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer rateLimited.Close()
+	notFound := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		notFoundCalls++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer notFound.Close()
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("content"))
+	}))
+	defer working.Close()
+
+	// moves onto the next gateway until the content is found
+	c, err := NewClientMulti(rateLimited.URL, notFound.URL, working.URL)
+	r.NoError(err)
+	b, err := c.GetBytes(context.Background(), "ref")
+	r.NoError(err)
+	r.Equal("content", string(b))
+
+	report, ok := c.Health().GetByName("gateway.0-127.0.0.1.request.error")
+	r.True(ok)
+	r.Equal(health.StatusFailing, report.Status)
+	report, ok = c.Health().GetByName("gateway.2-127.0.0.1.request.error")
+	r.True(ok)
+	r.Equal(health.StatusOK, report.Status)
+
+	// gives up after enough gateways agree that the content is not found
+	c, err = NewClientMulti(notFound.URL, notFound.URL, working.URL)
+	r.NoError(err)
+	_, err = c.GetBytes(context.Background(), "ref")
+	r.ErrorIs(err, ErrNotFound)
+	r.Equal(3, notFoundCalls)
+}
+
+func TestClient_GetBlock(t *testing.T) {
+	r := require.New(t)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/ipfs/"+testCacheCID || req.URL.Query().Get("format") != "raw" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("block"))
+	}))
+	defer s.Close()
+
+	c, err := NewClient(s.URL)
+	r.NoError(err)
+	b, err := c.GetBlock(context.Background(), cid.MustParse(testCacheCID))
+	r.NoError(err)
+	r.Equal("block", string(b))
+}
@@ -9,6 +9,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	cid "github.com/ipfs/go-cid"
 )
 
 // MockClient is a mock of Client interface.
@@ -92,3 +93,41 @@ func (mr *MockClientMockRecorder) UnmarshalJson(ctx, reference, target interface
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnmarshalJson", reflect.TypeOf((*MockClient)(nil).UnmarshalJson), ctx, reference, target)
 }
+
+// MockBlockGetter is a mock of BlockGetter interface.
+type MockBlockGetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockBlockGetterMockRecorder
+}
+
+// MockBlockGetterMockRecorder is the mock recorder for MockBlockGetter.
+type MockBlockGetterMockRecorder struct {
+	mock *MockBlockGetter
+}
+
+// NewMockBlockGetter creates a new mock instance.
+func NewMockBlockGetter(ctrl *gomock.Controller) *MockBlockGetter {
+	mock := &MockBlockGetter{ctrl: ctrl}
+	mock.recorder = &MockBlockGetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBlockGetter) EXPECT() *MockBlockGetterMockRecorder {
+	return m.recorder
+}
+
+// GetBlock mocks base method.
+func (m *MockBlockGetter) GetBlock(ctx context.Context, c cid.Cid) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlock", ctx, c)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlock indicates an expected call of GetBlock.
+func (mr *MockBlockGetterMockRecorder) GetBlock(ctx, c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlock", reflect.TypeOf((*MockBlockGetter)(nil).GetBlock), ctx, c)
+}
@@ -0,0 +1,221 @@
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	files "github.com/ipfs/go-ipfs-files"
+	"github.com/ipfs/go-merkledag"
+	"github.com/ipfs/go-unixfs"
+	coreiface "github.com/ipfs/interface-go-ipfs-core"
+	"github.com/ipfs/interface-go-ipfs-core/options"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrCIDMismatch is returned when the fetched content does not match the requested CID.
+var ErrCIDMismatch = errors.New("content does not match the cid")
+
+// ErrUnverifiable is returned when the reference does not start with a CID, e.g. an IPNS name.
+var ErrUnverifiable = errors.New("reference can not be verified")
+
+// verifiedClient makes sure that the content matches the CID of the reference before it is returned.
+// The content is accepted right away if the CID can be recomputed from it with the default settings.
+// Otherwise, the content is built again from the blocks of the DAG, each one verified against its CID,
+// so the content added with any chunker or layout can be verified.
+type verifiedClient struct {
+	Client
+	blocks  BlockGetter
+	coreApi coreiface.CoreAPI
+}
+
+// GetBytes gets the content of the reference and verifies it.
+func (vc *verifiedClient) GetBytes(ctx context.Context, reference string) ([]byte, error) {
+	c, err := vc.resolve(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+	return vc.fetch(ctx, c)
+}
+
+// UnmarshalJson gets the content of the reference, verifies it and unmarshals it.
+func (vc *verifiedClient) UnmarshalJson(ctx context.Context, reference string, target interface{}) error {
+	b, err := vc.GetBytes(ctx, reference)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, target)
+}
+
+// Verify checks if the content obtained elsewhere matches the reference.
+func (vc *verifiedClient) Verify(ctx context.Context, reference string, content []byte) error {
+	c, err := vc.resolve(ctx, reference)
+	if err != nil {
+		return err
+	}
+	return vc.verify(ctx, c, content)
+}
+
+func (vc *verifiedClient) fetch(ctx context.Context, c cid.Cid) ([]byte, error) {
+	b, err := vc.Client.GetBytes(ctx, c.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := vc.verify(ctx, c, b); err != nil {
+		log.WithError(err).WithField("cid", c.String()).Error("failed to verify content from ipfs")
+		return nil, err
+	}
+	return b, nil
+}
+
+func (vc *verifiedClient) verify(ctx context.Context, expected cid.Cid, content []byte) error {
+	if vc.matchesDefaultLayout(ctx, expected, content) {
+		return nil
+	}
+	built, err := vc.buildFile(ctx, expected)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(built, content) {
+		return fmt.Errorf("%w: %s", ErrCIDMismatch, expected)
+	}
+	return nil
+}
+
+// matchesDefaultLayout tells if the CID can be recomputed from the content without fetching the blocks.
+// A mismatch only means that the content might have been added with some other settings.
+func (vc *verifiedClient) matchesDefaultLayout(ctx context.Context, expected cid.Cid, content []byte) bool {
+	prefix := expected.Prefix()
+	switch prefix.Codec {
+	case cid.Raw:
+		computed, err := prefix.Sum(content)
+		return err == nil && computed.Equals(expected)
+
+	case cid.DagProtobuf:
+		for _, rawLeaves := range []bool{prefix.Version == 1, prefix.Version == 0} {
+			path, err := vc.coreApi.Unixfs().Add(
+				ctx,
+				files.NewBytesFile(content),
+				options.Unixfs.HashOnly(true),
+				options.Unixfs.CidVersion(int(prefix.Version)),
+				options.Unixfs.Hash(prefix.MhType),
+				options.Unixfs.RawLeaves(rawLeaves),
+			)
+			if err == nil && path.Cid().Equals(expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolve finds the CID of the reference by walking the path through the verified directory blocks.
+func (vc *verifiedClient) resolve(ctx context.Context, reference string) (cid.Cid, error) {
+	p := strings.TrimPrefix(reference, "ipfs://")
+	p = strings.TrimPrefix(p, "/ipfs/")
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	c, err := cid.Decode(segments[0])
+	if err != nil {
+		return cid.Undef, fmt.Errorf("%w: %s", ErrUnverifiable, reference)
+	}
+	for _, name := range segments[1:] {
+		if name == "" {
+			continue
+		}
+		nd, fsNode, err := vc.getNode(ctx, c)
+		if err != nil {
+			return cid.Undef, err
+		}
+		if fsNode.Type() != unixfs.TDirectory {
+			// sharded directories are not resolved here
+			return cid.Undef, fmt.Errorf("failed to resolve %s: %s is not a plain directory", reference, c)
+		}
+		link, err := nd.GetNodeLink(name)
+		if err != nil {
+			return cid.Undef, fmt.Errorf("%w: %s", ErrNotFound, reference)
+		}
+		c = link.Cid
+	}
+	return c, nil
+}
+
+// buildFile builds the content of a file from its verified blocks.
+func (vc *verifiedClient) buildFile(ctx context.Context, c cid.Cid) ([]byte, error) {
+	if c.Prefix().Codec == cid.Raw {
+		return vc.getBlock(ctx, c)
+	}
+	nd, fsNode, err := vc.getNode(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if t := fsNode.Type(); t != unixfs.TFile && t != unixfs.TRaw {
+		return nil, fmt.Errorf("%s is not a file", c)
+	}
+	data := append([]byte(nil), fsNode.Data()...)
+	for _, link := range nd.Links() {
+		b, err := vc.buildFile(ctx, link.Cid)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, b...)
+	}
+	if uint64(len(data)) != fsNode.FileSize() {
+		return nil, fmt.Errorf("%w: %s has unexpected size", ErrCIDMismatch, c)
+	}
+	return data, nil
+}
+
+func (vc *verifiedClient) getNode(ctx context.Context, c cid.Cid) (*merkledag.ProtoNode, *unixfs.FSNode, error) {
+	if c.Prefix().Codec != cid.DagProtobuf {
+		return nil, nil, fmt.Errorf("unsupported codec of %s: %d", c, c.Prefix().Codec)
+	}
+	b, err := vc.getBlock(ctx, c)
+	if err != nil {
+		return nil, nil, err
+	}
+	nd, err := merkledag.DecodeProtobuf(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode %s: %v", c, err)
+	}
+	fsNode, err := unixfs.FSNodeFromBytes(nd.Data())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode unixfs data of %s: %v", c, err)
+	}
+	return nd, fsNode, nil
+}
+
+func (vc *verifiedClient) getBlock(ctx context.Context, c cid.Cid) ([]byte, error) {
+	if vc.blocks == nil {
+		return nil, fmt.Errorf("%w: no blocks to verify %s with", ErrCIDMismatch, c)
+	}
+	b, err := vc.blocks.GetBlock(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	computed, err := c.Prefix().Sum(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate cid: %v", err)
+	}
+	if !computed.Equals(c) {
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrCIDMismatch, c, computed)
+	}
+	return b, nil
+}
+
+// NewVerifiedClient creates a client which verifies the content from given client by using the
+// blocks from given block getter.
+func NewVerifiedClient(client Client, blocks BlockGetter) (*verifiedClient, error) {
+	coreApi, err := newCoreAPI()
+	if err != nil {
+		return nil, err
+	}
+	return &verifiedClient{
+		Client:  client,
+		blocks:  blocks,
+		coreApi: coreApi,
+	}, nil
+}
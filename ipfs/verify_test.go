@@ -0,0 +1,95 @@
+package ipfs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-merkledag"
+	"github.com/ipfs/go-unixfs"
+	"github.com/stretchr/testify/require"
+
+	mock_ipfs "github.com/forta-network/forta-core-go/ipfs/mocks"
+)
+
+// newTestFile builds a file from raw leaves which are chunked differently than the default settings would.
+func newTestFile(r *require.Assertions, blocks map[cid.Cid][]byte, chunks ...string) *merkledag.ProtoNode {
+	fsNode := unixfs.NewFSNode(unixfs.TFile)
+	root := new(merkledag.ProtoNode)
+	for _, chunk := range chunks {
+		leaf := merkledag.NewRawNode([]byte(chunk))
+		blocks[leaf.Cid()] = leaf.RawData()
+		r.NoError(root.AddNodeLink("", leaf))
+		fsNode.AddBlockSize(uint64(len(chunk)))
+	}
+	data, err := fsNode.GetBytes()
+	r.NoError(err)
+	root.SetData(data)
+	blocks[root.Cid()] = root.RawData()
+	return root
+}
+
+func newTestVerifiedClient(r *require.Assertions, ctrl *gomock.Controller, blocks map[cid.Cid][]byte) (*verifiedClient, *mock_ipfs.MockClient) {
+	ic := mock_ipfs.NewMockClient(ctrl)
+	blockGetter := mock_ipfs.NewMockBlockGetter(ctrl)
+	blockGetter.EXPECT().GetBlock(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, c cid.Cid) ([]byte, error) {
+			b, ok := blocks[c]
+			if !ok {
+				return nil, ErrNotFound
+			}
+			return b, nil
+		},
+	).AnyTimes()
+	vc, err := NewVerifiedClient(ic, blockGetter)
+	r.NoError(err)
+	return vc, ic
+}
+
+func TestVerifiedClient_GetBytes(t *testing.T) {
+	r := require.New(t)
+
+	ctx := context.Background()
+	blocks := make(map[cid.Cid][]byte)
+	root := newTestFile(r, blocks, "hello ", "world\n")
+	vc, ic := newTestVerifiedClient(r, gomock.NewController(t), blocks)
+
+	ic.EXPECT().GetBytes(ctx, root.Cid().String()).Return([]byte("hello world\n"), nil)
+	b, err := vc.GetBytes(ctx, root.Cid().String())
+	r.NoError(err)
+	r.Equal("hello world\n", string(b))
+
+	ic.EXPECT().GetBytes(ctx, root.Cid().String()).Return([]byte("hello there\n"), nil)
+	_, err = vc.GetBytes(ctx, root.Cid().String())
+	r.ErrorIs(err, ErrCIDMismatch)
+
+	// a forged block is not accepted
+	blocks[root.Cid()] = []byte("forged")
+	ic.EXPECT().GetBytes(ctx, root.Cid().String()).Return([]byte("hello world\n"), nil)
+	_, err = vc.GetBytes(ctx, root.Cid().String())
+	r.ErrorIs(err, ErrCIDMismatch)
+}
+
+func TestVerifiedClient_Path(t *testing.T) {
+	r := require.New(t)
+
+	ctx := context.Background()
+	blocks := make(map[cid.Cid][]byte)
+	file := newTestFile(r, blocks, "hello ", "world\n")
+	dir := merkledag.NodeWithData(unixfs.FolderPBData())
+	r.NoError(dir.AddNodeLink("manifest.json", file))
+	blocks[dir.Cid()] = dir.RawData()
+	vc, ic := newTestVerifiedClient(r, gomock.NewController(t), blocks)
+
+	ic.EXPECT().GetBytes(ctx, file.Cid().String()).Return([]byte("hello world\n"), nil)
+	b, err := vc.GetBytes(ctx, "/ipfs/"+dir.Cid().String()+"/manifest.json")
+	r.NoError(err)
+	r.Equal("hello world\n", string(b))
+
+	_, err = vc.GetBytes(ctx, dir.Cid().String()+"/other.json")
+	r.ErrorIs(err, ErrNotFound)
+
+	_, err = vc.GetBytes(ctx, "/ipns/forta.network")
+	r.ErrorIs(err, ErrUnverifiable)
+}
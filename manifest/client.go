@@ -22,10 +22,20 @@ func (c *client) GetAgentManifest(ctx context.Context, reference string) (*Signe
 	return &m, nil
 }
 
+// NewClient creates a client which verifies the manifests from the gateway against their CIDs.
 func NewClient(ipfsGateway string) (*client, error) {
 	ic, err := ipfs.NewClient(ipfsGateway)
 	if err != nil {
 		return nil, err
 	}
-	return &client{ic: ic}, nil
+	vc, err := ipfs.NewVerifiedClient(ic, ic)
+	if err != nil {
+		return nil, err
+	}
+	return &client{ic: vc}, nil
+}
+
+// NewClientWithIPFS creates a client which uses given IPFS client, e.g. a cached one.
+func NewClientWithIPFS(ic ipfs.Client) *client {
+	return &client{ic: ic}
 }
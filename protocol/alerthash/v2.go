@@ -0,0 +1,317 @@
+package alerthash
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/utils"
+)
+
+// Version2 is the alert hash version which hashes a canonical protobuf encoding of
+// the whole finding, including the metadata, the labels and the source.
+const Version2 = "2"
+
+// ErrUnknownVersion is returned when an alert hash version is not known or
+// when an alert ID does not match any version.
+var ErrUnknownVersion = errors.New("unknown alert hash version")
+
+// field numbers of the v2 hash input
+const (
+	v2FieldVersion protowire.Number = iota + 1
+	v2FieldChainID
+	v2FieldEventHash
+	v2FieldBotImage
+	v2FieldBotID
+	v2FieldTxAddresses
+	v2FieldFinding
+	v2FieldUniqueKey
+)
+
+// ForBlockAlertV2 calculates the v2 hash for the block alert.
+func ForBlockAlertV2(inputs *Inputs) (string, error) {
+	return calculateV2(
+		inputs,
+		inputs.BlockEvent.GetNetwork().GetChainId(),
+		inputs.BlockEvent.GetBlockHash(),
+		nil,
+	)
+}
+
+// ForTransactionAlertV2 calculates the v2 hash for the transaction alert.
+func ForTransactionAlertV2(inputs *Inputs) (string, error) {
+	var txAddrs []string
+	if inputs.TransactionEvent != nil {
+		txAddrs = utils.MapKeys(inputs.TransactionEvent.TxAddresses)
+	}
+	return calculateV2(
+		inputs,
+		inputs.TransactionEvent.GetNetwork().GetChainId(),
+		inputs.TransactionEvent.GetTransaction().GetHash(),
+		txAddrs,
+	)
+}
+
+// ForCombinationAlertV2 calculates the v2 hash for the alert handler alert.
+func ForCombinationAlertV2(inputs *Inputs) (string, error) {
+	return calculateV2(
+		inputs,
+		"",
+		inputs.AlertEvent.GetAlert().GetHash(),
+		nil,
+	)
+}
+
+// calculateV2 hashes the inputs as fields of a protobuf message so that different
+// values can never produce the same hash input.
+func calculateV2(inputs *Inputs, chainID, eventHash string, txAddrs []string) (string, error) {
+	var b []byte
+	b = appendString(b, v2FieldVersion, Version2)
+	b = appendString(b, v2FieldBotID, inputs.BotID)
+
+	if inputs.Finding.GetUniqueKey() != "" {
+		b = appendString(b, v2FieldUniqueKey, inputs.Finding.UniqueKey)
+		return crypto.Keccak256Hash(b).Hex(), nil
+	}
+
+	finding, err := canonicalFinding(inputs.Finding)
+	if err != nil {
+		return "", err
+	}
+	sortedTxAddrs := append([]string(nil), txAddrs...)
+	sort.Strings(sortedTxAddrs)
+
+	b = appendString(b, v2FieldChainID, chainID)
+	b = appendString(b, v2FieldEventHash, eventHash)
+	b = appendString(b, v2FieldBotImage, inputs.BotImage)
+	for _, txAddr := range sortedTxAddrs {
+		b = appendString(b, v2FieldTxAddresses, txAddr)
+	}
+	b = protowire.AppendTag(b, v2FieldFinding, protowire.BytesType)
+	b = protowire.AppendBytes(b, finding)
+	return crypto.Keccak256Hash(b).Hex(), nil
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// canonicalFinding encodes the finding field by field in the order of the field numbers so that the
+// encoding does not depend on the protobuf library. The timestamp and the unknown fields are left out,
+// the map entries are sorted by the key and the repeated strings and messages which don't have
+// a meaningful order are sorted by their value or by their encoding.
+func canonicalFinding(finding *protocol.Finding) ([]byte, error) {
+	if finding == nil {
+		return nil, errors.New("nil finding")
+	}
+	var b []byte
+	b = appendStringField(b, 1, finding.Protocol)
+	b = appendVarintField(b, 2, uint64(finding.Severity))
+	for _, key := range sortedKeys(finding.Metadata) {
+		var entry []byte
+		entry = appendString(entry, 1, key)
+		entry = appendString(entry, 2, finding.Metadata[key])
+		b = appendBytesField(b, 3, entry)
+	}
+	b = appendVarintField(b, 4, uint64(finding.Type))
+	b = appendStringField(b, 5, finding.AlertId)
+	b = appendStringField(b, 6, finding.Name)
+	b = appendStringField(b, 7, finding.Description)
+	b = appendBoolField(b, 9, finding.Private)
+	b = appendSortedStrings(b, 10, finding.Addresses)
+	for _, key := range sortedKeys(finding.Indicators) {
+		var entry []byte
+		entry = appendString(entry, 1, key)
+		entry = protowire.AppendTag(entry, 2, protowire.Fixed64Type)
+		entry = protowire.AppendFixed64(entry, math.Float64bits(finding.Indicators[key]))
+		b = appendBytesField(b, 11, entry)
+	}
+	var labels [][]byte
+	for _, label := range finding.Labels {
+		labels = append(labels, canonicalLabel(label))
+	}
+	b = appendSortedMessages(b, 12, labels)
+	b = appendSortedStrings(b, 13, finding.RelatedAlerts)
+	b = appendStringField(b, 14, finding.UniqueKey)
+	if finding.Source != nil {
+		b = protowire.AppendTag(b, 15, protowire.BytesType)
+		b = protowire.AppendBytes(b, canonicalSource(finding.Source))
+	}
+	return b, nil
+}
+
+func canonicalLabel(label *protocol.Label) []byte {
+	var b []byte
+	b = appendVarintField(b, 1, uint64(label.GetEntityType()))
+	b = appendStringField(b, 2, label.GetEntity())
+	if bits := math.Float32bits(label.GetConfidence()); bits != 0 {
+		b = protowire.AppendTag(b, 4, protowire.Fixed32Type)
+		b = protowire.AppendFixed32(b, bits)
+	}
+	b = appendBoolField(b, 6, label.GetRemove())
+	b = appendStringField(b, 7, label.GetLabel())
+	b = appendSortedStrings(b, 8, label.GetMetadata())
+	b = appendStringField(b, 9, label.GetUniqueKey())
+	// the order of the embedding values matters
+	if len(label.GetEmbedding()) > 0 {
+		var packed []byte
+		for _, v := range label.GetEmbedding() {
+			packed = protowire.AppendFixed32(packed, math.Float32bits(v))
+		}
+		b = appendBytesField(b, 10, packed)
+	}
+	return b
+}
+
+func canonicalSource(source *protocol.Source) []byte {
+	var b []byte
+	var items [][]byte
+	for _, tx := range source.Transactions {
+		var item []byte
+		item = appendVarintField(item, 1, tx.GetChainId())
+		item = appendStringField(item, 2, tx.GetHash())
+		items = append(items, item)
+	}
+	b = appendSortedMessages(b, 1, items)
+
+	items = nil
+	for _, block := range source.Blocks {
+		var item []byte
+		item = appendVarintField(item, 1, block.GetChainId())
+		item = appendStringField(item, 2, block.GetHash())
+		item = appendVarintField(item, 3, block.GetNumber())
+		items = append(items, item)
+	}
+	b = appendSortedMessages(b, 2, items)
+
+	items = nil
+	for _, url := range source.Urls {
+		items = append(items, appendStringField(nil, 1, url.GetUrl()))
+	}
+	b = appendSortedMessages(b, 3, items)
+
+	items = nil
+	for _, chain := range source.Chains {
+		items = append(items, appendVarintField(nil, 1, chain.GetChainId()))
+	}
+	b = appendSortedMessages(b, 4, items)
+
+	items = nil
+	for _, alert := range source.Alerts {
+		items = append(items, appendStringField(nil, 1, alert.GetId()))
+	}
+	b = appendSortedMessages(b, 5, items)
+
+	items = nil
+	for _, custom := range source.CustomSources {
+		var item []byte
+		item = appendStringField(item, 1, custom.GetName())
+		item = appendStringField(item, 2, custom.GetValue())
+		items = append(items, item)
+	}
+	return appendSortedMessages(b, 6, items)
+}
+
+// appendStringField appends the string like a proto3 field, which is left out if it is empty.
+func appendStringField(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	return appendString(b, num, s)
+}
+
+func appendBytesField(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendBoolField(b []byte, num protowire.Number, v bool) []byte {
+	return appendVarintField(b, num, protowire.EncodeBool(v))
+}
+
+func appendSortedStrings(b []byte, num protowire.Number, strs []string) []byte {
+	strs = append([]string(nil), strs...)
+	sort.Strings(strs)
+	for _, s := range strs {
+		b = appendString(b, num, s)
+	}
+	return b
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func appendSortedMessages(b []byte, num protowire.Number, encoded [][]byte) []byte {
+	sort.SliceStable(encoded, func(i, j int) bool {
+		return bytes.Compare(encoded[i], encoded[j]) < 0
+	})
+	for _, item := range encoded {
+		b = appendBytesField(b, num, item)
+	}
+	return b
+}
+
+// ForAlert calculates the hash for the alert with the given version, by using the
+// event which is set in the inputs.
+func ForAlert(version string, inputs *Inputs) (string, error) {
+	switch version {
+	case Version:
+		switch {
+		case inputs.BlockEvent != nil:
+			return ForBlockAlert(inputs), nil
+		case inputs.TransactionEvent != nil:
+			return ForTransactionAlert(inputs), nil
+		case inputs.AlertEvent != nil:
+			return ForCombinationAlert(inputs), nil
+		}
+
+	case Version2:
+		switch {
+		case inputs.BlockEvent != nil:
+			return ForBlockAlertV2(inputs)
+		case inputs.TransactionEvent != nil:
+			return ForTransactionAlertV2(inputs)
+		case inputs.AlertEvent != nil:
+			return ForCombinationAlertV2(inputs)
+		}
+
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnknownVersion, version)
+	}
+	return "", errors.New("no event in inputs")
+}
+
+// DetectVersion finds the alert hash version which produced the alert ID from the inputs.
+func DetectVersion(inputs *Inputs, alertID string) (string, error) {
+	for _, version := range []string{Version2, Version} {
+		hash, err := ForAlert(version, inputs)
+		if err != nil {
+			return "", err
+		}
+		if hash == alertID {
+			return version, nil
+		}
+	}
+	return "", ErrUnknownVersion
+}
@@ -0,0 +1,178 @@
+package alerthash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/forta-network/forta-core-go/protocol"
+)
+
+func testV2Inputs(finding *protocol.Finding) *Inputs {
+	return &Inputs{
+		BlockEvent: &protocol.BlockEvent{
+			BlockHash: "0x123",
+			Network: &protocol.BlockEvent_Network{
+				ChainId: "1",
+			},
+		},
+		Finding: finding,
+		BotInfo: BotInfo{
+			BotImage: "image",
+			BotID:    "0xbot",
+		},
+	}
+}
+
+func testV2Finding() *protocol.Finding {
+	return &protocol.Finding{
+		AlertId:     "alert1",
+		Name:        "Alert 1",
+		Description: "This is Alert 1",
+		Protocol:    "Protocol 1",
+		Type:        protocol.Finding_EXPLOIT,
+		Severity:    protocol.Finding_HIGH,
+		Metadata:    map[string]string{"a": "1", "b": "2"},
+		Labels: []*protocol.Label{
+			{Entity: "0x1", Label: "attacker", Metadata: []string{"x", "y"}},
+			{Entity: "0x2", Label: "victim"},
+		},
+		Source: &protocol.Source{
+			Transactions: []*protocol.Source_TransactionSource{{ChainId: 1, Hash: "0xtx"}},
+		},
+	}
+}
+
+func TestForBlockAlertV2_Delimiters(t *testing.T) {
+	r := require.New(t)
+
+	finding1 := testV2Finding()
+	finding1.Name = "Alert 1This"
+	finding1.Description = " is Alert 1"
+	finding2 := testV2Finding()
+
+	// v1 can't tell these apart
+	r.Equal(ForBlockAlert(testV2Inputs(finding1)), ForBlockAlert(testV2Inputs(finding2)))
+
+	hash1, err := ForBlockAlertV2(testV2Inputs(finding1))
+	r.NoError(err)
+	hash2, err := ForBlockAlertV2(testV2Inputs(finding2))
+	r.NoError(err)
+	r.NotEqual(hash1, hash2)
+}
+
+func TestForBlockAlertV2_Canonical(t *testing.T) {
+	r := require.New(t)
+
+	expected, err := ForBlockAlertV2(testV2Inputs(testV2Finding()))
+	r.NoError(err)
+
+	// the order of the labels and the timestamp don't matter
+	finding := testV2Finding()
+	finding.Labels[0], finding.Labels[1] = finding.Labels[1], finding.Labels[0]
+	finding.Labels[1].Metadata = []string{"y", "x"}
+	finding.Timestamp = "2023-01-01T00:00:00Z"
+	hash, err := ForBlockAlertV2(testV2Inputs(finding))
+	r.NoError(err)
+	r.Equal(expected, hash)
+	// the inputs are not modified
+	r.Equal("0x2", finding.Labels[0].Entity)
+
+	// the metadata, the labels and the source matter
+	finding = testV2Finding()
+	finding.Metadata["a"] = "3"
+	hash, err = ForBlockAlertV2(testV2Inputs(finding))
+	r.NoError(err)
+	r.NotEqual(expected, hash)
+
+	finding = testV2Finding()
+	finding.Labels[0].Label = "victim"
+	hash, err = ForBlockAlertV2(testV2Inputs(finding))
+	r.NoError(err)
+	r.NotEqual(expected, hash)
+
+	finding = testV2Finding()
+	finding.Source.Transactions[0].Hash = "0xothertx"
+	hash, err = ForBlockAlertV2(testV2Inputs(finding))
+	r.NoError(err)
+	r.NotEqual(expected, hash)
+}
+
+func TestForBlockAlertV2_Golden(t *testing.T) {
+	r := require.New(t)
+
+	hash, err := ForBlockAlertV2(testV2Inputs(testV2Finding()))
+	r.NoError(err)
+	r.Equal("0xc51c76634f7349beae38423b245e4ef34dac21ca140556acc0c65155f1f5d58c", hash)
+
+	// the unknown fields are not hashed
+	finding := testV2Finding()
+	unknown := protowire.AppendTag(nil, 99, protowire.VarintType)
+	unknown = protowire.AppendVarint(unknown, 1)
+	finding.ProtoReflect().SetUnknown(protoreflect.RawFields(unknown))
+	hash, err = ForBlockAlertV2(testV2Inputs(finding))
+	r.NoError(err)
+	r.Equal("0xc51c76634f7349beae38423b245e4ef34dac21ca140556acc0c65155f1f5d58c", hash)
+
+	finding = testV2Finding()
+	finding.Private = true
+	finding.Addresses = []string{"0xb", "0xa"}
+	finding.Indicators = map[string]float64{"y": 2, "x": 1.5}
+	finding.RelatedAlerts = []string{"0xr2", "0xr1"}
+	finding.Labels = []*protocol.Label{
+		{
+			EntityType: protocol.Label_ADDRESS,
+			Entity:     "0x1",
+			Confidence: 0.5,
+			Remove:     true,
+			Label:      "attacker",
+			Metadata:   []string{"y", "x"},
+			UniqueKey:  "lk",
+			Embedding:  []float32{1, 0.25},
+		},
+	}
+	finding.Source = &protocol.Source{
+		Transactions:  []*protocol.Source_TransactionSource{{ChainId: 1, Hash: "0xtx"}},
+		Blocks:        []*protocol.Source_BlockSource{{ChainId: 1, Hash: "0xblock", Number: 10}},
+		Urls:          []*protocol.Source_URLSource{{Url: "https://forta.org"}},
+		Chains:        []*protocol.Source_ChainSource{{ChainId: 137}, {ChainId: 1}},
+		Alerts:        []*protocol.Source_AlertSource{{Id: "0xalert"}},
+		CustomSources: []*protocol.Source_CustomSource{{Name: "name", Value: "value"}},
+	}
+	hash, err = ForBlockAlertV2(testV2Inputs(finding))
+	r.NoError(err)
+	r.Equal("0x36dc38db566ac700d239e20fe49ff0081e1e005a76149ad8d57c49b3ec1b60e8", hash)
+
+	finding = testV2Finding()
+	finding.UniqueKey = "unique"
+	hash, err = ForBlockAlertV2(testV2Inputs(finding))
+	r.NoError(err)
+	r.Equal("0xfe705b6eb1ff23d5cd5c820f0e78f9a3ba053b85333ada1d8e601061bb7d8ff3", hash)
+}
+
+func TestDetectVersion(t *testing.T) {
+	r := require.New(t)
+
+	inputs := testV2Inputs(testV2Finding())
+	hashV1, err := ForAlert(Version, inputs)
+	r.NoError(err)
+	hashV2, err := ForAlert(Version2, inputs)
+	r.NoError(err)
+	r.NotEqual(hashV1, hashV2)
+
+	version, err := DetectVersion(inputs, hashV1)
+	r.NoError(err)
+	r.Equal(Version, version)
+
+	version, err = DetectVersion(inputs, hashV2)
+	r.NoError(err)
+	r.Equal(Version2, version)
+
+	_, err = DetectVersion(inputs, "0x1234")
+	r.ErrorIs(err, ErrUnknownVersion)
+
+	_, err = ForAlert("3", inputs)
+	r.ErrorIs(err, ErrUnknownVersion)
+}
@@ -0,0 +1,451 @@
+package batching
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/forta-network/forta-core-go/encoding"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/protocol/alerthash"
+	"github.com/forta-network/forta-core-go/security"
+	"github.com/forta-network/forta-core-go/utils"
+)
+
+// Default batch limits
+const (
+	DefaultMaxAlerts = 1000
+	DefaultMaxSize   = 5 << 20
+)
+
+// BatchBuilderConfig contains the batch builder settings.
+type BatchBuilderConfig struct {
+	ChainID        uint64
+	ScannerVersion *protocol.ScannerVersion
+	// Signer signs the alerts and the batches. Its address is the scanner address in the alerts.
+	Signer security.Signer
+	// Codec encodes the signed batches. The default is gzip.
+	Codec string
+	// MaxAlerts is the max number of alerts in a batch.
+	MaxAlerts int
+	// MaxSize is the approximate max size of a batch in bytes before encoding.
+	MaxSize int
+}
+
+// SignedBatch is a batch which is complete and signed.
+type SignedBatch struct {
+	Batch   *protocol.AlertBatch
+	Payload *protocol.SignedPayload
+}
+
+// Summary creates the summary of the batch after the batch is stored with given reference.
+func (sb *SignedBatch) Summary(ref, previousReceipt string) *protocol.BatchSummary {
+	return &protocol.BatchSummary{
+		Batch:             ref,
+		ChainId:           sb.Batch.ChainId,
+		BlockStart:        sb.Batch.BlockStart,
+		BlockEnd:          sb.Batch.BlockEnd,
+		AlertCount:        sb.Batch.AlertCount,
+		ScannerVersion:    sb.Batch.ScannerVersion,
+		PreviousReceipt:   previousReceipt,
+		LatestBlockInput:  sb.Batch.LatestBlockInput,
+		Timestamp:         time.Now().UTC().Format(time.RFC3339),
+		InspectionResults: sb.Batch.InspectionResults,
+		Provider:          sb.Batch.Provider,
+	}
+}
+
+// pendingBatch indexes the batch which is being built.
+type pendingBatch struct {
+	batch        *protocol.AlertBatch
+	blocks       map[uint64]*protocol.BlockResults
+	txs          map[string]*protocol.TransactionResults
+	combinations map[string]*protocol.CombinationAlertResults
+	agents       map[string]*protocol.BatchAgent
+	private      map[string]*protocol.AgentAlerts
+	size         int
+	// hasBlocks tells if the block range is set since block 0 is a valid start
+	hasBlocks bool
+}
+
+func newPendingBatch(cfg *BatchBuilderConfig) *pendingBatch {
+	return &pendingBatch{
+		batch: &protocol.AlertBatch{
+			ChainId:        cfg.ChainID,
+			ScannerVersion: cfg.ScannerVersion,
+		},
+		blocks:       make(map[uint64]*protocol.BlockResults),
+		txs:          make(map[string]*protocol.TransactionResults),
+		combinations: make(map[string]*protocol.CombinationAlertResults),
+		agents:       make(map[string]*protocol.BatchAgent),
+		private:      make(map[string]*protocol.AgentAlerts),
+	}
+}
+
+func (pb *pendingBatch) isEmpty() bool {
+	return len(pb.agents) == 0 && len(pb.batch.Metrics) == 0
+}
+
+// BatchBuilder groups the bot responses into alert batches and splits the batches
+// when they reach the alert count or the size limit.
+type BatchBuilder struct {
+	cfg     BatchBuilderConfig
+	chainID string
+	current *pendingBatch
+	ready   []*SignedBatch
+	mu      sync.Mutex
+}
+
+// NewBatchBuilder creates a new batch builder.
+func NewBatchBuilder(cfg BatchBuilderConfig) *BatchBuilder {
+	if cfg.MaxAlerts <= 0 {
+		cfg.MaxAlerts = DefaultMaxAlerts
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = DefaultMaxSize
+	}
+	if len(cfg.Codec) == 0 {
+		cfg.Codec = encoding.CodecGzip
+	}
+	return &BatchBuilder{
+		cfg:     cfg,
+		chainID: hexutil.EncodeUint64(cfg.ChainID),
+		current: newPendingBatch(&cfg),
+	}
+}
+
+// AddTxResponse adds the findings of a bot for a transaction.
+func (bb *BatchBuilder) AddTxResponse(agent *protocol.AgentInfo, event *protocol.TransactionEvent, resp *protocol.EvaluateTxResponse) error {
+	blockNumber, err := hexutil.DecodeUint64(event.GetBlock().GetBlockNumber())
+	if err != nil {
+		return fmt.Errorf("invalid tx block number: %v", err)
+	}
+	txHash := event.GetTransaction().GetHash()
+	alerts, err := bb.newAlerts(resp.Findings, func(finding *protocol.Finding) (*protocol.SignedAlert, error) {
+		inputs := &alerthash.Inputs{TransactionEvent: event, Finding: finding, BotInfo: botInfo(agent)}
+		return bb.newAlert(agent, protocol.AlertType_TRANSACTION, resp.Private, inputs, alerthash.ForTransactionAlert(inputs), resp.Timestamp, blockNumber)
+	})
+	if err != nil {
+		return err
+	}
+
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+
+	t := bb.newTracker(func(pb *pendingBatch) {
+		pb.includeBlock(blockNumber)
+		batchAgent := pb.batchAgent(agent)
+		batchAgent.Transactions = append(batchAgent.Transactions, txHash)
+	})
+	for _, alert := range alerts {
+		pb, err := bb.reserve(alert)
+		if err != nil {
+			return err
+		}
+		t.touch(pb)
+		if isPrivate(resp.Private, alert.Alert.Finding) {
+			pb.addPrivateAlert(agent, alert)
+			continue
+		}
+		txResults, ok := pb.txs[txHash]
+		if !ok {
+			txResults = &protocol.TransactionResults{Transaction: event}
+			blockResults := pb.blockResults(blockNumber, event.GetBlock().GetBlockHash(), event.GetBlock().GetBlockTimestamp())
+			blockResults.Transactions = append(blockResults.Transactions, txResults)
+			pb.txs[txHash] = txResults
+			pb.size += proto.Size(event)
+		}
+		appendAgentAlert(&txResults.Results, agent, alert)
+	}
+
+	t.done()
+	return nil
+}
+
+// AddBlockResponse adds the findings of a bot for a block.
+func (bb *BatchBuilder) AddBlockResponse(agent *protocol.AgentInfo, event *protocol.BlockEvent, resp *protocol.EvaluateBlockResponse) error {
+	blockNumber, err := hexutil.DecodeUint64(event.GetBlockNumber())
+	if err != nil {
+		return fmt.Errorf("invalid block number: %v", err)
+	}
+	alerts, err := bb.newAlerts(resp.Findings, func(finding *protocol.Finding) (*protocol.SignedAlert, error) {
+		inputs := &alerthash.Inputs{BlockEvent: event, Finding: finding, BotInfo: botInfo(agent)}
+		return bb.newAlert(agent, protocol.AlertType_BLOCK, resp.Private, inputs, alerthash.ForBlockAlert(inputs), resp.Timestamp, blockNumber)
+	})
+	if err != nil {
+		return err
+	}
+
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+
+	t := bb.newTracker(func(pb *pendingBatch) {
+		pb.includeBlock(blockNumber)
+		batchAgent := pb.batchAgent(agent)
+		batchAgent.Blocks = append(batchAgent.Blocks, blockNumber)
+	})
+	for _, alert := range alerts {
+		pb, err := bb.reserve(alert)
+		if err != nil {
+			return err
+		}
+		t.touch(pb)
+		if isPrivate(resp.Private, alert.Alert.Finding) {
+			pb.addPrivateAlert(agent, alert)
+			continue
+		}
+		blockResults := pb.blockResults(blockNumber, event.GetBlockHash(), event.GetBlock().GetTimestamp())
+		appendAgentAlert(&blockResults.Results, agent, alert)
+	}
+
+	t.done()
+	return nil
+}
+
+// AddAlertResponse adds the findings of a bot for an alert.
+func (bb *BatchBuilder) AddAlertResponse(agent *protocol.AgentInfo, event *protocol.AlertEvent, resp *protocol.EvaluateAlertResponse) error {
+	alertHash := event.GetAlert().GetHash()
+	blockNumber := event.GetAlert().GetSource().GetBlock().GetNumber()
+	alerts, err := bb.newAlerts(resp.Findings, func(finding *protocol.Finding) (*protocol.SignedAlert, error) {
+		inputs := &alerthash.Inputs{AlertEvent: event, Finding: finding, BotInfo: botInfo(agent)}
+		return bb.newAlert(agent, protocol.AlertType_COMBINATION, resp.Private, inputs, alerthash.ForCombinationAlert(inputs), resp.Timestamp, blockNumber)
+	})
+	if err != nil {
+		return err
+	}
+
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+
+	t := bb.newTracker(func(pb *pendingBatch) {
+		batchAgent := pb.batchAgent(agent)
+		batchAgent.Combinations = append(batchAgent.Combinations, alertHash)
+	})
+	for _, alert := range alerts {
+		pb, err := bb.reserve(alert)
+		if err != nil {
+			return err
+		}
+		t.touch(pb)
+		if isPrivate(resp.Private, alert.Alert.Finding) {
+			pb.addPrivateAlert(agent, alert)
+			continue
+		}
+		results, ok := pb.combinations[alertHash]
+		if !ok {
+			results = &protocol.CombinationAlertResults{AlertEvent: event}
+			pb.batch.CombinationAlerts = append(pb.batch.CombinationAlerts, results)
+			pb.combinations[alertHash] = results
+			pb.size += proto.Size(event)
+		}
+		appendAgentAlert(&results.Results, agent, alert)
+	}
+
+	t.done()
+	return nil
+}
+
+// AddMetrics adds the bot metrics to the current batch.
+func (bb *BatchBuilder) AddMetrics(metrics ...*protocol.AgentMetrics) {
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+
+	for _, m := range metrics {
+		bb.current.batch.Metrics = append(bb.current.batch.Metrics, m)
+		bb.current.size += proto.Size(m)
+	}
+}
+
+// Ready returns the batches which were completed by reaching the limits.
+func (bb *BatchBuilder) Ready() []*SignedBatch {
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+
+	ready := bb.ready
+	bb.ready = nil
+	return ready
+}
+
+// Flush completes the current batch and returns it with the other completed batches.
+func (bb *BatchBuilder) Flush() ([]*SignedBatch, error) {
+	bb.mu.Lock()
+	defer bb.mu.Unlock()
+
+	if !bb.current.isEmpty() {
+		if err := bb.seal(); err != nil {
+			return nil, err
+		}
+	}
+	ready := bb.ready
+	bb.ready = nil
+	return ready, nil
+}
+
+// responseTracker records a response once in each batch which receives its alerts
+// and in the current batch, so that the split batches list the response inputs.
+type responseTracker struct {
+	bb     *BatchBuilder
+	last   *pendingBatch
+	record func(pb *pendingBatch)
+}
+
+func (bb *BatchBuilder) newTracker(record func(pb *pendingBatch)) *responseTracker {
+	return &responseTracker{bb: bb, record: record}
+}
+
+func (t *responseTracker) touch(pb *pendingBatch) {
+	if pb != t.last {
+		t.last = pb
+		t.record(pb)
+	}
+}
+
+func (t *responseTracker) done() {
+	t.touch(t.bb.current)
+}
+
+// reserve counts the alert in the current batch and starts a new batch first if
+// the alert does not fit.
+func (bb *BatchBuilder) reserve(alert *protocol.SignedAlert) (*pendingBatch, error) {
+	alertSize := proto.Size(alert)
+	pb := bb.current
+	if pb.batch.AlertCount > 0 && (int(pb.batch.AlertCount) >= bb.cfg.MaxAlerts || pb.size+alertSize > bb.cfg.MaxSize) {
+		if err := bb.seal(); err != nil {
+			return nil, err
+		}
+		pb = bb.current
+	}
+	pb.batch.AlertCount++
+	pb.size += alertSize
+	if severity := alert.Alert.Finding.Severity; severity > pb.batch.MaxSeverity {
+		pb.batch.MaxSeverity = severity
+	}
+	return pb, nil
+}
+
+// seal signs the current batch and starts a new one.
+func (bb *BatchBuilder) seal() error {
+	batch := bb.current.batch
+	payload, err := security.SignBatchWithCodec(context.Background(), bb.cfg.Signer, bb.cfg.Codec, batch)
+	if err != nil {
+		return fmt.Errorf("failed to sign batch: %v", err)
+	}
+	bb.ready = append(bb.ready, &SignedBatch{Batch: batch, Payload: payload})
+	bb.current = newPendingBatch(&bb.cfg)
+	return nil
+}
+
+// newAlerts signs all alerts of a response before any of them is added to a batch so that
+// a signing error does not leave a partial response in the batch.
+func (bb *BatchBuilder) newAlerts(
+	findings []*protocol.Finding, newAlert func(finding *protocol.Finding) (*protocol.SignedAlert, error),
+) ([]*protocol.SignedAlert, error) {
+	alerts := make([]*protocol.SignedAlert, 0, len(findings))
+	for _, finding := range findings {
+		alert, err := newAlert(finding)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+func (bb *BatchBuilder) newAlert(
+	agent *protocol.AgentInfo, alertType protocol.AlertType, private bool,
+	inputs *alerthash.Inputs, alertID, timestamp string, blockNumber uint64,
+) (*protocol.SignedAlert, error) {
+	if isPrivate(private, inputs.Finding) {
+		alertType = protocol.AlertType_PRIVATE
+	}
+	if len(timestamp) == 0 {
+		timestamp = time.Now().UTC().Format(utils.AlertTimeFormat)
+	}
+	alert, err := security.SignAlertWithSigner(context.Background(), bb.cfg.Signer, &protocol.Alert{
+		Id:        alertID,
+		Type:      alertType,
+		Finding:   inputs.Finding,
+		Timestamp: timestamp,
+		Agent:     agent,
+		Scanner:   &protocol.ScannerInfo{Address: bb.cfg.Signer.Address().Hex()},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign alert: %v", err)
+	}
+	alert.ChainId = bb.chainID
+	alert.BlockNumber = hexutil.EncodeUint64(blockNumber)
+	return alert, nil
+}
+
+func (pb *pendingBatch) blockResults(number uint64, hash, timestamp string) *protocol.BlockResults {
+	results, ok := pb.blocks[number]
+	if !ok {
+		results = &protocol.BlockResults{
+			Block: &protocol.Block{
+				BlockHash:      hash,
+				BlockNumber:    number,
+				BlockTimestamp: timestamp,
+			},
+		}
+		pb.batch.Results = append(pb.batch.Results, results)
+		pb.blocks[number] = results
+		pb.size += proto.Size(results.Block)
+	}
+	return results
+}
+
+func (pb *pendingBatch) includeBlock(number uint64) {
+	if !pb.hasBlocks || number < pb.batch.BlockStart {
+		pb.batch.BlockStart = number
+	}
+	if !pb.hasBlocks || number > pb.batch.BlockEnd {
+		pb.batch.BlockEnd = number
+	}
+	pb.hasBlocks = true
+}
+
+func (pb *pendingBatch) batchAgent(agent *protocol.AgentInfo) *protocol.BatchAgent {
+	batchAgent, ok := pb.agents[agent.Id]
+	if !ok {
+		batchAgent = &protocol.BatchAgent{Info: agent}
+		pb.batch.Agents = append(pb.batch.Agents, batchAgent)
+		pb.agents[agent.Id] = batchAgent
+		pb.size += proto.Size(agent)
+	}
+	return batchAgent
+}
+
+func (pb *pendingBatch) addPrivateAlert(agent *protocol.AgentInfo, alert *protocol.SignedAlert) {
+	agentAlerts, ok := pb.private[agent.Id]
+	if !ok {
+		agentAlerts = &protocol.AgentAlerts{AgentManifest: agent.Manifest}
+		pb.batch.PrivateAlerts = append(pb.batch.PrivateAlerts, agentAlerts)
+		pb.private[agent.Id] = agentAlerts
+	}
+	agentAlerts.Alerts = append(agentAlerts.Alerts, alert)
+}
+
+func appendAgentAlert(results *[]*protocol.AgentAlerts, agent *protocol.AgentInfo, alert *protocol.SignedAlert) {
+	for _, agentAlerts := range *results {
+		if agentAlerts.AgentManifest == agent.Manifest {
+			agentAlerts.Alerts = append(agentAlerts.Alerts, alert)
+			return
+		}
+	}
+	*results = append(*results, &protocol.AgentAlerts{
+		AgentManifest: agent.Manifest,
+		Alerts:        []*protocol.SignedAlert{alert},
+	})
+}
+
+func isPrivate(privateResp bool, finding *protocol.Finding) bool {
+	return privateResp || finding.Private
+}
+
+func botInfo(agent *protocol.AgentInfo) alerthash.BotInfo {
+	return alerthash.BotInfo{BotImage: agent.Image, BotID: agent.Id}
+}
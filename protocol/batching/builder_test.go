@@ -0,0 +1,255 @@
+package batching
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/forta-network/forta-core-go/encoding"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/security"
+)
+
+var (
+	testAgent1 = &protocol.AgentInfo{Id: "0xagent1", Image: "image1", Manifest: "manifest1"}
+	testAgent2 = &protocol.AgentInfo{Id: "0xagent2", Image: "image2", Manifest: "manifest2"}
+)
+
+func newTestSigner(t *testing.T) security.Signer {
+	pk, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	return security.NewKeySigner(&keystore.Key{Address: crypto.PubkeyToAddress(pk.PublicKey), PrivateKey: pk})
+}
+
+func newTestBuilderWithSigner(signer security.Signer, maxAlerts, maxSize int) *BatchBuilder {
+	return NewBatchBuilder(BatchBuilderConfig{
+		ChainID:        1,
+		ScannerVersion: &protocol.ScannerVersion{Version: "v1"},
+		Signer:         signer,
+		MaxAlerts:      maxAlerts,
+		MaxSize:        maxSize,
+	})
+}
+
+func newTestBuilder(t *testing.T, maxAlerts, maxSize int) *BatchBuilder {
+	return newTestBuilderWithSigner(newTestSigner(t), maxAlerts, maxSize)
+}
+
+// limitedSigner fails after signing the given number of hashes.
+type limitedSigner struct {
+	security.Signer
+	left int
+}
+
+func (ls *limitedSigner) SignHash(ctx context.Context, hash []byte) ([]byte, error) {
+	if ls.left == 0 {
+		return nil, errors.New("signer failed")
+	}
+	ls.left--
+	return ls.Signer.SignHash(ctx, hash)
+}
+
+func testTxEvent(blockNumber, txHash string) *protocol.TransactionEvent {
+	return &protocol.TransactionEvent{
+		Block:       &protocol.TransactionEvent_EthBlock{BlockNumber: blockNumber, BlockHash: "0xblock" + blockNumber},
+		Transaction: &protocol.TransactionEvent_EthTransaction{Hash: txHash},
+		Network:     &protocol.TransactionEvent_Network{ChainId: "0x1"},
+	}
+}
+
+func testBlockEvent(blockNumber string) *protocol.BlockEvent {
+	return &protocol.BlockEvent{
+		BlockNumber: blockNumber,
+		BlockHash:   "0xblock" + blockNumber,
+		Network:     &protocol.BlockEvent_Network{ChainId: "0x1"},
+	}
+}
+
+func testFindings(severities ...protocol.Finding_Severity) []*protocol.Finding {
+	var findings []*protocol.Finding
+	for _, severity := range severities {
+		findings = append(findings, &protocol.Finding{AlertId: "ALERT", Severity: severity})
+	}
+	return findings
+}
+
+func requireValidBatch(t *testing.T, sb *SignedBatch) {
+	r := require.New(t)
+
+	r.NoError(security.VerifySignature([]byte(sb.Payload.Encoded), sb.Payload.Signature.Signer, sb.Payload.Signature.Signature))
+	var decoded protocol.AlertBatch
+	r.NoError(encoding.DecodeProto(sb.Payload.Encoded, &decoded))
+	r.True(proto.Equal(sb.Batch, &decoded))
+}
+
+func TestBatchBuilder_Grouping(t *testing.T) {
+	r := require.New(t)
+
+	bb := newTestBuilder(t, 0, 0)
+	r.NoError(bb.AddTxResponse(testAgent1, testTxEvent("0x2", "0xtx1"), &protocol.EvaluateTxResponse{
+		Findings: testFindings(protocol.Finding_LOW, protocol.Finding_HIGH),
+	}))
+	r.NoError(bb.AddTxResponse(testAgent2, testTxEvent("0x2", "0xtx1"), &protocol.EvaluateTxResponse{
+		Findings: testFindings(protocol.Finding_INFO),
+	}))
+	r.NoError(bb.AddTxResponse(testAgent2, testTxEvent("0x3", "0xtx2"), &protocol.EvaluateTxResponse{}))
+	r.NoError(bb.AddBlockResponse(testAgent1, testBlockEvent("0x3"), &protocol.EvaluateBlockResponse{
+		Findings: testFindings(protocol.Finding_MEDIUM),
+	}))
+	r.NoError(bb.AddTxResponse(testAgent1, testTxEvent("0x3", "0xtx2"), &protocol.EvaluateTxResponse{
+		Findings: testFindings(protocol.Finding_CRITICAL),
+		Private:  true,
+	}))
+	alertEvent := &protocol.AlertEvent{Alert: &protocol.AlertEvent_Alert{Hash: "0xalert"}}
+	r.NoError(bb.AddAlertResponse(testAgent2, alertEvent, &protocol.EvaluateAlertResponse{
+		Findings: testFindings(protocol.Finding_LOW),
+	}))
+	bb.AddMetrics(&protocol.AgentMetrics{AgentId: testAgent1.Id})
+
+	r.Empty(bb.Ready())
+	batches, err := bb.Flush()
+	r.NoError(err)
+	r.Len(batches, 1)
+	requireValidBatch(t, batches[0])
+
+	batch := batches[0].Batch
+	r.Equal(uint64(1), batch.ChainId)
+	r.Equal(uint64(2), batch.BlockStart)
+	r.Equal(uint64(3), batch.BlockEnd)
+	r.Equal(uint32(6), batch.AlertCount)
+	r.Equal(protocol.Finding_CRITICAL, batch.MaxSeverity)
+	r.Len(batch.Metrics, 1)
+
+	r.Len(batch.Results, 2)
+	r.Equal(uint64(2), batch.Results[0].Block.BlockNumber)
+	r.Len(batch.Results[0].Transactions, 1)
+	txResults := batch.Results[0].Transactions[0]
+	r.Equal("0xtx1", txResults.Transaction.Transaction.Hash)
+	r.Len(txResults.Results, 2)
+	r.Equal("manifest1", txResults.Results[0].AgentManifest)
+	r.Len(txResults.Results[0].Alerts, 2)
+	r.Len(txResults.Results[1].Alerts, 1)
+	alert := txResults.Results[0].Alerts[0]
+	r.NoError(security.VerifyAlertSignature(alert))
+	r.Equal(protocol.AlertType_TRANSACTION, alert.Alert.Type)
+	r.Equal("0x1", alert.ChainId)
+	r.Equal("0x2", alert.BlockNumber)
+	r.NotEmpty(alert.Alert.Id)
+
+	r.Equal(uint64(3), batch.Results[1].Block.BlockNumber)
+	r.Empty(batch.Results[1].Transactions)
+	r.Len(batch.Results[1].Results, 1)
+	r.Equal(protocol.AlertType_BLOCK, batch.Results[1].Results[0].Alerts[0].Alert.Type)
+
+	r.Len(batch.PrivateAlerts, 1)
+	r.Equal("manifest1", batch.PrivateAlerts[0].AgentManifest)
+	r.Equal(protocol.AlertType_PRIVATE, batch.PrivateAlerts[0].Alerts[0].Alert.Type)
+
+	r.Len(batch.CombinationAlerts, 1)
+	r.Equal("0xalert", batch.CombinationAlerts[0].AlertEvent.Alert.Hash)
+	r.Equal(protocol.AlertType_COMBINATION, batch.CombinationAlerts[0].Results[0].Alerts[0].Alert.Type)
+
+	r.Len(batch.Agents, 2)
+	r.Equal([]string{"0xtx1", "0xtx2"}, batch.Agents[0].Transactions)
+	r.Equal([]uint64{3}, batch.Agents[0].Blocks)
+	r.Equal([]string{"0xtx1", "0xtx2"}, batch.Agents[1].Transactions)
+	r.Equal([]string{"0xalert"}, batch.Agents[1].Combinations)
+
+	summary := batches[0].Summary("ref", "prev")
+	r.Equal("ref", summary.Batch)
+	r.Equal("prev", summary.PreviousReceipt)
+	r.Equal(batch.BlockStart, summary.BlockStart)
+	r.Equal(batch.BlockEnd, summary.BlockEnd)
+	r.Equal(batch.AlertCount, summary.AlertCount)
+	r.Equal(batch.ScannerVersion, summary.ScannerVersion)
+
+	batches, err = bb.Flush()
+	r.NoError(err)
+	r.Empty(batches)
+}
+
+func TestBatchBuilder_SplitByAlertCount(t *testing.T) {
+	r := require.New(t)
+
+	bb := newTestBuilder(t, 2, 0)
+	r.NoError(bb.AddTxResponse(testAgent1, testTxEvent("0x1", "0xtx1"), &protocol.EvaluateTxResponse{
+		Findings: testFindings(protocol.Finding_LOW, protocol.Finding_LOW, protocol.Finding_HIGH),
+	}))
+	r.NoError(bb.AddTxResponse(testAgent1, testTxEvent("0x2", "0xtx2"), &protocol.EvaluateTxResponse{
+		Findings: testFindings(protocol.Finding_LOW, protocol.Finding_LOW),
+	}))
+
+	ready := bb.Ready()
+	r.Len(ready, 2)
+	r.Empty(bb.Ready())
+	for _, sb := range ready {
+		requireValidBatch(t, sb)
+		r.Equal(uint32(2), sb.Batch.AlertCount)
+	}
+	r.Equal(protocol.Finding_LOW, ready[0].Batch.MaxSeverity)
+	r.Equal([]string{"0xtx1"}, ready[0].Batch.Agents[0].Transactions)
+	r.Equal(protocol.Finding_HIGH, ready[1].Batch.MaxSeverity)
+	r.Equal(uint64(1), ready[1].Batch.BlockStart)
+	r.Equal(uint64(2), ready[1].Batch.BlockEnd)
+	r.Equal([]string{"0xtx1", "0xtx2"}, ready[1].Batch.Agents[0].Transactions)
+
+	batches, err := bb.Flush()
+	r.NoError(err)
+	r.Len(batches, 1)
+	r.Equal(uint32(1), batches[0].Batch.AlertCount)
+	r.Equal(uint64(2), batches[0].Batch.BlockStart)
+}
+
+func TestBatchBuilder_SplitBySize(t *testing.T) {
+	r := require.New(t)
+
+	bb := newTestBuilder(t, 0, 1)
+	for i := 0; i < 3; i++ {
+		r.NoError(bb.AddBlockResponse(testAgent1, testBlockEvent("0x1"), &protocol.EvaluateBlockResponse{
+			Findings: testFindings(protocol.Finding_INFO),
+		}))
+	}
+	batches, err := bb.Flush()
+	r.NoError(err)
+	r.Len(batches, 3)
+	for _, sb := range batches {
+		r.Equal(uint32(1), sb.Batch.AlertCount)
+	}
+}
+
+func TestBatchBuilder_BlockZero(t *testing.T) {
+	r := require.New(t)
+
+	bb := newTestBuilder(t, 0, 0)
+	r.NoError(bb.AddBlockResponse(testAgent1, testBlockEvent("0x0"), &protocol.EvaluateBlockResponse{
+		Findings: testFindings(protocol.Finding_INFO),
+	}))
+	r.NoError(bb.AddTxResponse(testAgent1, testTxEvent("0x2", "0xtx1"), &protocol.EvaluateTxResponse{}))
+
+	batches, err := bb.Flush()
+	r.NoError(err)
+	r.Len(batches, 1)
+	r.Equal(uint64(0), batches[0].Batch.BlockStart)
+	r.Equal(uint64(2), batches[0].Batch.BlockEnd)
+}
+
+func TestBatchBuilder_SignError(t *testing.T) {
+	r := require.New(t)
+
+	signer := &limitedSigner{Signer: newTestSigner(t), left: 1}
+	bb := newTestBuilderWithSigner(signer, 0, 0)
+	err := bb.AddTxResponse(testAgent1, testTxEvent("0x1", "0xtx1"), &protocol.EvaluateTxResponse{
+		Findings: testFindings(protocol.Finding_LOW, protocol.Finding_HIGH),
+	})
+	r.Error(err)
+
+	// none of the alerts of the failed response are in the batch
+	batches, err := bb.Flush()
+	r.NoError(err)
+	r.Empty(batches)
+}
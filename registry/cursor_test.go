@@ -0,0 +1,188 @@
+package registry
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/feeds/checkpoint"
+	mock_feeds "github.com/forta-network/forta-core-go/feeds/mocks"
+)
+
+func TestListener_StartBlock(t *testing.T) {
+	r := require.New(t)
+
+	store := checkpoint.NewMemoryStore()
+	l := &listener{cfg: ListenerConfig{StartBlock: big.NewInt(5), CursorStore: store, ReplayBlocks: 2}}
+
+	// no cursor yet
+	startBlock, err := l.startBlock()
+	r.NoError(err)
+	r.Equal(int64(5), startBlock.Int64())
+
+	// resumes after the cursor with the replayed blocks
+	r.NoError(store.Save(DefaultListenerCursor, big.NewInt(100)))
+	startBlock, err = l.startBlock()
+	r.NoError(err)
+	r.Equal(int64(99), startBlock.Int64())
+
+	// never goes before the configured start block
+	l.cfg.StartBlock = big.NewInt(200)
+	startBlock, err = l.startBlock()
+	r.NoError(err)
+	r.Equal(int64(200), startBlock.Int64())
+}
+
+func TestListener_StartBlockWithOffset(t *testing.T) {
+	r := require.New(t)
+
+	store := checkpoint.NewMemoryStore()
+	l := &listener{cfg: ListenerConfig{StartBlock: big.NewInt(102), CursorStore: store, BlockOffset: 3}}
+
+	// block 100 is handled next and the configured start block handles block 99 first
+	r.NoError(store.Save(DefaultListenerCursor, big.NewInt(99)))
+	startBlock, err := l.startBlock()
+	r.NoError(err)
+	r.Equal(int64(103), startBlock.Int64())
+
+	// the configured start block handles block 101 first
+	l.cfg.StartBlock = big.NewInt(104)
+	startBlock, err = l.startBlock()
+	r.NoError(err)
+	r.Equal(int64(104), startBlock.Int64())
+
+	// the replayed blocks do not go below zero before the offset is added
+	l.cfg.StartBlock = nil
+	l.cfg.ReplayBlocks = 5
+	r.NoError(store.Save(DefaultListenerCursor, big.NewInt(1)))
+	startBlock, err = l.startBlock()
+	r.NoError(err)
+	r.Equal(int64(3), startBlock.Int64())
+}
+
+func TestListener_Listen_SavesCursor(t *testing.T) {
+	r := require.New(t)
+
+	store := checkpoint.NewMemoryStore()
+	logFeed := mock_feeds.NewMockLogFeed(gomock.NewController(t))
+	var afterBlockCount int
+	l := &listener{
+		ctx:  context.Background(),
+		cfg:  ListenerConfig{CursorStore: store, CursorName: "test"},
+		logs: logFeed,
+		handlerReg: NewHandlerRegistry(Handlers{
+			AfterBlockHandler: func(blk *domain.Block) error {
+				afterBlockCount++
+				return nil
+			},
+		}),
+	}
+
+	logFeed.EXPECT().ForEachLog(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(handler func(*domain.Block, types.Log) error, finishBlockHandler func(*domain.Block) error) error {
+			for _, number := range []string{"0xa", "0xb"} {
+				if err := finishBlockHandler(&domain.Block{Number: number}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+	r.NoError(l.Listen())
+	r.Equal(2, afterBlockCount)
+
+	cursor, err := store.Load("test")
+	r.NoError(err)
+	r.Equal(int64(11), cursor.Int64())
+}
+
+// countingClient counts the logs which are handled.
+type countingClient struct {
+	Client
+	handled int
+}
+
+func (cc *countingClient) Contracts() *Contracts {
+	cc.handled++
+	return &Contracts{}
+}
+
+func testCursorLog(blockNumber uint64, index uint) types.Log {
+	return types.Log{
+		Address:     common.HexToAddress("0x1"),
+		Topics:      []common.Hash{common.HexToHash("0x1234")},
+		BlockNumber: blockNumber,
+		Index:       index,
+	}
+}
+
+func TestListener_Listen_ResumesFromLogIndex(t *testing.T) {
+	r := require.New(t)
+
+	store := checkpoint.NewMemoryStore()
+	r.NoError(store.Save("test", big.NewInt(10)))
+	r.NoError(store.Save("test"+CursorLogIndexSuffix, big.NewInt(1)))
+
+	logFeed := mock_feeds.NewMockLogFeed(gomock.NewController(t))
+	client := &countingClient{}
+	l := &listener{
+		ctx:        context.Background(),
+		cfg:        ListenerConfig{CursorStore: store, CursorName: "test"},
+		logs:       logFeed,
+		client:     client,
+		handlerReg: NewHandlerRegistry(Handlers{}),
+	}
+	startBlock, err := l.startBlock()
+	r.NoError(err)
+	r.Equal(int64(11), startBlock.Int64())
+
+	var logIndexes []int64
+	logFeed.EXPECT().ForEachLog(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(handler func(*domain.Block, types.Log) error, finishBlockHandler func(*domain.Block) error) error {
+			blk := &domain.Block{Number: "0xb"}
+			for i := uint(0); i < 3; i++ {
+				if err := handler(blk, testCursorLog(11, i)); err != nil {
+					return err
+				}
+				logIndex, err := store.Load("test" + CursorLogIndexSuffix)
+				r.NoError(err)
+				logIndexes = append(logIndexes, logIndex.Int64())
+			}
+			return finishBlockHandler(blk)
+		},
+	)
+	r.NoError(l.Listen())
+
+	// only the log after the saved index is handled again
+	r.Equal(1, client.handled)
+	r.Equal([]int64{1, 1, 2}, logIndexes)
+
+	cursor, err := store.Load("test")
+	r.NoError(err)
+	r.Equal(int64(11), cursor.Int64())
+	logIndex, err := store.Load("test" + CursorLogIndexSuffix)
+	r.NoError(err)
+	r.Equal(int64(-1), logIndex.Int64())
+}
+
+func TestListener_StartBlock_ReplayIgnoresLogIndex(t *testing.T) {
+	r := require.New(t)
+
+	store := checkpoint.NewMemoryStore()
+	r.NoError(store.Save(DefaultListenerCursor, big.NewInt(10)))
+	r.NoError(store.Save(DefaultListenerCursor+CursorLogIndexSuffix, big.NewInt(1)))
+
+	l := &listener{cfg: ListenerConfig{CursorStore: store, ReplayBlocks: 1}}
+	startBlock, err := l.startBlock()
+	r.NoError(err)
+	r.Equal(int64(10), startBlock.Int64())
+	r.Nil(l.resumeLog)
+	// cleared before saving the next cursor
+	r.True(l.logIndexSaved)
+}
@@ -0,0 +1,428 @@
+package registry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-core-go/domain/registry/regmsg"
+	"github.com/forta-network/forta-core-go/feeds/checkpoint"
+)
+
+const (
+	queueSegmentExt = ".seg"
+	queueAckFile    = "ack.json"
+)
+
+// FileQueueConfig contains the file queue settings.
+type FileQueueConfig struct {
+	// Dir is where the segment files and the acked offset are kept.
+	Dir string
+	// SegmentSize is the max number of messages in a segment file.
+	SegmentSize int
+	// RetryDelay is how long to wait before delivering a message again after the handler fails.
+	RetryDelay time.Duration
+	// PollInterval is how often a caught up subscriber checks the dir for the messages
+	// which are published by another process.
+	PollInterval time.Duration
+}
+
+type queueAck struct {
+	Offset uint64 `json:"offset"`
+}
+
+// fileQueue is a durable queue which appends the messages to segment files as JSON lines.
+// Every segment file is named after the offset of its first message. A single process
+// should publish to a dir while the others can subscribe to it.
+type fileQueue struct {
+	cfg FileQueueConfig
+
+	segments    []uint64
+	tailBase    uint64
+	tailSize    int64
+	tailCount   int
+	active      *os.File
+	activeCount int
+	next        uint64
+	acked       uint64
+	closed      bool
+	notify      chan struct{}
+	mu          sync.Mutex
+}
+
+// NewFileQueue opens the queue in the directory. The last segment is recovered before
+// the first publish if it was not fully written.
+func NewFileQueue(cfg FileQueueConfig) (*fileQueue, error) {
+	if len(cfg.Dir) == 0 {
+		return nil, errors.New("queue dir is not set")
+	}
+	if cfg.SegmentSize <= 0 {
+		cfg.SegmentSize = defaultQueueSegmentSize
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = defaultQueueRetryDelay
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultQueuePollInterval
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create queue dir: %v", err)
+	}
+	fq := &fileQueue{
+		cfg:    cfg,
+		notify: make(chan struct{}),
+	}
+	if err := fq.refresh(); err != nil {
+		return nil, err
+	}
+	if err := fq.loadAck(); err != nil {
+		return nil, err
+	}
+	return fq, nil
+}
+
+var _ MessagePublisher = &fileQueue{}
+var _ MessageSubscriber = &fileQueue{}
+
+// refresh scans the dir for the segments and counts the complete lines in the last
+// segment so that the changes by another process are seen.
+func (fq *fileQueue) refresh() error {
+	entries, err := os.ReadDir(fq.cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read queue dir: %v", err)
+	}
+	var segments []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, queueSegmentExt) {
+			continue
+		}
+		base, err := strconv.ParseUint(strings.TrimSuffix(name, queueSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, base)
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i] < segments[j]
+	})
+	fq.segments = segments
+	if len(segments) == 0 {
+		return nil
+	}
+
+	// continue counting from the last known size of the same segment
+	base := segments[len(segments)-1]
+	if base != fq.tailBase {
+		fq.tailBase = base
+		fq.tailSize = 0
+		fq.tailCount = 0
+	}
+	count, size, err := countSegmentLines(fq.segmentPath(base), fq.tailSize)
+	if err != nil {
+		return err
+	}
+	fq.tailCount += count
+	fq.tailSize += size
+	if next := base + uint64(fq.tailCount); next > fq.next {
+		fq.next = next
+	}
+	return nil
+}
+
+// countSegmentLines counts the complete lines after the offset. A partial last line is
+// not counted since it can still be written.
+func countSegmentLines(path string, offset int64) (count int, size int64, err error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open segment: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return count, size, nil
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read segment: %v", err)
+		}
+		count++
+		size += int64(len(line))
+	}
+}
+
+// openActive opens the last segment for publishing and cuts off a partial line which
+// was left by a crash.
+func (fq *fileQueue) openActive() error {
+	if err := fq.refresh(); err != nil {
+		return err
+	}
+	if len(fq.segments) == 0 || fq.tailCount >= fq.cfg.SegmentSize {
+		return fq.newSegment()
+	}
+	f, err := os.OpenFile(fq.segmentPath(fq.tailBase), os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open segment: %v", err)
+	}
+	if err := f.Truncate(fq.tailSize); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to truncate segment: %v", err)
+	}
+	if _, err := f.Seek(fq.tailSize, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	fq.active = f
+	fq.activeCount = fq.tailCount
+	return nil
+}
+
+func (fq *fileQueue) loadAck() error {
+	b, err := os.ReadFile(filepath.Join(fq.cfg.Dir, queueAckFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read ack file: %v", err)
+	}
+	var qa queueAck
+	if err := json.Unmarshal(b, &qa); err != nil {
+		return fmt.Errorf("failed to decode ack file: %v", err)
+	}
+	if qa.Offset > fq.acked {
+		fq.acked = qa.Offset
+	}
+	return nil
+}
+
+func (fq *fileQueue) segmentPath(base uint64) string {
+	return filepath.Join(fq.cfg.Dir, fmt.Sprintf("%020d%s", base, queueSegmentExt))
+}
+
+// Publish implements the MessagePublisher interface. The message is synced to the disk
+// before returning.
+func (fq *fileQueue) Publish(ctx context.Context, logger *log.Entry, msg regmsg.Interface) error {
+	raw, err := encodeQueueMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+	if fq.closed {
+		return ErrQueueClosed
+	}
+	if fq.active == nil {
+		if err := fq.openActive(); err != nil {
+			return err
+		}
+	}
+	if fq.activeCount >= fq.cfg.SegmentSize {
+		if err := fq.newSegment(); err != nil {
+			return err
+		}
+	}
+	if _, err := fq.active.WriteString(raw + "\n"); err != nil {
+		return fmt.Errorf("failed to write message: %v", err)
+	}
+	if err := fq.active.Sync(); err != nil {
+		return fmt.Errorf("failed to sync segment: %v", err)
+	}
+	fq.activeCount++
+	fq.next++
+	fq.broadcast()
+	return nil
+}
+
+func (fq *fileQueue) newSegment() error {
+	if fq.active != nil {
+		if err := fq.active.Close(); err != nil {
+			return fmt.Errorf("failed to close segment: %v", err)
+		}
+		fq.active = nil
+	}
+	f, err := os.OpenFile(fq.segmentPath(fq.next), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create segment: %v", err)
+	}
+	fq.active = f
+	fq.activeCount = 0
+	fq.segments = append(fq.segments, fq.next)
+	return nil
+}
+
+// Subscribe implements the MessageSubscriber interface by delivering the messages
+// after the last acked one.
+func (fq *fileQueue) Subscribe(ctx context.Context, handler regmsg.HandlerFunc[regmsg.Interface]) error {
+	return fq.SubscribeFrom(ctx, fq.Acked(), handler)
+}
+
+// SubscribeFrom delivers the messages starting from the offset.
+func (fq *fileQueue) SubscribeFrom(ctx context.Context, offset uint64, handler regmsg.HandlerFunc[regmsg.Interface]) error {
+	return subscribe(ctx, fq, offset, fq.cfg.RetryDelay, handler)
+}
+
+// Acked returns the offset of the first message which is not acked.
+func (fq *fileQueue) Acked() uint64 {
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+	return fq.acked
+}
+
+// Compact removes the segment files which contain only the acked messages.
+func (fq *fileQueue) Compact() error {
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+	// the segments and the acked offset can be changed by another process
+	if err := fq.refresh(); err != nil {
+		return err
+	}
+	if err := fq.loadAck(); err != nil {
+		return err
+	}
+	// never remove the last segment
+	for len(fq.segments) > 1 && fq.segments[1] <= fq.acked {
+		if err := os.Remove(fq.segmentPath(fq.segments[0])); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove segment: %v", err)
+		}
+		fq.segments = fq.segments[1:]
+	}
+	return nil
+}
+
+// Close stops the subscriptions and closes the active segment.
+func (fq *fileQueue) Close() error {
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+	if fq.closed {
+		return nil
+	}
+	fq.closed = true
+	fq.broadcast()
+	if fq.active != nil {
+		return fq.active.Close()
+	}
+	return nil
+}
+
+func (fq *fileQueue) read(ctx context.Context, offset uint64) ([]string, error) {
+	fq.mu.Lock()
+	for {
+		if fq.closed {
+			fq.mu.Unlock()
+			return nil, ErrQueueClosed
+		}
+		if offset >= fq.next {
+			// another process can be publishing to the dir
+			if err := fq.refresh(); err != nil {
+				fq.mu.Unlock()
+				return nil, err
+			}
+		}
+		if offset > fq.next {
+			fq.mu.Unlock()
+			return nil, fmt.Errorf("%w: %d", ErrOffsetNotPublished, offset)
+		}
+		if offset < fq.next {
+			break
+		}
+		if err := fq.wait(ctx); err != nil {
+			fq.mu.Unlock()
+			return nil, err
+		}
+	}
+	i := sort.Search(len(fq.segments), func(i int) bool {
+		return fq.segments[i] > offset
+	}) - 1
+	if i < 0 {
+		fq.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d", ErrOffsetNotRetained, offset)
+	}
+	base := fq.segments[i]
+	end := fq.next
+	if i+1 < len(fq.segments) {
+		end = fq.segments[i+1]
+	}
+	fq.mu.Unlock()
+
+	// only the lines before the end are complete so the file can be read while publishing
+	f, err := os.Open(fq.segmentPath(base))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %d", ErrOffsetNotRetained, offset)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open segment: %v", err)
+	}
+	defer f.Close()
+	var msgs []string
+	r := bufio.NewReader(f)
+	for current := base; current < end; current++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read segment at offset %d: %v", current, err)
+		}
+		if current >= offset {
+			msgs = append(msgs, strings.TrimSuffix(line, "\n"))
+		}
+	}
+	return msgs, nil
+}
+
+func (fq *fileQueue) ack(offset uint64) error {
+	fq.mu.Lock()
+	defer fq.mu.Unlock()
+	if offset <= fq.acked {
+		return nil
+	}
+	b, err := json.Marshal(&queueAck{Offset: offset})
+	if err != nil {
+		return err
+	}
+	if err := checkpoint.WriteFileAtomic(filepath.Join(fq.cfg.Dir, queueAckFile), b); err != nil {
+		return err
+	}
+	fq.acked = offset
+	return nil
+}
+
+// wait releases the lock until there is a change in the queue or until it is time to
+// check the dir again.
+func (fq *fileQueue) wait(ctx context.Context) error {
+	notify := fq.notify
+	fq.mu.Unlock()
+	defer fq.mu.Lock()
+	timer := time.NewTimer(fq.cfg.PollInterval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-notify:
+		return nil
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (fq *fileQueue) broadcast() {
+	close(fq.notify)
+	fq.notify = make(chan struct{})
+}
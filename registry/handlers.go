@@ -14,6 +14,9 @@ import (
 // Handlers helps declaring all handlers in the order they are intended to be executed.
 type Handlers struct {
 	AfterBlockHandler func(blk *domain.Block) error
+	// ConfirmedBlockHandler is called after all messages of the block are delivered to the handlers,
+	// which can be later than AfterBlockHandler if the listener holds the messages for confirmations.
+	ConfirmedBlockHandler func(blk *domain.Block) error
 
 	// implementation upgrades
 	UpgradeHandlers regmsg.HandlerFuncs[*registry.UpgradeMessage]
@@ -46,11 +49,15 @@ type Handlers struct {
 
 	// payments
 	UpdatePaymentSubscriptionMessageHandlers regmsg.HandlerFuncs[*registry.UpdatePaymentSubscriptionMessage]
+
+	// reorgs
+	RetractionHandlers regmsg.HandlerFuncs[*registry.RetractionMessage]
 }
 
 type HandlerRegistry struct {
-	afterBlockHandler func(blk *domain.Block) error
-	all               slicemap.SliceMap[string, []reflect.Value]
+	afterBlockHandler     func(blk *domain.Block) error
+	confirmedBlockHandler func(blk *domain.Block) error
+	all                   slicemap.SliceMap[string, []reflect.Value]
 }
 
 // Handle finds the right handler for the message and calls it.
@@ -81,6 +88,7 @@ func NewHandlerRegistry(h Handlers) *HandlerRegistry {
 
 	// set handlers
 	reg.afterBlockHandler = h.AfterBlockHandler
+	reg.confirmedBlockHandler = h.ConfirmedBlockHandler
 	handlers := reflect.ValueOf(h)
 	for i := 0; i < handlers.NumField(); i++ {
 		field := handlers.Field(i)
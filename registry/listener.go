@@ -5,12 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
-	"sync"
+	"sort"
+	"time"
 
 	"github.com/forta-network/forta-core-go/contracts/generated/contract_rewards_distributor_0_1_0"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/types"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
@@ -27,6 +27,7 @@ import (
 	"github.com/forta-network/forta-core-go/domain/registry/regmsg"
 	"github.com/forta-network/forta-core-go/ethereum"
 	"github.com/forta-network/forta-core-go/feeds"
+	"github.com/forta-network/forta-core-go/feeds/checkpoint"
 	"github.com/forta-network/forta-core-go/utils"
 )
 
@@ -40,6 +41,17 @@ type listener struct {
 	publisher  MessagePublisher
 	handlerReg *HandlerRegistry
 	handler    regmsg.HandlerFunc[regmsg.Interface]
+	reorgGuard *reorgGuard
+
+	// the logs which were handled before the restart, in the block after the cursor
+	resumeLog *logPosition
+	// whether a log index needs to be cleared before saving the next cursor
+	logIndexSaved bool
+}
+
+type logPosition struct {
+	block uint64
+	index uint
 }
 
 // MessagePublisher sends messages to a remote consumer.
@@ -75,8 +87,43 @@ type ListenerConfig struct {
 	Topics         []string
 	Publisher      MessagePublisher
 	NoRefresh      bool
+	// CursorStore saves the last fully handled block as the cursor while listening,
+	// so that the listener resumes from the block after the cursor on start. The index of
+	// the last handled log in the block after the cursor is saved as well, so that the logs
+	// at or below it are skipped when resuming.
+	CursorStore checkpoint.Store
+	// CursorName is the name of the saved cursor. The default is DefaultListenerCursor.
+	// The log index is saved with the CursorLogIndexSuffix after the name.
+	CursorName string
+	// ReplayBlocks is the number of blocks before the cursor to handle again when resuming.
+	// All logs of the replayed blocks and the block after the cursor are handled again.
+	ReplayBlocks int
+	// Confirmations is the number of blocks to see after a block before delivering the messages
+	// from its logs while listening. The messages of the orphaned blocks are never delivered.
+	Confirmations int
+	// RetractOrphaned makes the listener deliver a RetractionMessage for every delivered message
+	// of a log which is removed or is in an orphaned block. Together with zero confirmations,
+	// the messages are delivered optimistically and retracted later if needed.
+	RetractOrphaned bool
+	// ReorgDepth is the number of recent blocks which are checked for reorgs when the confirmations
+	// or the retractions are enabled. The default is 64.
+	ReorgDepth int
+	// BackfillPageSize is the number of blocks in a page of ProcessBlockRange. The default is 2000.
+	BackfillPageSize int
+	// BackfillWorkers is the number of pages which ProcessBlockRange fetches concurrently.
+	// The default is 25.
+	BackfillWorkers int
+	// BackfillProgress is called after ProcessBlockRange handles every page.
+	BackfillProgress func(progress BackfillProgress)
 }
 
+// DefaultListenerCursor is the default cursor name of the listener.
+const DefaultListenerCursor = "registry-listener"
+
+// CursorLogIndexSuffix is added to the cursor name to save the index of the last handled log.
+// The index is -1 if no log of the block after the cursor was handled.
+const CursorLogIndexSuffix = "-log-index"
+
 type Listener interface {
 	Listen() error
 	ProcessLastBlocks(blocksAgo int64) error
@@ -456,15 +503,53 @@ func (l *listener) handleAfterBlock(blk *domain.Block) error {
 	return nil
 }
 
+func (l *listener) handleConfirmedBlock(blk *domain.Block) error {
+	if l.ctx.Err() != nil {
+		return l.ctx.Err()
+	}
+	if l.handlerReg.confirmedBlockHandler != nil {
+		return l.handlerReg.confirmedBlockHandler(blk)
+	}
+	return nil
+}
+
 type page struct {
 	Start int64
 	End   int64
 }
 
-// ProcessBlockRange pages over a range of blocks, 10k blocks per page
+const (
+	defaultBackfillPageSize = 2000
+	defaultBackfillWorkers  = 25
+)
+
+// BackfillProgress is the progress of ProcessBlockRange.
+type BackfillProgress struct {
+	PagesDone  int
+	PagesTotal int
+	// LastBlock is the last block which had all of its logs handled.
+	LastBlock int64
+	Elapsed   time.Duration
+	ETA       time.Duration
+}
+
+type pageResult struct {
+	logs   []types.Log
+	blocks map[uint64]*domain.Block
+}
+
+type pageJob struct {
+	page   page
+	result chan *pageResult
+}
+
+// ProcessBlockRange pages over a range of blocks. The pages are fetched concurrently but
+// the logs are handled in the block and log index order.
 func (l *listener) ProcessBlockRange(startBlock *big.Int, endBlock *big.Int) error {
-	start := startBlock
-	pageSize := big.NewInt(2000)
+	return l.processBlockRange(startBlock, endBlock, l.handleLog)
+}
+
+func (l *listener) processBlockRange(startBlock *big.Int, endBlock *big.Int, handleLog func(*domain.Block, types.Log) error) error {
 	if endBlock == nil {
 		bn, err := l.eth.BlockNumber(context.Background())
 		if err != nil {
@@ -472,55 +557,104 @@ func (l *listener) ProcessBlockRange(startBlock *big.Int, endBlock *big.Int) err
 		}
 		endBlock = bn
 	}
-	end := math.BigMin(big.NewInt(0).Add(start, pageSize), endBlock)
-	pages := make(chan page)
+	if startBlock.Cmp(endBlock) > 0 {
+		return fmt.Errorf("start block %s is after end block %s", startBlock, endBlock)
+	}
+
+	pageSize := int64(l.cfg.BackfillPageSize)
+	if pageSize <= 0 {
+		pageSize = defaultBackfillPageSize
+	}
+	workers := l.cfg.BackfillWorkers
+	if workers <= 0 {
+		workers = defaultBackfillWorkers
+	}
+	var pages []page
+	for start := startBlock.Int64(); start <= endBlock.Int64(); start += pageSize {
+		end := start + pageSize - 1
+		if end > endBlock.Int64() {
+			end = endBlock.Int64()
+		}
+		pages = append(pages, page{Start: start, End: end})
+	}
+
 	grp, ctx := errgroup.WithContext(l.ctx)
-	mux := sync.Mutex{}
+	jobs := make(chan *pageJob)
+	// limits how far the workers can get ahead of the handled page
+	ordered := make(chan *pageJob, workers)
 
-	for i := 0; i < 25; i++ {
+	for i := 0; i < workers; i++ {
 		grp.Go(func() error {
-			for p := range pages {
+			for job := range jobs {
 				if ctx.Err() != nil {
 					return ctx.Err()
 				}
-				logs, err := l.logs.GetLogsForRange(big.NewInt(p.Start), big.NewInt(p.End))
+				logs, err := l.logs.GetLogsForRange(big.NewInt(job.page.Start), big.NewInt(job.page.End))
 				if err != nil {
 					return err
 				}
-				var block *domain.Block
-				for _, lg := range logs {
-					if block == nil || block.Number != utils.BigIntToHex(big.NewInt(int64(lg.BlockNumber))) {
-						blk, err := l.eth.BlockByNumber(l.ctx, big.NewInt(int64(lg.BlockNumber)))
-						if err != nil {
-							return err
-						}
-						block = blk
-					}
-					// avoids concurrent handleLogs
-					mux.Lock()
-					err := l.handleLog(block, lg)
-					mux.Unlock()
-					if err != nil {
-						return err
-					}
+				blocks, err := l.blocksForLogs(logs)
+				if err != nil {
+					return err
 				}
+				job.result <- &pageResult{logs: logs, blocks: blocks}
 			}
 			return nil
 		})
 	}
 
 	grp.Go(func() error {
-		defer close(pages)
-		for end.Cmp(endBlock) <= 0 {
-			pages <- page{
-				Start: start.Int64(),
-				End:   end.Int64(),
+		defer close(jobs)
+		defer close(ordered)
+		for _, p := range pages {
+			job := &pageJob{page: p, result: make(chan *pageResult, 1)}
+			select {
+			case ordered <- job:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+
+	grp.Go(func() error {
+		startTime := time.Now()
+		var done int
+		for job := range ordered {
+			var res *pageResult
+			select {
+			case res = <-job.result:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			sort.SliceStable(res.logs, func(i, j int) bool {
+				if res.logs[i].BlockNumber != res.logs[j].BlockNumber {
+					return res.logs[i].BlockNumber < res.logs[j].BlockNumber
+				}
+				return res.logs[i].Index < res.logs[j].Index
+			})
+			for _, lg := range res.logs {
+				if err := handleLog(res.blocks[lg.BlockNumber], lg); err != nil {
+					return err
+				}
 			}
-			if end.Cmp(endBlock) == 0 {
-				return nil
+
+			done++
+			if l.cfg.BackfillProgress != nil {
+				elapsed := time.Since(startTime)
+				l.cfg.BackfillProgress(BackfillProgress{
+					PagesDone:  done,
+					PagesTotal: len(pages),
+					LastBlock:  job.page.End,
+					Elapsed:    elapsed,
+					ETA:        elapsed / time.Duration(done) * time.Duration(len(pages)-done),
+				})
 			}
-			start = big.NewInt(0).Add(end, big.NewInt(1))
-			end = math.BigMin(big.NewInt(0).Add(start, pageSize), endBlock)
 		}
 		return nil
 	})
@@ -528,6 +662,32 @@ func (l *listener) ProcessBlockRange(startBlock *big.Int, endBlock *big.Int) err
 	return grp.Wait()
 }
 
+// blocksForLogs gets the blocks of the logs in batch requests.
+func (l *listener) blocksForLogs(logs []types.Log) (map[uint64]*domain.Block, error) {
+	var numbers []*big.Int
+	seen := make(map[uint64]bool)
+	for _, lg := range logs {
+		if seen[lg.BlockNumber] {
+			continue
+		}
+		seen[lg.BlockNumber] = true
+		numbers = append(numbers, big.NewInt(0).SetUint64(lg.BlockNumber))
+	}
+	if len(numbers) == 0 {
+		return nil, nil
+	}
+
+	results, errs := l.eth.BlocksByNumbers(l.ctx, numbers)
+	blocks := make(map[uint64]*domain.Block, len(numbers))
+	for i, number := range numbers {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("failed to get block %s: %v", number, errs[i])
+		}
+		blocks[number.Uint64()] = results[i]
+	}
+	return blocks, nil
+}
+
 // ProcessLastBlocks fetches the logs in a single pass and calls handlers for them
 func (l *listener) ProcessLastBlocks(blocksAgo int64) error {
 	bn, err := l.eth.BlockNumber(context.Background())
@@ -537,13 +697,120 @@ func (l *listener) ProcessLastBlocks(blocksAgo int64) error {
 	if bn.Int64() == 0 {
 		return errors.New("current block is unexpectedly 0")
 	}
-	start := bn
-	end := big.NewInt(bn.Int64() - blocksAgo)
-	return l.ProcessBlockRange(start, end)
+	start := big.NewInt(bn.Int64() - blocksAgo)
+	return l.ProcessBlockRange(start, bn)
 }
 
 func (l *listener) Listen() error {
-	return l.logs.ForEachLog(l.handleLog, l.handleAfterBlock)
+	if l.reorgGuard != nil {
+		return l.listenReorgSafe()
+	}
+	return l.logs.ForEachLog(l.handleCursorLog, func(blk *domain.Block) error {
+		if err := l.handleAfterBlock(blk); err != nil {
+			return err
+		}
+		if err := l.handleConfirmedBlock(blk); err != nil {
+			return err
+		}
+		l.saveCursor(blk)
+		return nil
+	})
+}
+
+// handleCursorLog skips the logs which were handled before the restart and saves the index
+// of every handled log.
+func (l *listener) handleCursorLog(blk *domain.Block, le types.Log) error {
+	if rl := l.resumeLog; rl != nil && le.BlockNumber >= rl.block {
+		if le.BlockNumber == rl.block && le.Index <= rl.index {
+			getLoggerForLog(le).Info("skipping tx log handled before restart")
+			return nil
+		}
+		l.resumeLog = nil
+	}
+	if err := l.handleLog(blk, le); err != nil {
+		return err
+	}
+	l.saveLogIndex(big.NewInt(int64(le.Index)))
+	return nil
+}
+
+func (l *listener) saveLogIndex(index *big.Int) bool {
+	if l.cfg.CursorStore == nil {
+		return true
+	}
+	if err := l.cfg.CursorStore.Save(l.cursorName()+CursorLogIndexSuffix, index); err != nil {
+		log.WithField("cursor", l.cursorName()).WithError(err).Error("failed to save cursor log index")
+		return false
+	}
+	l.logIndexSaved = index.Sign() >= 0
+	return true
+}
+
+func (l *listener) saveCursor(blk *domain.Block) {
+	if l.cfg.CursorStore == nil {
+		return
+	}
+	logger := log.WithField("cursor", l.cursorName()).WithField("block", blk.Number)
+	blockNum, err := utils.HexToBigInt(blk.Number)
+	if err != nil {
+		logger.WithError(err).Error("failed to decode block number for cursor")
+		return
+	}
+	// the log index is cleared first so that it is never applied to the wrong block after
+	// a crash between the saves: the block is handled again instead
+	if l.logIndexSaved && !l.saveLogIndex(big.NewInt(-1)) {
+		return
+	}
+	if err := l.cfg.CursorStore.Save(l.cursorName(), blockNum); err != nil {
+		logger.WithError(err).Error("failed to save cursor")
+	}
+}
+
+func (l *listener) cursorName() string {
+	if l.cfg.CursorName != "" {
+		return l.cfg.CursorName
+	}
+	return DefaultListenerCursor
+}
+
+// startBlock returns the block to start listening from: the block after the saved cursor
+// minus the replayed blocks, unless the configured start block is later. The log feed handles
+// the start block minus the offset, so the blocks are compared before adding the offset.
+// The logs of the resumed block which were handled before are skipped if no blocks are replayed.
+func (l *listener) startBlock() (*big.Int, error) {
+	if l.cfg.CursorStore == nil {
+		return l.cfg.StartBlock, nil
+	}
+	cursor, err := l.cfg.CursorStore.Load(l.cursorName())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cursor: %v", err)
+	}
+	if cursor == nil {
+		return l.cfg.StartBlock, nil
+	}
+	offset := big.NewInt(int64(l.cfg.BlockOffset))
+	next := new(big.Int).Add(cursor, big.NewInt(int64(1-l.cfg.ReplayBlocks)))
+	if next.Sign() < 0 {
+		next.SetInt64(0)
+	}
+	logIndex, err := l.cfg.CursorStore.Load(l.cursorName() + CursorLogIndexSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cursor log index: %v", err)
+	}
+	l.logIndexSaved = logIndex != nil && logIndex.Sign() >= 0
+	if l.cfg.StartBlock != nil && next.Cmp(new(big.Int).Sub(l.cfg.StartBlock, offset)) < 0 {
+		return l.cfg.StartBlock, nil
+	}
+	if l.logIndexSaved && l.cfg.ReplayBlocks == 0 && l.reorgGuard == nil {
+		l.resumeLog = &logPosition{block: next.Uint64(), index: uint(logIndex.Uint64())}
+	}
+	log.WithFields(log.Fields{
+		"cursor":         l.cursorName(),
+		"cursorBlock":    cursor.String(),
+		"cursorLogIndex": logIndex,
+		"replayBlocks":   l.cfg.ReplayBlocks,
+	}).Infof("resuming listener from block %s", next)
+	return next.Add(next, offset), nil
 }
 
 func NewDefaultListener(ctx context.Context, handlers Handlers) (*listener, error) {
@@ -629,6 +896,9 @@ func (l *listener) setLogFilterAddrs() {
 }
 
 func NewListenerWithClients(ctx context.Context, cfg ListenerConfig, ethClient ethereum.Client, regClient Client, publisher MessagePublisher) (*listener, error) {
+	if cfg.Confirmations < 0 {
+		return nil, fmt.Errorf("confirmations cannot be below zero: confirmations=%d", cfg.Confirmations)
+	}
 	li := &listener{
 		ctx:        ctx,
 		client:     regClient,
@@ -644,16 +914,24 @@ func NewListenerWithClients(ctx context.Context, cfg ListenerConfig, ethClient e
 	} else {
 		li.handler = li.handlerReg.Handle
 	}
+	// hold or retract the messages by tracking the recent blocks
+	if cfg.Confirmations > 0 || cfg.RetractOrphaned {
+		li.reorgGuard = newReorgGuard(ctx, cfg, li.handler)
+		li.handler = li.reorgGuard.handle
+	}
 
 	var topics [][]string
 	if len(cfg.Topics) > 0 {
 		topics = [][]string{cfg.Topics}
 	}
 
-	var err error
+	startBlock, err := li.startBlock()
+	if err != nil {
+		return nil, err
+	}
 	li.logs, err = feeds.NewLogFeed(ctx, ethClient, feeds.LogFeedConfig{
 		Topics:     topics,
-		StartBlock: cfg.StartBlock,
+		StartBlock: startBlock,
 		EndBlock:   cfg.EndBlock,
 		Offset:     cfg.BlockOffset,
 	})
@@ -3,10 +3,16 @@ package registry
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
 	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/golang/mock/gomock"
 
 	"github.com/forta-network/forta-core-go/contracts/generated/contract_forta_staking_0_1_2"
 	"github.com/forta-network/forta-core-go/contracts/generated/contract_rewards_distributor_0_1_0"
@@ -17,8 +23,11 @@ import (
 	"github.com/forta-network/forta-core-go/contracts/generated/contract_scanner_node_version_0_1_0"
 	"github.com/forta-network/forta-core-go/contracts/generated/contract_scanner_pool_registry_0_1_0"
 
+	"github.com/forta-network/forta-core-go/domain"
 	"github.com/forta-network/forta-core-go/domain/registry"
 	"github.com/forta-network/forta-core-go/domain/registry/regmsg"
+	mock_ethereum "github.com/forta-network/forta-core-go/ethereum/mocks"
+	mock_feeds "github.com/forta-network/forta-core-go/feeds/mocks"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -392,3 +401,65 @@ func TestListener_Listen(t *testing.T) {
 		})
 	}
 }
+
+func TestListener_ProcessBlockRange_Ordered(t *testing.T) {
+	r := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	ethClient := mock_ethereum.NewMockClient(ctrl)
+	logFeed := mock_feeds.NewMockLogFeed(ctrl)
+
+	var progress []BackfillProgress
+	l := &listener{
+		ctx:  context.Background(),
+		eth:  ethClient,
+		logs: logFeed,
+		cfg: ListenerConfig{
+			BackfillPageSize: 10,
+			BackfillWorkers:  3,
+			BackfillProgress: func(p BackfillProgress) {
+				progress = append(progress, p)
+			},
+		},
+	}
+
+	// the earlier pages take longer and the logs are not sorted in the pages
+	logFeed.EXPECT().GetLogsForRange(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(start, end *big.Int) ([]types.Log, error) {
+			time.Sleep(time.Duration(40-start.Int64()) * time.Millisecond)
+			return []types.Log{
+				{BlockNumber: end.Uint64(), Index: 1},
+				{BlockNumber: end.Uint64(), Index: 0},
+				{BlockNumber: start.Uint64(), Index: 0},
+			}, nil
+		},
+	).Times(3)
+	ethClient.EXPECT().BlocksByNumbers(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, numbers []*big.Int) ([]*domain.Block, []error) {
+			blocks := make([]*domain.Block, len(numbers))
+			for i, number := range numbers {
+				blocks[i] = &domain.Block{Number: hexutil.EncodeBig(number)}
+			}
+			return blocks, make([]error, len(numbers))
+		},
+	).Times(3)
+
+	var handled []string
+	err := l.processBlockRange(big.NewInt(1), big.NewInt(30), func(blk *domain.Block, lg types.Log) error {
+		r.Equal(hexutil.EncodeUint64(lg.BlockNumber), blk.Number)
+		handled = append(handled, fmt.Sprintf("%d-%d", lg.BlockNumber, lg.Index))
+		return nil
+	})
+	r.NoError(err)
+	r.Equal([]string{
+		"1-0", "10-0", "10-1",
+		"11-0", "20-0", "20-1",
+		"21-0", "30-0", "30-1",
+	}, handled)
+
+	r.Len(progress, 3)
+	r.Equal(3, progress[2].PagesDone)
+	r.Equal(3, progress[2].PagesTotal)
+	r.Equal(int64(30), progress[2].LastBlock)
+	r.Zero(progress[2].ETA)
+}
@@ -0,0 +1,269 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/domain/registry/regmsg"
+)
+
+// Queue errors
+var (
+	ErrQueueClosed        = errors.New("queue is closed")
+	ErrOffsetNotRetained  = errors.New("offset is no longer retained")
+	ErrOffsetNotPublished = errors.New("offset is not published yet")
+	ErrQueueFull          = errors.New("queue is full")
+	ErrAlreadySubscribed  = errors.New("queue already has a subscriber")
+)
+
+const (
+	defaultQueueRetryDelay   = time.Second
+	defaultMemoryQueueSize   = 1000
+	defaultQueueSegmentSize  = 10000
+	defaultQueuePollInterval = time.Second
+)
+
+// messageLog is the storage of a queue. The messages are kept in the publish order
+// and the offsets start from zero.
+type messageLog interface {
+	// read returns the messages starting from the offset and waits if there are none yet.
+	read(ctx context.Context, offset uint64) ([]string, error)
+	// ack records that the messages before the offset are handled.
+	ack(offset uint64) error
+}
+
+// subscribe delivers the messages from the offset until the context is done or the queue is closed.
+// A message is acked only after the handler succeeds, otherwise it is delivered again.
+func subscribe(
+	ctx context.Context, ml messageLog, offset uint64, retryDelay time.Duration,
+	handler regmsg.HandlerFunc[regmsg.Interface],
+) error {
+	for {
+		msgs, err := ml.read(ctx, offset)
+		if errors.Is(err, ErrQueueClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, raw := range msgs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			msg, err := registry.ParseMessage(raw)
+			if err != nil {
+				return fmt.Errorf("failed to parse message at offset %d: %v", offset, err)
+			}
+			logger := log.WithFields(msg.LogFields()).WithFields(log.Fields{
+				"action": msg.ActionName(),
+				"offset": offset,
+			})
+			for {
+				err := handler(ctx, logger, msg)
+				if err == nil {
+					break
+				}
+				logger.WithError(err).Warn("failed to handle message - retrying")
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(retryDelay):
+				}
+			}
+			offset++
+			if err := ml.ack(offset); err != nil {
+				return fmt.Errorf("failed to ack offset %d: %v", offset, err)
+			}
+		}
+	}
+}
+
+func encodeQueueMessage(msg regmsg.Interface) (string, error) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode message: %v", err)
+	}
+	return string(b), nil
+}
+
+// MemoryQueueConfig contains the in-memory queue settings.
+type MemoryQueueConfig struct {
+	// Size is the max number of messages which are kept. Publishing waits while there are
+	// this many messages which are not acked yet. The acked ones are kept for replay
+	// until they are pushed out by the new ones.
+	Size int
+	// RetryDelay is how long to wait before delivering a message again after the handler fails.
+	RetryDelay time.Duration
+	// PublishTimeout is how long publishing waits for an ack when the queue is full before
+	// failing with ErrQueueFull. Zero waits until the context of the publishing is done.
+	PublishTimeout time.Duration
+}
+
+// memoryQueue is a bounded in-process queue with a single consumer. There is a single acked
+// offset, so a subscription fails with ErrAlreadySubscribed while another one is active.
+type memoryQueue struct {
+	cfg MemoryQueueConfig
+
+	msgs       []string
+	first      uint64
+	acked      uint64
+	closed     bool
+	subscribed bool
+	notify     chan struct{}
+	mu         sync.Mutex
+}
+
+// NewMemoryQueue creates a new in-memory queue which implements both MessagePublisher
+// and MessageSubscriber.
+func NewMemoryQueue(cfg MemoryQueueConfig) *memoryQueue {
+	if cfg.Size <= 0 {
+		cfg.Size = defaultMemoryQueueSize
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = defaultQueueRetryDelay
+	}
+	return &memoryQueue{
+		cfg:    cfg,
+		notify: make(chan struct{}),
+	}
+}
+
+var _ MessagePublisher = &memoryQueue{}
+var _ MessageSubscriber = &memoryQueue{}
+
+// Publish implements the MessagePublisher interface.
+func (mq *memoryQueue) Publish(ctx context.Context, logger *log.Entry, msg regmsg.Interface) error {
+	raw, err := encodeQueueMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	waitCtx := ctx
+	if mq.cfg.PublishTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, mq.cfg.PublishTimeout)
+		defer cancel()
+	}
+
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	for !mq.closed && mq.next()-mq.acked >= uint64(mq.cfg.Size) {
+		if err := mq.wait(waitCtx); err != nil {
+			if ctx.Err() == nil {
+				return fmt.Errorf("%w: no ack in %s", ErrQueueFull, mq.cfg.PublishTimeout)
+			}
+			return err
+		}
+	}
+	if mq.closed {
+		return ErrQueueClosed
+	}
+	mq.msgs = append(mq.msgs, raw)
+	// the dropped ones are always acked because of the wait above
+	if len(mq.msgs) > mq.cfg.Size {
+		mq.msgs = mq.msgs[1:]
+		mq.first++
+	}
+	mq.broadcast()
+	return nil
+}
+
+// Subscribe implements the MessageSubscriber interface by delivering the messages
+// after the last acked one.
+func (mq *memoryQueue) Subscribe(ctx context.Context, handler regmsg.HandlerFunc[regmsg.Interface]) error {
+	return mq.SubscribeFrom(ctx, mq.Acked(), handler)
+}
+
+// SubscribeFrom delivers the messages starting from the offset.
+func (mq *memoryQueue) SubscribeFrom(ctx context.Context, offset uint64, handler regmsg.HandlerFunc[regmsg.Interface]) error {
+	mq.mu.Lock()
+	if mq.subscribed {
+		mq.mu.Unlock()
+		return ErrAlreadySubscribed
+	}
+	mq.subscribed = true
+	mq.mu.Unlock()
+	defer func() {
+		mq.mu.Lock()
+		mq.subscribed = false
+		mq.mu.Unlock()
+	}()
+	return subscribe(ctx, mq, offset, mq.cfg.RetryDelay, handler)
+}
+
+// Acked returns the offset of the first message which is not acked.
+func (mq *memoryQueue) Acked() uint64 {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	return mq.acked
+}
+
+// Close stops the subscriptions and makes the publishing fail.
+func (mq *memoryQueue) Close() error {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	mq.closed = true
+	mq.broadcast()
+	return nil
+}
+
+func (mq *memoryQueue) read(ctx context.Context, offset uint64) ([]string, error) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	for {
+		if mq.closed {
+			return nil, ErrQueueClosed
+		}
+		next := mq.next()
+		switch {
+		case offset < mq.first:
+			return nil, fmt.Errorf("%w: %d", ErrOffsetNotRetained, offset)
+		case offset > next:
+			return nil, fmt.Errorf("%w: %d", ErrOffsetNotPublished, offset)
+		case offset < next:
+			return append([]string(nil), mq.msgs[offset-mq.first:]...), nil
+		}
+		if err := mq.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (mq *memoryQueue) ack(offset uint64) error {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	if offset > mq.acked {
+		mq.acked = offset
+		mq.broadcast()
+	}
+	return nil
+}
+
+func (mq *memoryQueue) next() uint64 {
+	return mq.first + uint64(len(mq.msgs))
+}
+
+// wait releases the lock until there is a change in the queue.
+func (mq *memoryQueue) wait(ctx context.Context) error {
+	notify := mq.notify
+	mq.mu.Unlock()
+	defer mq.mu.Lock()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-notify:
+		return nil
+	}
+}
+
+func (mq *memoryQueue) broadcast() {
+	close(mq.notify)
+	mq.notify = make(chan struct{})
+}
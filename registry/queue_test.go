@@ -0,0 +1,235 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/domain/registry/regmsg"
+)
+
+func testQueueMessage(agentID string) *registry.AgentSaveMessage {
+	return &registry.AgentSaveMessage{
+		AgentMessage: registry.AgentMessage{
+			Message: regmsg.Message{Action: registry.SaveAgent},
+			AgentID: agentID,
+		},
+	}
+}
+
+type testQueue interface {
+	MessagePublisher
+	SubscribeFrom(ctx context.Context, offset uint64, handler regmsg.HandlerFunc[regmsg.Interface]) error
+	Subscribe(ctx context.Context, handler regmsg.HandlerFunc[regmsg.Interface]) error
+	Acked() uint64
+}
+
+// collect subscribes until the count is reached and returns the agent IDs in the delivery order.
+func collect(r *require.Assertions, subscribe func(context.Context, regmsg.HandlerFunc[regmsg.Interface]) error, count int) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	var agentIDs []string
+	err := subscribe(ctx, func(ctx context.Context, logger *log.Entry, msg regmsg.Interface) error {
+		agentIDs = append(agentIDs, msg.(*registry.AgentSaveMessage).AgentID)
+		if len(agentIDs) == count {
+			cancel()
+		}
+		return nil
+	})
+	r.ErrorIs(err, context.Canceled)
+	return agentIDs
+}
+
+func testQueueDelivery(t *testing.T, queue testQueue) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	for _, agentID := range []string{"0x1", "0x2", "0x3"} {
+		r.NoError(queue.Publish(ctx, nil, testQueueMessage(agentID)))
+	}
+
+	// the failed message is delivered again and not acked before it's handled
+	failed := false
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	var agentIDs []string
+	err := queue.Subscribe(ctx, func(ctx context.Context, logger *log.Entry, msg regmsg.Interface) error {
+		agentID := msg.(*registry.AgentSaveMessage).AgentID
+		if agentID == "0x2" && !failed {
+			failed = true
+			return errors.New("failed")
+		}
+		agentIDs = append(agentIDs, agentID)
+		if agentID == "0x2" {
+			cancel()
+		}
+		return nil
+	})
+	cancel()
+	r.ErrorIs(err, context.Canceled)
+	r.Equal([]string{"0x1", "0x2"}, agentIDs)
+	r.Equal(uint64(2), queue.Acked())
+
+	// resumes after the acked ones
+	r.Equal([]string{"0x3"}, collect(r, queue.Subscribe, 1))
+	r.Equal(uint64(3), queue.Acked())
+
+	// replays from an offset
+	r.Equal([]string{"0x2", "0x3"}, collect(r, func(ctx context.Context, handler regmsg.HandlerFunc[regmsg.Interface]) error {
+		return queue.SubscribeFrom(ctx, 1, handler)
+	}, 2))
+	r.Equal(uint64(3), queue.Acked())
+}
+
+func TestMemoryQueue(t *testing.T) {
+	testQueueDelivery(t, NewMemoryQueue(MemoryQueueConfig{RetryDelay: time.Millisecond}))
+}
+
+func TestMemoryQueue_Bounded(t *testing.T) {
+	r := require.New(t)
+
+	queue := NewMemoryQueue(MemoryQueueConfig{Size: 2, RetryDelay: time.Millisecond})
+	r.NoError(queue.Publish(context.Background(), nil, testQueueMessage("0x1")))
+	r.NoError(queue.Publish(context.Background(), nil, testQueueMessage("0x2")))
+
+	// waits for an ack when full
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	r.ErrorIs(queue.Publish(ctx, nil, testQueueMessage("0x3")), context.DeadlineExceeded)
+
+	r.Equal([]string{"0x1"}, collect(r, queue.Subscribe, 1))
+	r.NoError(queue.Publish(context.Background(), nil, testQueueMessage("0x3")))
+
+	// the acked message is pushed out
+	err := queue.SubscribeFrom(context.Background(), 0, nil)
+	r.ErrorIs(err, ErrOffsetNotRetained)
+
+	r.NoError(queue.Close())
+	r.NoError(queue.Subscribe(context.Background(), nil))
+	r.ErrorIs(queue.Publish(context.Background(), nil, testQueueMessage("0x4")), ErrQueueClosed)
+}
+
+func TestMemoryQueue_PublishTimeout(t *testing.T) {
+	r := require.New(t)
+
+	queue := NewMemoryQueue(MemoryQueueConfig{Size: 1, PublishTimeout: time.Millisecond * 50})
+	r.NoError(queue.Publish(context.Background(), nil, testQueueMessage("0x1")))
+
+	// nobody acks the message
+	r.ErrorIs(queue.Publish(context.Background(), nil, testQueueMessage("0x2")), ErrQueueFull)
+
+	// the context is still respected
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.ErrorIs(queue.Publish(ctx, nil, testQueueMessage("0x2")), context.Canceled)
+}
+
+func TestMemoryQueue_SingleSubscriber(t *testing.T) {
+	r := require.New(t)
+
+	queue := NewMemoryQueue(MemoryQueueConfig{RetryDelay: time.Millisecond})
+	r.NoError(queue.Publish(context.Background(), nil, testQueueMessage("0x1")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	var secondErr error
+	err := queue.Subscribe(ctx, func(ctx context.Context, logger *log.Entry, msg regmsg.Interface) error {
+		defer cancel()
+		secondErr = queue.Subscribe(context.Background(), nil)
+		return secondErr
+	})
+	r.ErrorIs(err, context.Canceled)
+	r.ErrorIs(secondErr, ErrAlreadySubscribed)
+	r.Equal(uint64(0), queue.Acked())
+
+	// can subscribe again after the first one is done
+	r.Equal([]string{"0x1"}, collect(r, queue.Subscribe, 1))
+}
+
+func TestFileQueue(t *testing.T) {
+	queue, err := NewFileQueue(FileQueueConfig{
+		Dir:         t.TempDir(),
+		SegmentSize: 2,
+		RetryDelay:  time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer queue.Close()
+	testQueueDelivery(t, queue)
+}
+
+func TestFileQueue_Reopen(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	cfg := FileQueueConfig{Dir: dir, SegmentSize: 2, RetryDelay: time.Millisecond}
+	queue, err := NewFileQueue(cfg)
+	r.NoError(err)
+	for _, agentID := range []string{"0x1", "0x2", "0x3"} {
+		r.NoError(queue.Publish(context.Background(), nil, testQueueMessage(agentID)))
+	}
+	r.Equal([]string{"0x1", "0x2"}, collect(r, queue.Subscribe, 2))
+	r.NoError(queue.Close())
+
+	// simulate a crash while writing the last segment
+	f, err := os.OpenFile(filepath.Join(dir, "00000000000000000002.seg"), os.O_WRONLY|os.O_APPEND, 0644)
+	r.NoError(err)
+	_, err = f.WriteString(`{"action":"SaveAg`)
+	r.NoError(err)
+	r.NoError(f.Close())
+
+	queue, err = NewFileQueue(cfg)
+	r.NoError(err)
+	defer queue.Close()
+	r.Equal(uint64(2), queue.Acked())
+	r.NoError(queue.Publish(context.Background(), nil, testQueueMessage("0x4")))
+	r.Equal([]string{"0x3", "0x4"}, collect(r, queue.Subscribe, 2))
+
+	// the first segment is fully acked
+	r.NoError(queue.Compact())
+	_, err = os.Stat(filepath.Join(dir, "00000000000000000000.seg"))
+	r.ErrorIs(err, os.ErrNotExist)
+	err = queue.SubscribeFrom(context.Background(), 0, nil)
+	r.ErrorIs(err, ErrOffsetNotRetained)
+	r.Equal([]string{"0x3", "0x4"}, collect(r, func(ctx context.Context, handler regmsg.HandlerFunc[regmsg.Interface]) error {
+		return queue.SubscribeFrom(ctx, 2, handler)
+	}, 2))
+}
+
+func TestFileQueue_SeparateInstances(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	publisher, err := NewFileQueue(FileQueueConfig{Dir: dir, SegmentSize: 2})
+	r.NoError(err)
+	defer publisher.Close()
+	subscriber, err := NewFileQueue(FileQueueConfig{Dir: dir, RetryDelay: time.Millisecond, PollInterval: time.Millisecond * 10})
+	r.NoError(err)
+	defer subscriber.Close()
+
+	r.NoError(publisher.Publish(context.Background(), nil, testQueueMessage("0x1")))
+
+	// the subscriber is caught up before the rest is published
+	done := make(chan []string)
+	go func() {
+		done <- collect(r, subscriber.Subscribe, 3)
+	}()
+	time.Sleep(time.Millisecond * 50)
+	for _, agentID := range []string{"0x2", "0x3"} {
+		r.NoError(publisher.Publish(context.Background(), nil, testQueueMessage(agentID)))
+	}
+	r.Equal([]string{"0x1", "0x2", "0x3"}, <-done)
+	r.Equal(uint64(3), subscriber.Acked())
+
+	// the publisher compacts with the acks of the subscriber
+	r.NoError(publisher.Compact())
+	_, err = os.Stat(filepath.Join(dir, "00000000000000000000.seg"))
+	r.ErrorIs(err, os.ErrNotExist)
+	r.NoError(publisher.Publish(context.Background(), nil, testQueueMessage("0x4")))
+	r.Equal([]string{"0x4"}, collect(r, subscriber.Subscribe, 1))
+}
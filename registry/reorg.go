@@ -0,0 +1,288 @@
+package registry
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/domain/registry/regmsg"
+	"github.com/forta-network/forta-core-go/utils"
+)
+
+const defaultReorgDepth = 64
+
+type trackedMessage struct {
+	logger    *log.Entry
+	msg       regmsg.Interface
+	log       types.Log
+	delivered bool
+}
+
+type trackedBlock struct {
+	number   uint64
+	block    *domain.Block
+	messages []*trackedMessage
+	// all messages are delivered
+	confirmed bool
+}
+
+// reorgGuard keeps the messages of the recent blocks to hold them until they are confirmed
+// and to retract them if their blocks are orphaned.
+type reorgGuard struct {
+	ctx           context.Context
+	confirmations int
+	retract       bool
+	depth         int
+	deliver       regmsg.HandlerFunc[regmsg.Interface]
+
+	// recent blocks from the oldest to the newest
+	blocks     []*trackedBlock
+	current    *trackedBlock
+	currentLog *types.Log
+}
+
+func newReorgGuard(ctx context.Context, cfg ListenerConfig, deliver regmsg.HandlerFunc[regmsg.Interface]) *reorgGuard {
+	depth := cfg.ReorgDepth
+	if depth <= 0 {
+		depth = defaultReorgDepth
+	}
+	// should never forget the blocks before delivering them
+	if depth <= cfg.Confirmations {
+		depth = cfg.Confirmations + 1
+	}
+	return &reorgGuard{
+		ctx:           ctx,
+		confirmations: cfg.Confirmations,
+		retract:       cfg.RetractOrphaned,
+		depth:         depth,
+		deliver:       deliver,
+	}
+}
+
+// handle is used as the message handler of the listener.
+func (rg *reorgGuard) handle(ctx context.Context, logger *log.Entry, msg regmsg.Interface) error {
+	// not listening (e.g. processing a block range)
+	if rg.current == nil || rg.currentLog == nil {
+		return rg.deliver(ctx, logger, msg)
+	}
+	tm := &trackedMessage{logger: logger, msg: msg, log: *rg.currentLog}
+	rg.current.messages = append(rg.current.messages, tm)
+	if rg.confirmations > 0 {
+		return nil
+	}
+	tm.delivered = true
+	return rg.deliver(ctx, logger, msg)
+}
+
+func (rg *reorgGuard) last() *trackedBlock {
+	if len(rg.blocks) == 0 {
+		return nil
+	}
+	return rg.blocks[len(rg.blocks)-1]
+}
+
+// confirm delivers the held messages of the blocks which have enough confirmations and
+// returns the newly confirmed blocks from the oldest to the newest.
+func (rg *reorgGuard) confirm(number uint64) ([]*trackedBlock, error) {
+	var confirmed []*trackedBlock
+	for _, tb := range rg.blocks {
+		if tb.number+uint64(rg.confirmations) > number {
+			break
+		}
+		if tb.confirmed {
+			continue
+		}
+		for _, tm := range tb.messages {
+			if tm.delivered {
+				continue
+			}
+			if err := rg.deliver(rg.ctx, tm.logger, tm.msg); err != nil {
+				return nil, err
+			}
+			tm.delivered = true
+		}
+		tb.confirmed = true
+		confirmed = append(confirmed, tb)
+	}
+	if len(rg.blocks) > rg.depth {
+		rg.blocks = rg.blocks[len(rg.blocks)-rg.depth:]
+	}
+	return confirmed, nil
+}
+
+// drop forgets the message and sends a retraction if the message was delivered.
+func (rg *reorgGuard) drop(tm *trackedMessage) error {
+	if !tm.delivered {
+		return nil
+	}
+	logger := tm.logger.WithField("retracted", true)
+	if !rg.retract {
+		logger.Warn("delivered message is orphaned by a reorg")
+		return nil
+	}
+	msg, err := registry.NewRetractionMessage(tm.msg, tm.log.Index)
+	if err != nil {
+		return err
+	}
+	return rg.deliver(rg.ctx, logger, msg)
+}
+
+// removeLog drops the messages of a log which is removed from the chain.
+func (rg *reorgGuard) removeLog(le types.Log) error {
+	for _, tb := range rg.blocks {
+		var kept []*trackedMessage
+		for _, tm := range tb.messages {
+			if tm.log.BlockHash != le.BlockHash || tm.log.TxHash != le.TxHash || tm.log.Index != le.Index {
+				kept = append(kept, tm)
+				continue
+			}
+			if err := rg.drop(tm); err != nil {
+				return err
+			}
+		}
+		tb.messages = kept
+	}
+	return nil
+}
+
+func (l *listener) listenReorgSafe() error {
+	return l.logs.ForEachLog(l.handleReorgSafeLog, func(blk *domain.Block) error {
+		confirmed, err := l.finishReorgSafeBlock(blk)
+		if err != nil {
+			return err
+		}
+		if err := l.handleAfterBlock(blk); err != nil {
+			return err
+		}
+		for _, tb := range confirmed {
+			if err := l.handleConfirmedBlock(tb.block); err != nil {
+				return err
+			}
+		}
+		// the cursor points to the confirmed block so that the held messages are not lost on restart
+		if len(confirmed) > 0 {
+			l.saveCursor(confirmed[len(confirmed)-1].block)
+		}
+		return nil
+	})
+}
+
+func (l *listener) handleReorgSafeLog(blk *domain.Block, le types.Log) error {
+	rg := l.reorgGuard
+	if err := l.enterBlock(blk); err != nil {
+		return err
+	}
+	if le.Removed {
+		return rg.removeLog(le)
+	}
+	rg.currentLog = &le
+	defer func() {
+		rg.currentLog = nil
+	}()
+	return l.handleLog(blk, le)
+}
+
+func (l *listener) finishReorgSafeBlock(blk *domain.Block) ([]*trackedBlock, error) {
+	rg := l.reorgGuard
+	if err := l.enterBlock(blk); err != nil {
+		return nil, err
+	}
+	number := rg.current.number
+	rg.current = nil
+	return rg.confirm(number)
+}
+
+// enterBlock starts tracking the block and handles the reorg if the block is not the child
+// of the last tracked block.
+func (l *listener) enterBlock(blk *domain.Block) error {
+	rg := l.reorgGuard
+	if rg.current != nil && rg.current.block.Hash == blk.Hash {
+		return nil
+	}
+	number, err := utils.HexToBigInt(blk.Number)
+	if err != nil {
+		return err
+	}
+	if last := rg.last(); last != nil && number.Uint64() == last.number+1 && blk.ParentHash != last.block.Hash {
+		if err := l.handleReorg(); err != nil {
+			return err
+		}
+	}
+	rg.current = &trackedBlock{
+		number: number.Uint64(),
+		block:  blk,
+	}
+	rg.blocks = append(rg.blocks, rg.current)
+	return nil
+}
+
+// handleReorg finds the orphaned blocks, retracts their messages and handles the logs
+// of the canonical blocks instead.
+func (l *listener) handleReorg() error {
+	rg := l.reorgGuard
+	rg.current = nil
+
+	forkIndex := len(rg.blocks)
+	for i := len(rg.blocks) - 1; i >= 0; i-- {
+		tb := rg.blocks[i]
+		canonical, err := l.eth.BlockByNumber(l.ctx, new(big.Int).SetUint64(tb.number))
+		if err != nil {
+			return err
+		}
+		if canonical.Hash == tb.block.Hash {
+			break
+		}
+		forkIndex = i
+	}
+	orphaned := rg.blocks[forkIndex:]
+	rg.blocks = rg.blocks[:forkIndex]
+	if len(orphaned) == 0 {
+		return nil
+	}
+
+	logger := log.WithFields(log.Fields{
+		"fromBlock": orphaned[0].number,
+		"toBlock":   orphaned[len(orphaned)-1].number,
+	})
+	if forkIndex == 0 {
+		logger.Warn("reorg is deeper than the tracked blocks")
+	} else {
+		logger.Warn("detected reorg")
+	}
+
+	// retract from the newest to the oldest
+	for i := len(orphaned) - 1; i >= 0; i-- {
+		messages := orphaned[i].messages
+		for j := len(messages) - 1; j >= 0; j-- {
+			if err := rg.drop(messages[j]); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, tb := range orphaned {
+		num := new(big.Int).SetUint64(tb.number)
+		blk, err := l.eth.BlockByNumber(l.ctx, num)
+		if err != nil {
+			return err
+		}
+		logs, err := l.logs.GetLogsForRange(num, num)
+		if err != nil {
+			return err
+		}
+		if err := l.enterBlock(blk); err != nil {
+			return err
+		}
+		for _, le := range logs {
+			if err := l.handleReorgSafeLog(blk, le); err != nil {
+				return err
+			}
+		}
+		rg.current = nil
+	}
+	return nil
+}
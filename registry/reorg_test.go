@@ -0,0 +1,140 @@
+package registry
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/domain/registry/regmsg"
+	mock_ethereum "github.com/forta-network/forta-core-go/ethereum/mocks"
+	mock_feeds "github.com/forta-network/forta-core-go/feeds/mocks"
+)
+
+type reorgTest struct {
+	r         *require.Assertions
+	l         *listener
+	eth       *mock_ethereum.MockClient
+	logs      *mock_feeds.MockLogFeed
+	delivered []regmsg.Interface
+	confirmed []string
+}
+
+func newReorgTest(t *testing.T, cfg ListenerConfig) *reorgTest {
+	ctrl := gomock.NewController(t)
+	rt := &reorgTest{
+		r:    require.New(t),
+		eth:  mock_ethereum.NewMockClient(ctrl),
+		logs: mock_feeds.NewMockLogFeed(ctrl),
+	}
+	rt.l = &listener{
+		ctx:  context.Background(),
+		cfg:  cfg,
+		eth:  rt.eth,
+		logs: rt.logs,
+	}
+	rt.l.reorgGuard = newReorgGuard(rt.l.ctx, cfg, func(ctx context.Context, logger *log.Entry, msg regmsg.Interface) error {
+		rt.delivered = append(rt.delivered, msg)
+		return nil
+	})
+	return rt
+}
+
+func testReorgBlock(number int64, hash, parentHash string) *domain.Block {
+	return &domain.Block{
+		Number:     hexutil.EncodeBig(big.NewInt(number)),
+		Hash:       hash,
+		ParentHash: parentHash,
+	}
+}
+
+// handleBlock simulates handling a block with a log which creates a message.
+func (rt *reorgTest) handleBlock(blk *domain.Block, withMessage bool) {
+	rt.r.NoError(rt.l.enterBlock(blk))
+	if withMessage {
+		rg := rt.l.reorgGuard
+		rg.currentLog = &types.Log{BlockHash: common.HexToHash(blk.Hash), Index: 1}
+		msg := &registry.DispatchMessage{
+			Message: regmsg.From("0xtx", blk, registry.Link),
+		}
+		rt.r.NoError(rg.handle(rt.l.ctx, log.NewEntry(log.StandardLogger()), msg))
+		rg.currentLog = nil
+	}
+	confirmed, err := rt.l.finishReorgSafeBlock(blk)
+	rt.r.NoError(err)
+	for _, tb := range confirmed {
+		rt.confirmed = append(rt.confirmed, tb.block.Hash)
+	}
+}
+
+func TestReorgGuard_Optimistic(t *testing.T) {
+	rt := newReorgTest(t, ListenerConfig{RetractOrphaned: true})
+
+	rt.handleBlock(testReorgBlock(1, "0x1", "0x0"), false)
+	rt.handleBlock(testReorgBlock(2, "0x2", "0x1"), true)
+	rt.r.Len(rt.delivered, 1)
+
+	// block 2 is replaced and the new block 3 is not the child of the old block 2
+	rt.eth.EXPECT().BlockByNumber(gomock.Any(), big.NewInt(2)).Return(testReorgBlock(2, "0x2b", "0x1"), nil).Times(2)
+	rt.eth.EXPECT().BlockByNumber(gomock.Any(), big.NewInt(1)).Return(testReorgBlock(1, "0x1", "0x0"), nil)
+	rt.logs.EXPECT().GetLogsForRange(big.NewInt(2), big.NewInt(2)).Return(nil, nil)
+	rt.handleBlock(testReorgBlock(3, "0x3", "0x2b"), false)
+
+	rt.r.Len(rt.delivered, 2)
+	retraction, ok := rt.delivered[1].(*registry.RetractionMessage)
+	rt.r.True(ok)
+	rt.r.Equal(registry.Link, retraction.RetractedAction)
+	rt.r.Equal("0x2", retraction.Source.BlockHash)
+
+	// the canonical blocks are tracked now
+	var hashes []string
+	for _, tb := range rt.l.reorgGuard.blocks {
+		hashes = append(hashes, tb.block.Hash)
+	}
+	rt.r.Equal([]string{"0x1", "0x2b", "0x3"}, hashes)
+}
+
+func TestReorgGuard_Confirmations(t *testing.T) {
+	rt := newReorgTest(t, ListenerConfig{Confirmations: 2})
+
+	rt.handleBlock(testReorgBlock(1, "0x1", "0x0"), true)
+	rt.handleBlock(testReorgBlock(2, "0x2", "0x1"), true)
+	rt.r.Len(rt.delivered, 0)
+	rt.r.Empty(rt.confirmed)
+
+	// block 2 is orphaned before it is confirmed
+	rt.eth.EXPECT().BlockByNumber(gomock.Any(), big.NewInt(2)).Return(testReorgBlock(2, "0x2b", "0x1"), nil).Times(2)
+	rt.eth.EXPECT().BlockByNumber(gomock.Any(), big.NewInt(1)).Return(testReorgBlock(1, "0x1", "0x0"), nil)
+	rt.logs.EXPECT().GetLogsForRange(big.NewInt(2), big.NewInt(2)).Return(nil, nil)
+	rt.handleBlock(testReorgBlock(3, "0x3", "0x2b"), false)
+
+	// only the message of block 1 is delivered after two confirmations
+	rt.r.Len(rt.delivered, 1)
+	rt.r.Equal("0x1", rt.delivered[0].Info().Source.BlockHash)
+	rt.r.Equal([]string{"0x1"}, rt.confirmed)
+
+	rt.handleBlock(testReorgBlock(4, "0x4", "0x3"), false)
+	rt.r.Len(rt.delivered, 1)
+	// each block is confirmed once
+	rt.r.Equal([]string{"0x1", "0x2b"}, rt.confirmed)
+}
+
+func TestReorgGuard_RemovedLog(t *testing.T) {
+	rt := newReorgTest(t, ListenerConfig{RetractOrphaned: true})
+
+	blk := testReorgBlock(1, "0x1", "0x0")
+	rt.handleBlock(blk, true)
+	rt.r.NoError(rt.l.reorgGuard.removeLog(types.Log{BlockHash: common.HexToHash(blk.Hash), Index: 1}))
+
+	rt.r.Len(rt.delivered, 2)
+	_, ok := rt.delivered[1].(*registry.RetractionMessage)
+	rt.r.True(ok)
+}
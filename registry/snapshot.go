@@ -0,0 +1,485 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-core-go/contracts/merged/contract_scanner_pool_registry"
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/domain/registry/regmsg"
+	"github.com/forta-network/forta-core-go/feeds/checkpoint"
+	"github.com/forta-network/forta-core-go/utils"
+)
+
+// ScannerPool is a scanner pool in the snapshot.
+type ScannerPool struct {
+	PoolID  string `json:"poolId"`
+	ChainID int64  `json:"chainId"`
+	Owner   string `json:"owner"`
+	// the stakes are set only if the snapshot tracks the stakes
+	ActiveStake     *big.Int `json:"activeStake,omitempty"`
+	AllocatedStake  *big.Int `json:"allocatedStake,omitempty"`
+	StakePerScanner *big.Int `json:"stakePerScanner,omitempty"`
+}
+
+// SnapshotConfig contains the snapshot settings.
+type SnapshotConfig struct {
+	// Stakes enables reading and tracking the active stakes, which needs a call per entity.
+	Stakes bool
+}
+
+// Snapshot is an in-memory model of the registry at a block. All IDs are lowercase.
+//
+// It is not safe for concurrent use.
+type Snapshot struct {
+	BlockNumber uint64                  `json:"blockNumber"`
+	Agents      map[string]*Agent       `json:"agents"`
+	Scanners    map[string]*Scanner     `json:"scanners"`
+	Pools       map[string]*ScannerPool `json:"pools"`
+	// Assignments contains the sorted IDs of the agents assigned to each scanner.
+	Assignments map[string][]string `json:"assignments"`
+
+	// the stakes are nil if they are not tracked and a nil stake in a map
+	// is changed after the snapshot block and needs a refresh
+	AgentStakes   map[string]*big.Int `json:"agentStakes"`
+	ScannerStakes map[string]*big.Int `json:"scannerStakes"`
+
+	// Outdated is set when a retracted message can't be undone and the snapshot
+	// should be built again.
+	Outdated bool `json:"outdated,omitempty"`
+}
+
+// NewSnapshot creates an empty snapshot at the block.
+func NewSnapshot(blockNumber uint64, cfg SnapshotConfig) *Snapshot {
+	s := &Snapshot{
+		BlockNumber: blockNumber,
+		Agents:      make(map[string]*Agent),
+		Scanners:    make(map[string]*Scanner),
+		Pools:       make(map[string]*ScannerPool),
+		Assignments: make(map[string][]string),
+	}
+	if cfg.Stakes {
+		s.AgentStakes = make(map[string]*big.Int)
+		s.ScannerStakes = make(map[string]*big.Int)
+	}
+	return s
+}
+
+// BuildSnapshot builds a snapshot from the contract calls at the block. It pegs the client
+// to the block and resets the client opts when done.
+func BuildSnapshot(c Client, blockNumber *big.Int, cfg SnapshotConfig) (*Snapshot, error) {
+	if blockNumber == nil {
+		return nil, errors.New("snapshot block number is not set")
+	}
+	c.PegBlock(blockNumber)
+	defer c.ResetOpts()
+
+	s := NewSnapshot(blockNumber.Uint64(), cfg)
+	logger := log.WithField("block", s.BlockNumber)
+
+	err := c.ForEachAgent(func(a *Agent) error {
+		agent := *a
+		agent.AgentID = strings.ToLower(agent.AgentID)
+		s.Agents[agent.AgentID] = &agent
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get agents: %v", err)
+	}
+	logger.WithField("count", len(s.Agents)).Info("collected agents")
+
+	// the pool scanners are collected after the legacy ones so that they replace the migrated scanners
+	addScanner := func(scn *Scanner) error {
+		scanner := *scn
+		scanner.ScannerID = strings.ToLower(scanner.ScannerID)
+		s.Scanners[scanner.ScannerID] = &scanner
+		return nil
+	}
+	if err := c.ForEachScanner(addScanner); err != nil {
+		return nil, fmt.Errorf("failed to get scanners: %v", err)
+	}
+	err = c.ForEachPoolScannerSinceBlock(
+		scannerRegistryDeployBlock,
+		func(_ *contract_scanner_pool_registry.ScannerPoolRegistryScannerUpdated, scn *Scanner) error {
+			return addScanner(scn)
+		},
+	)
+	if err != nil && !errors.Is(err, ErrContractNotReady) {
+		return nil, fmt.Errorf("failed to get pool scanners: %v", err)
+	}
+	logger.WithField("count", len(s.Scanners)).Info("collected scanners")
+
+	for _, scanner := range s.Scanners {
+		if err := s.buildPool(c, blockNumber, scanner); err != nil {
+			return nil, err
+		}
+		err := c.ForEachAssignedAgent(scanner.ScannerID, func(a *Agent) error {
+			s.link(scanner.ScannerID, a.AgentID)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get assigned agents of scanner %s: %v", scanner.ScannerID, err)
+		}
+	}
+	logger.WithField("count", len(s.Pools)).Info("collected pools and assignments")
+
+	if cfg.Stakes {
+		for agentID := range s.Agents {
+			s.AgentStakes[agentID] = nil
+		}
+		for scannerID := range s.Scanners {
+			s.ScannerStakes[scannerID] = nil
+		}
+		if err := s.RefreshStakes(c); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Snapshot) buildPool(c Client, blockNumber *big.Int, scanner *Scanner) error {
+	if len(scanner.PoolID) == 0 {
+		return nil
+	}
+	if pool, ok := s.Pools[scanner.PoolID]; ok {
+		scanner.Owner = pool.Owner
+		return nil
+	}
+	poolID, ok := new(big.Int).SetString(scanner.PoolID, 10)
+	if !ok {
+		return fmt.Errorf("invalid pool id: %s", scanner.PoolID)
+	}
+	owner, err := c.GetScannerPoolOwner(poolID)
+	if err != nil {
+		return fmt.Errorf("failed to get owner of pool %s: %v", scanner.PoolID, err)
+	}
+	pool := &ScannerPool{
+		PoolID:  scanner.PoolID,
+		ChainID: scanner.ChainID,
+		Owner:   owner,
+	}
+	scanner.Owner = pool.Owner
+	s.Pools[pool.PoolID] = pool
+	if s.AgentStakes == nil {
+		return nil
+	}
+	if pool.AllocatedStake, err = c.GetAllocatedStakePerManaged(blockNumber, poolID); err != nil {
+		return fmt.Errorf("failed to get allocated stake of pool %s: %v", pool.PoolID, err)
+	}
+	return nil
+}
+
+// RefreshStakes reads the stakes which are changed after the snapshot block again,
+// at the snapshot block.
+func (s *Snapshot) RefreshStakes(c Client) error {
+	if s.AgentStakes == nil {
+		return nil
+	}
+	blockNumber := new(big.Int).SetUint64(s.BlockNumber)
+	for agentID, stake := range s.AgentStakes {
+		if stake != nil {
+			continue
+		}
+		stake, err := c.GetActiveAgentStake(blockNumber, agentID)
+		if err != nil {
+			return fmt.Errorf("failed to get stake of agent %s: %v", agentID, err)
+		}
+		s.AgentStakes[agentID] = stake
+	}
+	for scannerID, stake := range s.ScannerStakes {
+		if stake != nil {
+			continue
+		}
+		stake, err := c.GetActiveScannerStake(blockNumber, scannerID)
+		if err != nil {
+			return fmt.Errorf("failed to get stake of scanner %s: %v", scannerID, err)
+		}
+		s.ScannerStakes[scannerID] = stake
+	}
+	for _, pool := range s.Pools {
+		if pool.ActiveStake != nil {
+			continue
+		}
+		poolID, ok := new(big.Int).SetString(pool.PoolID, 10)
+		if !ok {
+			return fmt.Errorf("invalid pool id: %s", pool.PoolID)
+		}
+		stake, err := c.GetActivePoolStake(blockNumber, poolID)
+		if err != nil {
+			return fmt.Errorf("failed to get stake of pool %s: %v", pool.PoolID, err)
+		}
+		pool.ActiveStake = stake
+	}
+	return nil
+}
+
+// Apply advances the model with a message from the listener. The stake changes only mark
+// the stakes for a refresh since the messages don't always contain the amounts.
+func (s *Snapshot) Apply(msg regmsg.Interface) error {
+	switch m := msg.(type) {
+	case *registry.RetractionMessage:
+		return s.retract(m)
+
+	case *registry.AgentSaveMessage:
+		agentID := strings.ToLower(m.AgentID)
+		agent, ok := s.Agents[agentID]
+		if !ok {
+			agent = &Agent{AgentID: agentID}
+			s.Agents[agentID] = agent
+			s.markAgentStake(agentID)
+		}
+		agent.ChainIDs = m.ChainIDs
+		agent.Enabled = m.Enabled
+		agent.Manifest = m.Metadata
+		agent.Owner = m.Owner
+
+	case *registry.AgentMessage:
+		// the enabled flag follows the latest enable or disable message
+		if agent, ok := s.Agents[strings.ToLower(m.AgentID)]; ok {
+			agent.Enabled = m.Action == registry.EnableAgent
+		}
+
+	case *registry.ScannerSaveMessage:
+		scannerID := strings.ToLower(m.ScannerID)
+		scanner, ok := s.Scanners[scannerID]
+		if !ok {
+			scanner = &Scanner{ScannerID: scannerID}
+			s.Scanners[scannerID] = scanner
+			s.markScannerStake(scannerID)
+		}
+		scanner.ChainID = m.ChainID
+		scanner.PoolID = m.PoolID
+		scanner.Enabled = m.Enabled
+		if pool, ok := s.Pools[m.PoolID]; ok {
+			scanner.Owner = pool.Owner
+		}
+
+	case *registry.ScannerMessage:
+		if scanner, ok := s.Scanners[strings.ToLower(m.ScannerID)]; ok {
+			scanner.Enabled = m.Action == registry.EnableScanner
+		}
+
+	case *registry.UpdateScannerPoolMessage:
+		pool, ok := s.Pools[m.PoolID]
+		if !ok {
+			pool = &ScannerPool{PoolID: m.PoolID}
+			s.Pools[m.PoolID] = pool
+			if s.AgentStakes != nil {
+				pool.ActiveStake = new(big.Int)
+			}
+		}
+		if m.ChainID != nil {
+			pool.ChainID = *m.ChainID
+		}
+		if m.Owner != nil {
+			pool.Owner = *m.Owner
+			for _, scanner := range s.Scanners {
+				if scanner.PoolID == pool.PoolID {
+					scanner.Owner = pool.Owner
+				}
+			}
+		}
+
+	case *registry.ScannerPoolAllocationMessage:
+		pool, ok := s.Pools[m.PoolID]
+		if !ok || s.AgentStakes == nil {
+			return nil
+		}
+		pool.AllocatedStake, ok = new(big.Int).SetString(m.TotalAmount, 10)
+		if !ok {
+			return fmt.Errorf("invalid allocated stake: %s", m.TotalAmount)
+		}
+		pool.StakePerScanner, ok = new(big.Int).SetString(m.StakePerScanner, 10)
+		if !ok {
+			return fmt.Errorf("invalid stake per scanner: %s", m.StakePerScanner)
+		}
+
+	case *registry.DispatchMessage:
+		scannerID := strings.ToLower(m.ScannerID)
+		agentID := strings.ToLower(m.AgentID)
+		if m.Action == registry.Link {
+			s.link(scannerID, agentID)
+		} else {
+			s.unlink(scannerID, agentID)
+		}
+
+	case *registry.AgentStakeMessage:
+		s.markAgentStake(strings.ToLower(m.AgentID))
+
+	case *registry.ScannerStakeMessage:
+		s.markScannerStake(strings.ToLower(m.ScannerID))
+
+	case *registry.ScannerPoolStakeMessage:
+		if pool, ok := s.Pools[m.PoolID]; ok && s.AgentStakes != nil {
+			pool.ActiveStake = nil
+		}
+	}
+	return nil
+}
+
+// retract undoes a message which is orphaned by a reorg. The links and the stake changes
+// can be undone but the messages don't contain the previous state of the agents, the
+// scanners and the pools, so the snapshot is marked outdated for the other messages.
+func (s *Snapshot) retract(m *registry.RetractionMessage) error {
+	msg, err := registry.ParseMessage(string(m.Retracted))
+	if err != nil {
+		return fmt.Errorf("failed to parse retracted message: %v", err)
+	}
+	switch retracted := msg.(type) {
+	case *registry.DispatchMessage:
+		scannerID := strings.ToLower(retracted.ScannerID)
+		agentID := strings.ToLower(retracted.AgentID)
+		if retracted.Action == registry.Link {
+			s.unlink(scannerID, agentID)
+		} else {
+			s.link(scannerID, agentID)
+		}
+
+	case *registry.AgentStakeMessage, *registry.ScannerStakeMessage, *registry.ScannerPoolStakeMessage:
+		// the stakes are read again at the snapshot block
+		return s.Apply(msg)
+
+	case *registry.ScannerNodeVersionMessage, *registry.AgentStakeThresholdMessage, *registry.ScannerStakeThresholdMessage,
+		*registry.TransferSharesMessage, *registry.UpgradeMessage, *registry.UpdatePaymentSubscriptionMessage:
+		// not in the snapshot
+
+	case *registry.ScannerPoolAllocationMessage:
+		if s.AgentStakes != nil {
+			s.outdate(m)
+		}
+
+	default:
+		s.outdate(m)
+	}
+	return nil
+}
+
+func (s *Snapshot) outdate(m *registry.RetractionMessage) {
+	log.WithFields(m.LogFields()).Warn("snapshot is outdated after a retraction")
+	s.Outdated = true
+}
+
+func (s *Snapshot) markAgentStake(agentID string) {
+	if s.AgentStakes != nil {
+		s.AgentStakes[agentID] = nil
+	}
+}
+
+func (s *Snapshot) markScannerStake(scannerID string) {
+	if s.ScannerStakes != nil {
+		s.ScannerStakes[scannerID] = nil
+	}
+}
+
+func (s *Snapshot) link(scannerID, agentID string) {
+	scannerID = strings.ToLower(scannerID)
+	agentID = strings.ToLower(agentID)
+	agentIDs := s.Assignments[scannerID]
+	i := sort.SearchStrings(agentIDs, agentID)
+	if i < len(agentIDs) && agentIDs[i] == agentID {
+		return
+	}
+	agentIDs = append(agentIDs, "")
+	copy(agentIDs[i+1:], agentIDs[i:])
+	agentIDs[i] = agentID
+	s.Assignments[scannerID] = agentIDs
+}
+
+func (s *Snapshot) unlink(scannerID, agentID string) {
+	agentIDs := s.Assignments[scannerID]
+	i := sort.SearchStrings(agentIDs, agentID)
+	if i == len(agentIDs) || agentIDs[i] != agentID {
+		return
+	}
+	agentIDs = append(agentIDs[:i], agentIDs[i+1:]...)
+	if len(agentIDs) == 0 {
+		delete(s.Assignments, scannerID)
+		return
+	}
+	s.Assignments[scannerID] = agentIDs
+}
+
+// AssignedScanners returns the sorted IDs of the scanners which the agent is assigned to.
+func (s *Snapshot) AssignedScanners(agentID string) []string {
+	agentID = strings.ToLower(agentID)
+	var scannerIDs []string
+	for scannerID, agentIDs := range s.Assignments {
+		i := sort.SearchStrings(agentIDs, agentID)
+		if i < len(agentIDs) && agentIDs[i] == agentID {
+			scannerIDs = append(scannerIDs, scannerID)
+		}
+	}
+	sort.Strings(scannerIDs)
+	return scannerIDs
+}
+
+// Handlers adds the snapshot handlers after the given handlers, so that the snapshot
+// follows the listener.
+func (s *Snapshot) Handlers(h Handlers) Handlers {
+	h.SaveAgentHandlers = append(h.SaveAgentHandlers, snapshotHandler[*registry.AgentSaveMessage](s))
+	h.AgentActionHandlers = append(h.AgentActionHandlers, snapshotHandler[*registry.AgentMessage](s))
+	h.SaveScannerHandlers = append(h.SaveScannerHandlers, snapshotHandler[*registry.ScannerSaveMessage](s))
+	h.ScannerActionHandlers = append(h.ScannerActionHandlers, snapshotHandler[*registry.ScannerMessage](s))
+	h.UpdateScannerPoolHandlers = append(h.UpdateScannerPoolHandlers, snapshotHandler[*registry.UpdateScannerPoolMessage](s))
+	h.DispatchHandlers = append(h.DispatchHandlers, snapshotHandler[*registry.DispatchMessage](s))
+	h.AgentStakeHandlers = append(h.AgentStakeHandlers, snapshotHandler[*registry.AgentStakeMessage](s))
+	h.ScannerStakeHandlers = append(h.ScannerStakeHandlers, snapshotHandler[*registry.ScannerStakeMessage](s))
+	h.ScannerPoolStakeHandlers = append(h.ScannerPoolStakeHandlers, snapshotHandler[*registry.ScannerPoolStakeMessage](s))
+	h.ScannerPoolAllocationHandlers = append(h.ScannerPoolAllocationHandlers, snapshotHandler[*registry.ScannerPoolAllocationMessage](s))
+	h.RetractionHandlers = append(h.RetractionHandlers, snapshotHandler[*registry.RetractionMessage](s))
+
+	// the messages can be held for confirmations after the block is handled
+	confirmedBlock := h.ConfirmedBlockHandler
+	h.ConfirmedBlockHandler = func(blk *domain.Block) error {
+		if confirmedBlock != nil {
+			if err := confirmedBlock(blk); err != nil {
+				return err
+			}
+		}
+		number, err := utils.HexToBigInt(blk.Number)
+		if err != nil {
+			return err
+		}
+		s.BlockNumber = number.Uint64()
+		return nil
+	}
+	return h
+}
+
+func snapshotHandler[I regmsg.Interface](s *Snapshot) regmsg.HandlerFunc[I] {
+	return func(ctx context.Context, logger *log.Entry, msg I) error {
+		return s.Apply(msg)
+	}
+}
+
+// SaveFile writes the snapshot to the file.
+func (s *Snapshot) SaveFile(path string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %v", err)
+	}
+	return checkpoint.WriteFileAtomic(path, b)
+}
+
+// LoadSnapshotFile reads a snapshot which was saved to the file. The listener can start
+// from the block after the snapshot block to keep it up to date.
+func LoadSnapshotFile(path string) (*Snapshot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot file: %v", err)
+	}
+	s := NewSnapshot(0, SnapshotConfig{})
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot file: %v", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,184 @@
+package registry_test
+
+import (
+	"context"
+	"math/big"
+	"path"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-core-go/contracts/merged/contract_scanner_pool_registry"
+	"github.com/forta-network/forta-core-go/domain"
+	regdomain "github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/domain/registry/regmsg"
+	"github.com/forta-network/forta-core-go/registry"
+	mock_registry "github.com/forta-network/forta-core-go/registry/mocks"
+)
+
+const (
+	testSnapshotAgentID   = "0xagent1"
+	testSnapshotAgentID2  = "0xagent2"
+	testSnapshotScannerID = "0xscanner1"
+	testSnapshotPoolOwner = "0xOwner"
+)
+
+func TestBuildSnapshot(t *testing.T) {
+	r := require.New(t)
+
+	client := mock_registry.NewMockClient(gomock.NewController(t))
+	blockNumber := big.NewInt(100)
+
+	client.EXPECT().PegBlock(blockNumber)
+	client.EXPECT().ResetOpts()
+	client.EXPECT().ForEachAgent(gomock.Any()).DoAndReturn(func(handler func(*registry.Agent) error) error {
+		return handler(&registry.Agent{AgentID: "0xAgent1", ChainIDs: []int64{1}, Enabled: true})
+	})
+	client.EXPECT().ForEachScanner(gomock.Any()).Return(nil)
+	client.EXPECT().ForEachPoolScannerSinceBlock(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ uint64, handler func(*contract_scanner_pool_registry.ScannerPoolRegistryScannerUpdated, *registry.Scanner) error) error {
+			return handler(nil, &registry.Scanner{ScannerID: "0xScanner1", ChainID: 1, Enabled: true, PoolID: "1"})
+		},
+	)
+	client.EXPECT().GetScannerPoolOwner(big.NewInt(1)).Return(testSnapshotPoolOwner, nil)
+	client.EXPECT().GetAllocatedStakePerManaged(blockNumber, big.NewInt(1)).Return(big.NewInt(500), nil)
+	client.EXPECT().ForEachAssignedAgent(testSnapshotScannerID, gomock.Any()).DoAndReturn(
+		func(_ string, handler func(*registry.Agent) error) error {
+			return handler(&registry.Agent{AgentID: testSnapshotAgentID})
+		},
+	)
+	client.EXPECT().GetActiveAgentStake(blockNumber, testSnapshotAgentID).Return(big.NewInt(10), nil)
+	client.EXPECT().GetActiveScannerStake(blockNumber, testSnapshotScannerID).Return(big.NewInt(20), nil)
+	client.EXPECT().GetActivePoolStake(blockNumber, big.NewInt(1)).Return(big.NewInt(30), nil)
+
+	s, err := registry.BuildSnapshot(client, blockNumber, registry.SnapshotConfig{Stakes: true})
+	r.NoError(err)
+
+	r.Equal(uint64(100), s.BlockNumber)
+	r.Contains(s.Agents, testSnapshotAgentID)
+	r.Equal(testSnapshotPoolOwner, s.Scanners[testSnapshotScannerID].Owner)
+	r.Equal(&registry.ScannerPool{
+		PoolID:         "1",
+		ChainID:        1,
+		Owner:          testSnapshotPoolOwner,
+		ActiveStake:    big.NewInt(30),
+		AllocatedStake: big.NewInt(500),
+	}, s.Pools["1"])
+	r.Equal([]string{testSnapshotAgentID}, s.Assignments[testSnapshotScannerID])
+	r.Equal([]string{testSnapshotScannerID}, s.AssignedScanners(testSnapshotAgentID))
+	r.Equal(big.NewInt(10), s.AgentStakes[testSnapshotAgentID])
+	r.Equal(big.NewInt(20), s.ScannerStakes[testSnapshotScannerID])
+
+	// stake changes are read again
+	r.NoError(s.Apply(&regdomain.AgentStakeMessage{AgentID: testSnapshotAgentID}))
+	r.Nil(s.AgentStakes[testSnapshotAgentID])
+	client.EXPECT().GetActiveAgentStake(blockNumber, testSnapshotAgentID).Return(big.NewInt(15), nil)
+	r.NoError(s.RefreshStakes(client))
+	r.Equal(big.NewInt(15), s.AgentStakes[testSnapshotAgentID])
+}
+
+func TestSnapshot_Handlers(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	logger := log.NewEntry(log.StandardLogger())
+
+	s := registry.NewSnapshot(10, registry.SnapshotConfig{})
+	var afterBlock, confirmedBlock bool
+	handlers := s.Handlers(registry.Handlers{
+		AfterBlockHandler: func(blk *domain.Block) error {
+			afterBlock = true
+			return nil
+		},
+		ConfirmedBlockHandler: func(blk *domain.Block) error {
+			confirmedBlock = true
+			return nil
+		},
+	})
+	handlerReg := registry.NewHandlerRegistry(handlers)
+
+	for _, msg := range []regmsg.Interface{
+		&regdomain.AgentSaveMessage{
+			AgentMessage:    regdomain.AgentMessage{Message: regmsg.Message{Action: regdomain.SaveAgent}, AgentID: testSnapshotAgentID},
+			AgentProperties: regdomain.AgentProperties{Enabled: true, Metadata: "manifest", ChainIDs: []int64{1}},
+		},
+		&regdomain.AgentSaveMessage{
+			AgentMessage:    regdomain.AgentMessage{Message: regmsg.Message{Action: regdomain.SaveAgent}, AgentID: testSnapshotAgentID2},
+			AgentProperties: regdomain.AgentProperties{Enabled: true},
+		},
+		&regdomain.AgentMessage{Message: regmsg.Message{Action: regdomain.DisableAgent}, AgentID: testSnapshotAgentID2},
+		&regdomain.ScannerSaveMessage{
+			ScannerMessage: regdomain.ScannerMessage{Message: regmsg.Message{Action: regdomain.SaveScanner}, ScannerID: testSnapshotScannerID},
+			ChainID:        1,
+			PoolID:         "1",
+			Enabled:        true,
+		},
+		&regdomain.DispatchMessage{Message: regmsg.Message{Action: regdomain.Link}, ScannerID: testSnapshotScannerID, AgentID: testSnapshotAgentID2},
+		&regdomain.DispatchMessage{Message: regmsg.Message{Action: regdomain.Link}, ScannerID: testSnapshotScannerID, AgentID: testSnapshotAgentID},
+		&regdomain.DispatchMessage{Message: regmsg.Message{Action: regdomain.Unlink}, ScannerID: testSnapshotScannerID, AgentID: testSnapshotAgentID2},
+	} {
+		r.NoError(handlerReg.Handle(ctx, logger, msg))
+	}
+	owner := testSnapshotPoolOwner
+	r.NoError(s.Apply(&regdomain.UpdateScannerPoolMessage{PoolID: "1", Owner: &owner}))
+	// the messages of the block can still be held for confirmations
+	r.NoError(handlers.AfterBlockHandler(&domain.Block{Number: "0xb"}))
+	r.True(afterBlock)
+	r.Equal(uint64(10), s.BlockNumber)
+
+	r.NoError(handlers.ConfirmedBlockHandler(&domain.Block{Number: "0xb"}))
+	r.True(confirmedBlock)
+	r.Equal(uint64(11), s.BlockNumber)
+	r.Equal("manifest", s.Agents[testSnapshotAgentID].Manifest)
+	r.True(s.Agents[testSnapshotAgentID].Enabled)
+	r.False(s.Agents[testSnapshotAgentID2].Enabled)
+	r.Equal(testSnapshotPoolOwner, s.Scanners[testSnapshotScannerID].Owner)
+	r.Equal([]string{testSnapshotAgentID}, s.Assignments[testSnapshotScannerID])
+
+	// warm start
+	filePath := path.Join(t.TempDir(), "snapshot.json")
+	r.NoError(s.SaveFile(filePath))
+	loaded, err := registry.LoadSnapshotFile(filePath)
+	r.NoError(err)
+	r.Equal(s, loaded)
+}
+
+func testRetraction(t *testing.T, msg regmsg.Interface) *regdomain.RetractionMessage {
+	rm, err := regdomain.NewRetractionMessage(msg, 0)
+	require.NoError(t, err)
+	return rm
+}
+
+func TestSnapshot_Retraction(t *testing.T) {
+	r := require.New(t)
+
+	s := registry.NewSnapshot(10, registry.SnapshotConfig{Stakes: true})
+	link := &regdomain.DispatchMessage{Message: regmsg.Message{Action: regdomain.Link}, ScannerID: testSnapshotScannerID, AgentID: testSnapshotAgentID}
+	unlink := &regdomain.DispatchMessage{Message: regmsg.Message{Action: regdomain.Unlink}, ScannerID: testSnapshotScannerID, AgentID: testSnapshotAgentID2}
+	s.Assignments[testSnapshotScannerID] = []string{testSnapshotAgentID2}
+	r.NoError(s.Apply(link))
+	r.NoError(s.Apply(unlink))
+	r.Equal([]string{testSnapshotAgentID}, s.Assignments[testSnapshotScannerID])
+
+	// the links are undone
+	r.NoError(s.Apply(testRetraction(t, link)))
+	r.NoError(s.Apply(testRetraction(t, unlink)))
+	r.Equal([]string{testSnapshotAgentID2}, s.Assignments[testSnapshotScannerID])
+
+	// the stake is read again
+	s.AgentStakes[testSnapshotAgentID] = big.NewInt(10)
+	r.NoError(s.Apply(testRetraction(t, &regdomain.AgentStakeMessage{
+		StakeMessage: regdomain.StakeMessage{Message: regmsg.Message{Action: regdomain.AgentStake}},
+		AgentID:      testSnapshotAgentID,
+	})))
+	r.Contains(s.AgentStakes, testSnapshotAgentID)
+	r.Nil(s.AgentStakes[testSnapshotAgentID])
+	r.False(s.Outdated)
+
+	// the previous agent state is unknown
+	r.NoError(s.Apply(testRetraction(t, &regdomain.AgentSaveMessage{
+		AgentMessage: regdomain.AgentMessage{Message: regmsg.Message{Action: regdomain.SaveAgent}, AgentID: testSnapshotAgentID},
+	})))
+	r.True(s.Outdated)
+}
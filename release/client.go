@@ -1,9 +1,14 @@
 package release
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"time"
 
+	"github.com/forta-network/forta-core-go/ipfs"
 	"github.com/forta-network/forta-core-go/utils/httpclient"
 	log "github.com/sirupsen/logrus"
 )
@@ -14,44 +19,101 @@ type Client interface {
 
 type client struct {
 	prefixes []string
+	ic       ipfs.Client
+	verifier contentVerifier
 }
 
-func get(url string) (*ReleaseManifest, error) {
-	res, err := httpclient.Default.Get(url)
+// contentVerifier verifies the content obtained from somewhere else than IPFS.
+type contentVerifier interface {
+	Verify(ctx context.Context, reference string, content []byte) error
+}
+
+func get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := httpclient.Default.Do(req)
 	if err != nil {
 		return nil, err
 	}
-	var rm ReleaseManifest
 	defer res.Body.Close()
-	if err := json.NewDecoder(res.Body).Decode(&rm); err != nil {
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status code %d", url, res.StatusCode)
+	}
+	return io.ReadAll(res.Body)
+}
+
+func (c *client) GetReleaseManifest(reference string) (*ReleaseManifest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// the urls are used only if the content from them matches the cid
+	if c.verifier != nil {
+		for _, p := range c.prefixes {
+			url := fmt.Sprintf("%s/%s", p, reference)
+			res, err := c.getFromURL(ctx, url, reference)
+			if err != nil {
+				log.WithError(err).WithField("url", url).Error("error loading from url")
+				continue
+			}
+			return res, nil
+		}
+	}
+
+	// an unverified url is never tried instead, e.g. after ipfs.ErrCIDMismatch
+	res, err := c.getFromIPFS(ctx, reference)
+	if err != nil {
+		log.WithError(err).WithField("reference", reference).Error("error loading from ipfs client")
 		return nil, err
 	}
+	return res, nil
+}
 
+func (c *client) getFromURL(ctx context.Context, url, reference string) (*ReleaseManifest, error) {
+	b, err := get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verifier.Verify(ctx, reference, b); err != nil {
+		return nil, err
+	}
+	var rm ReleaseManifest
+	if err := json.Unmarshal(b, &rm); err != nil {
+		return nil, err
+	}
 	if rm.Release.Version == "" {
 		return nil, fmt.Errorf("%s: version not set", url)
 	}
-
 	return &rm, nil
 }
 
-func (c *client) GetReleaseManifest(reference string) (*ReleaseManifest, error) {
-	var resErr error
-	for _, p := range c.prefixes {
-		url := fmt.Sprintf("%s/%s", p, reference)
-		logger := log.WithFields(log.Fields{
-			"url": url,
-		})
-		res, err := get(url)
-		if err != nil {
-			logger.WithError(err).Error("error loading from url")
-			resErr = err
-			continue
-		}
-		return res, nil
+func (c *client) getFromIPFS(ctx context.Context, reference string) (*ReleaseManifest, error) {
+	var rm ReleaseManifest
+	if err := c.ic.UnmarshalJson(ctx, reference, &rm); err != nil {
+		return nil, err
+	}
+	if rm.Release.Version == "" {
+		return nil, fmt.Errorf("%s: version not set", reference)
 	}
-	return nil, resErr
+	return &rm, nil
 }
 
+// NewClient creates a client which gets the release manifests from the url prefixes or the gateway.
+// The manifests are verified against their CIDs in both cases.
 func NewClient(ipfsGateway string, urlPrefixes []string) (Client, error) {
-	return &client{prefixes: append(urlPrefixes, fmt.Sprintf("%s/ipfs", ipfsGateway))}, nil
+	ic, err := ipfs.NewClient(ipfsGateway)
+	if err != nil {
+		return nil, err
+	}
+	vc, err := ipfs.NewVerifiedClient(ic, ic)
+	if err != nil {
+		return nil, err
+	}
+	return &client{prefixes: urlPrefixes, ic: vc, verifier: vc}, nil
+}
+
+// NewClientWithIPFS creates a client which uses only given IPFS client, e.g. a cached one.
+func NewClientWithIPFS(ic ipfs.Client) (Client, error) {
+	return &client{ic: ic}, nil
 }
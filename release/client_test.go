@@ -1,9 +1,14 @@
 package release
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/forta-network/forta-core-go/ipfs"
+	mock_ipfs "github.com/forta-network/forta-core-go/ipfs/mocks"
 )
 
 func TestClient_GetReleaseManifestFromIPFS(t *testing.T) {
@@ -33,3 +38,16 @@ func TestClient_GetReleaseManifestFromErr(t *testing.T) {
 	_, err = c.GetReleaseManifest("invalid")
 	assert.Error(t, err, "error")
 }
+
+func TestClient_GetReleaseManifestCIDMismatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ic := mock_ipfs.NewMockClient(ctrl)
+	c, err := NewClientWithIPFS(ic)
+	assert.NoError(t, err)
+
+	ic.EXPECT().UnmarshalJson(gomock.Any(), "QmVDqk2pFVJ6joc8sNsNjsT1vzkjnYgz7X1dVw8eT1EgnR", gomock.Any()).
+		Return(fmt.Errorf("%w: expected a, got b", ipfs.ErrCIDMismatch))
+
+	_, err = c.GetReleaseManifest("QmVDqk2pFVJ6joc8sNsNjsT1vzkjnYgz7X1dVw8eT1EgnR")
+	assert.ErrorIs(t, err, ipfs.ErrCIDMismatch)
+}
@@ -41,12 +41,12 @@ func main() {
 	}
 
 	var receipt protocol.BatchReceipt
-	if err := encoding.DecodeGzippedProto(sr.Encoded, &receipt); err != nil {
+	if err := encoding.DecodeProto(sr.Encoded, &receipt); err != nil {
 		log.WithError(err).Fatal("cannot decode receipt")
 	}
 
 	var bs protocol.BatchSummary
-	if err := encoding.DecodeGzippedProto(receipt.BatchSummary.Encoded, &bs); err != nil {
+	if err := encoding.DecodeProto(receipt.BatchSummary.Encoded, &bs); err != nil {
 		log.WithError(err).Fatal("cannot decode receipt")
 	}
 
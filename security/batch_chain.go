@@ -0,0 +1,299 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/forta-network/forta-core-go/encoding"
+	"github.com/forta-network/forta-core-go/protocol"
+)
+
+// BatchChainIssueType is the type of an issue found in a batch chain.
+type BatchChainIssueType string
+
+// Batch chain issue types
+const (
+	BatchChainIssueInvalidPayload   BatchChainIssueType = "invalid-payload"
+	BatchChainIssueInvalidSignature BatchChainIssueType = "invalid-signature"
+	BatchChainIssueGap              BatchChainIssueType = "gap"
+	BatchChainIssueFork             BatchChainIssueType = "fork"
+	BatchChainIssueNonMonotonic     BatchChainIssueType = "non-monotonic"
+	BatchChainIssueSignerChange     BatchChainIssueType = "signer-change"
+	BatchChainIssueBatchMismatch    BatchChainIssueType = "batch-mismatch"
+)
+
+// BatchChainIssue is an issue found at the payload with the index.
+type BatchChainIssue struct {
+	Index   int
+	Type    BatchChainIssueType
+	Message string
+}
+
+func (issue *BatchChainIssue) String() string {
+	return fmt.Sprintf("payload %d: %s: %s", issue.Index, issue.Type, issue.Message)
+}
+
+// BatchChainReport is the result of the batch chain verification.
+type BatchChainReport struct {
+	Batches   int
+	Summaries int
+	Receipts  int
+	Issues    []*BatchChainIssue
+}
+
+// Valid tells if no issues were found.
+func (report *BatchChainReport) Valid() bool {
+	return len(report.Issues) == 0
+}
+
+// BatchChainConfig contains the batch chain verifier settings.
+type BatchChainConfig struct {
+	// Reference returns the reference of a payload which the summaries link to
+	// (e.g. the IPFS CID of the receipt). The default is the hash of the encoded payload.
+	Reference func(payload *protocol.SignedPayload) (string, error)
+}
+
+// batchRange is the part of a batch which its summary should agree with.
+type batchRange struct {
+	chainID    uint64
+	blockStart uint64
+	blockEnd   uint64
+	alertCount uint32
+}
+
+func (br batchRange) String() string {
+	return fmt.Sprintf("chain %d blocks %d-%d with %d alerts", br.chainID, br.blockStart, br.blockEnd, br.alertCount)
+}
+
+// batchChainVerifier walks through the submission history of a scanner and checks the links
+// between the batches, the batch summaries and the batch receipts.
+type batchChainVerifier struct {
+	cfg    BatchChainConfig
+	report BatchChainReport
+	index  int
+
+	scannerSigner  string
+	receiptSigner  string
+	summaries      map[string]bool
+	receipts       map[string]bool
+	linkedReceipts map[string]bool
+	lastBlockEnd   map[uint64]uint64
+	batches        map[string]batchRange
+	summaryBatches map[string]batchRange
+}
+
+// NewBatchChainVerifier creates a new verifier which expects the payloads in the submission order.
+func NewBatchChainVerifier(cfg BatchChainConfig) *batchChainVerifier {
+	if cfg.Reference == nil {
+		cfg.Reference = hashReference
+	}
+	return &batchChainVerifier{
+		cfg:            cfg,
+		summaries:      make(map[string]bool),
+		receipts:       make(map[string]bool),
+		linkedReceipts: make(map[string]bool),
+		lastBlockEnd:   make(map[uint64]uint64),
+		batches:        make(map[string]batchRange),
+		summaryBatches: make(map[string]batchRange),
+	}
+}
+
+// VerifyBatchChain verifies the payloads in given order.
+func VerifyBatchChain(cfg BatchChainConfig, payloads ...*protocol.SignedPayload) *BatchChainReport {
+	verifier := NewBatchChainVerifier(cfg)
+	for _, payload := range payloads {
+		verifier.Add(payload)
+	}
+	return verifier.Report()
+}
+
+func hashReference(payload *protocol.SignedPayload) (string, error) {
+	return crypto.Keccak256Hash([]byte(payload.Encoded)).Hex(), nil
+}
+
+// Report returns the result of the verification so far.
+func (v *batchChainVerifier) Report() *BatchChainReport {
+	report := v.report
+	report.Issues = append([]*BatchChainIssue(nil), v.report.Issues...)
+	return &report
+}
+
+// Add verifies the next payload in the chain.
+func (v *batchChainVerifier) Add(payload *protocol.SignedPayload) {
+	index := v.index
+	v.index++
+
+	if payload == nil {
+		v.addIssue(index, BatchChainIssueInvalidPayload, "nil payload")
+		return
+	}
+	if err := VerifySignedPayload(payload); err != nil {
+		v.addIssue(index, BatchChainIssueInvalidSignature, err.Error())
+		return
+	}
+
+	switch payload.Type {
+	case protocol.SignedPayload_BATCH:
+		v.addBatch(index, payload)
+
+	case protocol.SignedPayload_BATCH_SUMMARY:
+		v.addSummary(index, payload)
+
+	case protocol.SignedPayload_BATCH_RECEIPT:
+		v.addReceipt(index, payload)
+
+	default:
+		v.addIssue(index, BatchChainIssueInvalidPayload, fmt.Sprintf("unknown payload type %d", payload.Type))
+	}
+}
+
+func (v *batchChainVerifier) addBatch(index int, payload *protocol.SignedPayload) {
+	var batch protocol.AlertBatch
+	if err := encoding.DecodeProto(payload.Encoded, &batch); err != nil {
+		v.addIssue(index, BatchChainIssueInvalidPayload, fmt.Sprintf("failed to decode batch: %v", err))
+		return
+	}
+	ref, ok := v.reference(index, payload)
+	if !ok {
+		return
+	}
+	v.report.Batches++
+	v.checkSigner(index, &v.scannerSigner, "scanner", payload)
+
+	br := batchRange{
+		chainID:    batch.ChainId,
+		blockStart: batch.BlockStart,
+		blockEnd:   batch.BlockEnd,
+		alertCount: batch.AlertCount,
+	}
+	v.batches[ref] = br
+	if summarized, ok := v.summaryBatches[ref]; ok {
+		v.checkBatch(index, ref, br, summarized)
+	}
+}
+
+func (v *batchChainVerifier) addSummary(index int, payload *protocol.SignedPayload) {
+	// the summaries are seen both alone and inside the receipts
+	if v.summaries[payload.Signature.Signature] {
+		return
+	}
+	v.summaries[payload.Signature.Signature] = true
+
+	var summary protocol.BatchSummary
+	if err := encoding.DecodeProto(payload.Encoded, &summary); err != nil {
+		v.addIssue(index, BatchChainIssueInvalidPayload, fmt.Sprintf("failed to decode batch summary: %v", err))
+		return
+	}
+	v.report.Summaries++
+	v.checkSigner(index, &v.scannerSigner, "scanner", payload)
+
+	// check the block range
+	if summary.BlockEnd < summary.BlockStart {
+		v.addIssue(index, BatchChainIssueNonMonotonic, fmt.Sprintf(
+			"block end %d is before block start %d", summary.BlockEnd, summary.BlockStart,
+		))
+	}
+	lastBlockEnd, ok := v.lastBlockEnd[summary.ChainId]
+	if ok && summary.BlockStart <= lastBlockEnd {
+		v.addIssue(index, BatchChainIssueNonMonotonic, fmt.Sprintf(
+			"block range %d-%d does not start after block %d on chain %d",
+			summary.BlockStart, summary.BlockEnd, lastBlockEnd, summary.ChainId,
+		))
+	}
+	if !ok || summary.BlockEnd > lastBlockEnd {
+		v.lastBlockEnd[summary.ChainId] = summary.BlockEnd
+	}
+
+	// check the link to the previous receipt: the first summary can link to something we haven't seen
+	prev := summary.PreviousReceipt
+	first := v.report.Summaries == 1
+	switch {
+	case prev == "" && !first:
+		v.addIssue(index, BatchChainIssueGap, "batch summary does not link to a previous receipt")
+	case prev != "" && !first && !v.receipts[prev]:
+		v.addIssue(index, BatchChainIssueGap, fmt.Sprintf("batch summary links to unknown receipt %s", prev))
+	}
+	if prev != "" {
+		if v.linkedReceipts[prev] {
+			v.addIssue(index, BatchChainIssueFork, fmt.Sprintf("receipt %s is linked by more than one batch summary", prev))
+		}
+		v.linkedReceipts[prev] = true
+	}
+
+	// check the batch if it's seen
+	if summary.Batch != "" {
+		br := batchRange{
+			chainID:    summary.ChainId,
+			blockStart: summary.BlockStart,
+			blockEnd:   summary.BlockEnd,
+			alertCount: summary.AlertCount,
+		}
+		v.summaryBatches[summary.Batch] = br
+		if batch, ok := v.batches[summary.Batch]; ok {
+			v.checkBatch(index, summary.Batch, batch, br)
+		}
+	}
+}
+
+func (v *batchChainVerifier) addReceipt(index int, payload *protocol.SignedPayload) {
+	var receipt protocol.BatchReceipt
+	if err := encoding.DecodeProto(payload.Encoded, &receipt); err != nil {
+		v.addIssue(index, BatchChainIssueInvalidPayload, fmt.Sprintf("failed to decode batch receipt: %v", err))
+		return
+	}
+	if receipt.BatchSummary == nil {
+		v.addIssue(index, BatchChainIssueInvalidPayload, "batch receipt does not contain a batch summary")
+		return
+	}
+	if err := VerifySignedPayload(receipt.BatchSummary); err != nil {
+		v.addIssue(index, BatchChainIssueInvalidSignature, fmt.Sprintf("batch summary in receipt: %v", err))
+		return
+	}
+	ref, ok := v.reference(index, payload)
+	if !ok {
+		return
+	}
+	v.report.Receipts++
+	v.checkSigner(index, &v.receiptSigner, "receipt", payload)
+
+	// the summary is added before the receipt so it can't link to its own receipt
+	v.addSummary(index, receipt.BatchSummary)
+	v.receipts[ref] = true
+}
+
+func (v *batchChainVerifier) reference(index int, payload *protocol.SignedPayload) (string, bool) {
+	ref, err := v.cfg.Reference(payload)
+	if err != nil {
+		v.addIssue(index, BatchChainIssueInvalidPayload, fmt.Sprintf("failed to get reference: %v", err))
+		return "", false
+	}
+	return ref, true
+}
+
+func (v *batchChainVerifier) checkSigner(index int, lastSigner *string, role string, payload *protocol.SignedPayload) {
+	signer := strings.ToLower(payload.Signature.Signer)
+	if *lastSigner != "" && *lastSigner != signer {
+		v.addIssue(index, BatchChainIssueSignerChange, fmt.Sprintf(
+			"%s signer changed from %s to %s", role, *lastSigner, signer,
+		))
+	}
+	*lastSigner = signer
+}
+
+func (v *batchChainVerifier) checkBatch(index int, ref string, batch, summarized batchRange) {
+	if batch != summarized {
+		v.addIssue(index, BatchChainIssueBatchMismatch, fmt.Sprintf(
+			"batch %s has %s but the summary has %s", ref, batch, summarized,
+		))
+	}
+}
+
+func (v *batchChainVerifier) addIssue(index int, issueType BatchChainIssueType, msg string) {
+	v.report.Issues = append(v.report.Issues, &BatchChainIssue{
+		Index:   index,
+		Type:    issueType,
+		Message: msg,
+	})
+}
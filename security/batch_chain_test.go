@@ -0,0 +1,131 @@
+package security
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-core-go/protocol"
+)
+
+type testBatchChain struct {
+	t        *testing.T
+	scanner  *keystore.Key
+	analyzer *keystore.Key
+}
+
+func newTestBatchChain(t *testing.T) *testBatchChain {
+	scanner, err := LoadKeyWithPassphrase("testkey", "Forta123")
+	require.NoError(t, err)
+	return &testBatchChain{t: t, scanner: scanner, analyzer: newTestKey(t)}
+}
+
+func newTestKey(t *testing.T) *keystore.Key {
+	pk, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	return &keystore.Key{Address: crypto.PubkeyToAddress(pk.PublicKey), PrivateKey: pk}
+}
+
+func (tbc *testBatchChain) summary(key *keystore.Key, prev *protocol.SignedPayload, start, end uint64) *protocol.SignedPayload {
+	summary := &protocol.BatchSummary{ChainId: 1, BlockStart: start, BlockEnd: end}
+	if prev != nil {
+		summary.PreviousReceipt, _ = hashReference(prev)
+	}
+	signed, err := SignBatchSummary(key, summary)
+	require.NoError(tbc.t, err)
+	return signed
+}
+
+func (tbc *testBatchChain) receipt(summary *protocol.SignedPayload) *protocol.SignedPayload {
+	signed, err := SignBatchReceipt(tbc.analyzer, &protocol.BatchReceipt{BatchSummary: summary})
+	require.NoError(tbc.t, err)
+	return signed
+}
+
+func issueTypes(report *BatchChainReport) (types []BatchChainIssueType) {
+	for _, issue := range report.Issues {
+		types = append(types, issue.Type)
+	}
+	return
+}
+
+func TestVerifyBatchChain(t *testing.T) {
+	r := require.New(t)
+	tbc := newTestBatchChain(t)
+
+	summary1 := tbc.summary(tbc.scanner, nil, 1, 10)
+	receipt1 := tbc.receipt(summary1)
+	summary2 := tbc.summary(tbc.scanner, receipt1, 11, 20)
+	receipt2 := tbc.receipt(summary2)
+	summary3 := tbc.summary(tbc.scanner, receipt2, 21, 30)
+
+	report := VerifyBatchChain(BatchChainConfig{}, summary1, receipt1, summary2, receipt2, summary3)
+	r.True(report.Valid(), report.Issues)
+	r.Equal(3, report.Summaries)
+	r.Equal(2, report.Receipts)
+
+	// receipts are enough to follow the chain
+	report = VerifyBatchChain(BatchChainConfig{}, receipt1, receipt2)
+	r.True(report.Valid(), report.Issues)
+	r.Equal(2, report.Summaries)
+}
+
+func TestVerifyBatchChain_Issues(t *testing.T) {
+	r := require.New(t)
+	tbc := newTestBatchChain(t)
+
+	summary1 := tbc.summary(tbc.scanner, nil, 1, 10)
+	receipt1 := tbc.receipt(summary1)
+	summary2 := tbc.summary(tbc.scanner, receipt1, 11, 20)
+	receipt2 := tbc.receipt(summary2)
+
+	// gap: the second receipt is missing
+	summary3 := tbc.summary(tbc.scanner, receipt2, 21, 30)
+	report := VerifyBatchChain(BatchChainConfig{}, receipt1, summary3)
+	r.Equal([]BatchChainIssueType{BatchChainIssueGap}, issueTypes(report))
+
+	// fork: two summaries after the same receipt
+	forked := tbc.summary(tbc.scanner, receipt1, 21, 25)
+	report = VerifyBatchChain(BatchChainConfig{}, receipt1, receipt2, forked)
+	r.Equal([]BatchChainIssueType{BatchChainIssueFork}, issueTypes(report))
+
+	// non-monotonic: goes back in blocks
+	backwards := tbc.summary(tbc.scanner, receipt2, 5, 30)
+	report = VerifyBatchChain(BatchChainConfig{}, receipt1, receipt2, backwards)
+	r.Equal([]BatchChainIssueType{BatchChainIssueNonMonotonic}, issueTypes(report))
+
+	// non-monotonic: starts at the last block of the previous summary
+	overlapping := tbc.summary(tbc.scanner, receipt2, 20, 30)
+	report = VerifyBatchChain(BatchChainConfig{}, receipt1, receipt2, overlapping)
+	r.Equal([]BatchChainIssueType{BatchChainIssueNonMonotonic}, issueTypes(report))
+
+	// signer change: signed by another scanner
+	otherSigner := tbc.summary(newTestKey(t), receipt2, 21, 30)
+	report = VerifyBatchChain(BatchChainConfig{}, receipt1, receipt2, otherSigner)
+	r.Equal([]BatchChainIssueType{BatchChainIssueSignerChange}, issueTypes(report))
+
+	// invalid signature
+	tampered := tbc.summary(tbc.scanner, receipt2, 21, 30)
+	tampered.Encoded = summary1.Encoded
+	report = VerifyBatchChain(BatchChainConfig{}, receipt1, receipt2, tampered)
+	r.Equal([]BatchChainIssueType{BatchChainIssueInvalidSignature}, issueTypes(report))
+}
+
+func TestVerifyBatchChain_BatchMismatch(t *testing.T) {
+	r := require.New(t)
+	tbc := newTestBatchChain(t)
+
+	batch, err := SignBatch(tbc.scanner, &protocol.AlertBatch{ChainId: 1, BlockStart: 1, BlockEnd: 9})
+	r.NoError(err)
+	batchRef, _ := hashReference(batch)
+	summary, err := SignBatchSummary(tbc.scanner, &protocol.BatchSummary{
+		Batch: batchRef, ChainId: 1, BlockStart: 1, BlockEnd: 10,
+	})
+	r.NoError(err)
+
+	report := VerifyBatchChain(BatchChainConfig{}, batch, summary)
+	r.Equal([]BatchChainIssueType{BatchChainIssueBatchMismatch}, issueTypes(report))
+	r.Equal(1, report.Batches)
+}
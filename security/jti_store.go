@@ -0,0 +1,55 @@
+package security
+
+import (
+	"sync"
+	"time"
+)
+
+const jtiStorePruneInterval = time.Minute
+
+// JTIStore keeps the seen JWT IDs to reject the replayed tokens.
+type JTIStore interface {
+	// Seen stores the JWT ID until it expires and tells if it was stored before.
+	Seen(jti string, expiresAt time.Time) (bool, error)
+}
+
+// memoryJTIStore keeps the JWT IDs in memory until they expire.
+type memoryJTIStore struct {
+	ids       map[string]time.Time
+	lastPrune time.Time
+	mu        sync.Mutex
+}
+
+// NewMemoryJTIStore creates a new in-memory JWT ID store.
+func NewMemoryJTIStore() *memoryJTIStore {
+	return &memoryJTIStore{
+		ids:       make(map[string]time.Time),
+		lastPrune: time.Now(),
+	}
+}
+
+// Seen implements the JTIStore interface.
+func (store *memoryJTIStore) Seen(jti string, expiresAt time.Time) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(store.lastPrune) > jtiStorePruneInterval {
+		store.prune(now)
+	}
+
+	if exp, ok := store.ids[jti]; ok && now.Before(exp) {
+		return true, nil
+	}
+	store.ids[jti] = expiresAt
+	return false, nil
+}
+
+func (store *memoryJTIStore) prune(now time.Time) {
+	for jti, exp := range store.ids {
+		if !now.Before(exp) {
+			delete(store.ids, jti)
+		}
+	}
+	store.lastPrune = now
+}
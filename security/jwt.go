@@ -1,10 +1,14 @@
 package security
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -52,9 +56,21 @@ func (e ethSigningMethod) Verify(signingString, signature string, key interface{
 }
 
 func (e ethSigningMethod) Sign(signingString string, key interface{}) (string, error) {
-	pk := key.(*ecdsa.PrivateKey)
 	hash := crypto.Keccak256([]byte(signingString))
-	sig, err := crypto.Sign(hash, pk)
+	var (
+		sig []byte
+		err error
+	)
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		sig, err = crypto.Sign(hash, k)
+	case *contextSigner:
+		sig, err = k.SignHash(k.ctx, hash)
+	case Signer:
+		sig, err = k.SignHash(context.Background(), hash)
+	default:
+		return "", jwt.ErrInvalidKeyType
+	}
 	if err != nil {
 		return "", err
 	}
@@ -79,8 +95,43 @@ func parseSub(token *jwt.Token) (string, error) {
 	return "", errors.New("invalid claims")
 }
 
+// Scanner JWT verification errors
+var (
+	ErrTokenExpired     = errors.New("token is expired")
+	ErrTokenNotValidYet = errors.New("token is not valid yet")
+	ErrTokenAudience    = errors.New("token audience is not accepted")
+	ErrTokenScope       = errors.New("token does not have the required scope")
+	ErrTokenClaim       = errors.New("token does not have the required claim")
+	ErrTokenReplayed    = errors.New("token is already used")
+)
+
+const scopeClaim = "scope"
+
+// ScannerJWTVerifyOptions contains the extra checks of the scanner JWT verification.
+type ScannerJWTVerifyOptions struct {
+	// Audience is the audience which the "aud" claim must contain, if not empty.
+	Audience string
+	// Scopes are the scopes which the space-separated "scope" claim must contain.
+	Scopes []string
+	// RequiredClaims are the claims which must exist in the token. If the value of a claim is
+	// not nil, the JSON-decoded claim value (e.g. float64 for numbers) must be equal to it.
+	RequiredClaims map[string]interface{}
+	// ClockSkew is tolerated while checking the "exp", "nbf" and "iat" claims.
+	ClockSkew time.Duration
+	// ReplayStore rejects the tokens which have a "jti" claim that is seen before, if not nil.
+	// The tokens must have the "jti" and "exp" claims when it is used.
+	ReplayStore JTIStore
+}
+
 func VerifyScannerJWT(tokenString string) (*ScannerToken, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+	return VerifyScannerJWTWithOptions(tokenString, ScannerJWTVerifyOptions{})
+}
+
+// VerifyScannerJWTWithOptions verifies the scanner JWT signature and claims with the options.
+func VerifyScannerJWTWithOptions(tokenString string, opts ScannerJWTVerifyOptions) (*ScannerToken, error) {
+	// the time claims are checked below by tolerating the clock skew
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	token, err := parser.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(ethSigningMethod); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
@@ -100,27 +151,141 @@ func VerifyScannerJWT(tokenString string) (*ScannerToken, error) {
 		return nil, err
 	}
 
+	if err := verifyScannerClaims(token.Claims.(jwt.MapClaims), opts); err != nil {
+		return nil, err
+	}
+
 	return &ScannerToken{
 		Scanner: sub,
 		Token:   token,
 	}, nil
 }
 
+func verifyScannerClaims(claims jwt.MapClaims, opts ScannerJWTVerifyOptions) error {
+	now := time.Now()
+	if !claims.VerifyExpiresAt(now.Add(-opts.ClockSkew).Unix(), opts.ReplayStore != nil) {
+		return ErrTokenExpired
+	}
+	if !claims.VerifyNotBefore(now.Add(opts.ClockSkew).Unix(), false) {
+		return ErrTokenNotValidYet
+	}
+	if !claims.VerifyIssuedAt(now.Add(opts.ClockSkew).Unix(), false) {
+		return fmt.Errorf("%w: issued in the future", ErrTokenNotValidYet)
+	}
+
+	if opts.Audience != "" && !claims.VerifyAudience(opts.Audience, true) {
+		return fmt.Errorf("%w: expected %s", ErrTokenAudience, opts.Audience)
+	}
+
+	if len(opts.Scopes) > 0 {
+		scopeStr, _ := claims[scopeClaim].(string)
+		scopes := make(map[string]bool)
+		for _, scope := range strings.Fields(scopeStr) {
+			scopes[scope] = true
+		}
+		for _, scope := range opts.Scopes {
+			if !scopes[scope] {
+				return fmt.Errorf("%w: %s", ErrTokenScope, scope)
+			}
+		}
+	}
+
+	for name, expected := range opts.RequiredClaims {
+		value, ok := claims[name]
+		if !ok || (expected != nil && !reflect.DeepEqual(value, expected)) {
+			return fmt.Errorf("%w: %s", ErrTokenClaim, name)
+		}
+	}
+
+	if opts.ReplayStore != nil {
+		jti, _ := claims["jti"].(string)
+		if jti == "" {
+			return fmt.Errorf("%w: jti", ErrTokenClaim)
+		}
+		exp, err := claimTime(claims, "exp")
+		if err != nil {
+			return err
+		}
+		seen, err := opts.ReplayStore.Seen(jti, exp.Add(opts.ClockSkew))
+		if err != nil {
+			return fmt.Errorf("failed to check jti: %v", err)
+		}
+		if seen {
+			return ErrTokenReplayed
+		}
+	}
+
+	return nil
+}
+
+func claimTime(claims jwt.MapClaims, name string) (time.Time, error) {
+	switch v := claims[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), nil
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s claim: %v", name, err)
+		}
+		return time.Unix(n, 0), nil
+	default:
+		return time.Time{}, fmt.Errorf("%w: %s", ErrTokenClaim, name)
+	}
+}
+
 func CreateScannerJWT(key *keystore.Key, claims map[string]interface{}) (string, error) {
+	return CreateScannerJWTWithSigner(context.Background(), NewKeySigner(key), claims)
+}
+
+// ScannerJWTOptions contains the settings of a new scanner JWT.
+type ScannerJWTOptions struct {
+	// Audience is set as the "aud" claim, if not empty.
+	Audience string
+	// Scopes are set as the space-separated "scope" claim, if not empty.
+	Scopes []string
+	// TTL is the duration which the token is valid for. The default is 30 seconds.
+	TTL time.Duration
+}
+
+const defaultScannerJWTTTL = 30 * time.Second
+
+// CreateScannerJWTWithSigner creates a scanner JWT which is signed by the signer.
+func CreateScannerJWTWithSigner(ctx context.Context, signer Signer, claims map[string]interface{}) (string, error) {
+	return CreateScannerJWTWithOptions(ctx, signer, ScannerJWTOptions{}, claims)
+}
+
+// contextSigner passes the context of the token to the signer through the jwt library.
+type contextSigner struct {
+	Signer
+	ctx context.Context
+}
+
+// CreateScannerJWTWithOptions creates a scanner JWT which is signed by the signer and is
+// bound to the audience and the scopes from the options.
+func CreateScannerJWTWithOptions(ctx context.Context, signer Signer, opts ScannerJWTOptions, claims map[string]interface{}) (string, error) {
+	if opts.TTL == 0 {
+		opts.TTL = defaultScannerJWTTTL
+	}
 	u := uuid.Must(uuid.NewUUID())
 	now := time.Now().UTC()
 	mapClaims := map[string]interface{}{
 		"jti": u.String(),
-		"sub": key.Address.Hex(),
+		"sub": signer.Address().Hex(),
 		"iat": now.Unix(),
 		"nbf": now.Add(-30 * time.Second).Unix(),
-		"exp": now.Add(30 * time.Second).Unix(),
+		"exp": now.Add(opts.TTL).Unix(),
+	}
+	if opts.Audience != "" {
+		mapClaims["aud"] = opts.Audience
+	}
+	if len(opts.Scopes) > 0 {
+		mapClaims[scopeClaim] = strings.Join(opts.Scopes, " ")
 	}
 	for k, v := range claims {
 		mapClaims[k] = v
 	}
 	token := jwt.NewWithClaims(&ethSigningMethod{}, jwt.MapClaims(mapClaims))
-	str, err := token.SignedString(key.PrivateKey)
+	str, err := token.SignedString(&contextSigner{Signer: signer, ctx: ctx})
 	if err != nil {
 		return "", err
 	}
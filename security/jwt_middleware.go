@@ -0,0 +1,46 @@
+package security
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-core-go/utils/apiutils"
+)
+
+// NewScannerJWTMiddleware creates a middleware which verifies the scanner JWT from the
+// "Authorization: Bearer <token>" header and puts the scanner address into the request
+// context by using apiutils.SetAddress.
+func NewScannerJWTMiddleware(opts ScannerJWTVerifyOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString, ok := bearerToken(r)
+			if !ok {
+				apiutils.Unauthorized(w, "missing bearer token")
+				return
+			}
+			scannerToken, err := VerifyScannerJWTWithOptions(tokenString, opts)
+			if errors.Is(err, ErrTokenScope) {
+				apiutils.Forbidden(w, "insufficient scope")
+				return
+			}
+			if err != nil {
+				log.WithError(err).Debug("scanner jwt verification failed")
+				apiutils.Unauthorized(w, "invalid token")
+				return
+			}
+			ctx := apiutils.SetAddress(r.Context(), scannerToken.Scanner)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || parts[1] == "" {
+		return "", false
+	}
+	return strings.TrimSpace(parts[1]), true
+}
@@ -1,10 +1,17 @@
 package security
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-core-go/utils/apiutils"
 )
 
 func TestCreateJWT(t *testing.T) {
@@ -30,3 +37,88 @@ func TestCreateJWT(t *testing.T) {
 
 	assert.Equal(t, address, validToken.Scanner)
 }
+
+func TestVerifyScannerJWTWithOptions(t *testing.T) {
+	r := require.New(t)
+
+	key, err := LoadKeyWithPassphrase("testkey", "Forta123")
+	r.NoError(err)
+	signer := NewKeySigner(key)
+
+	token, err := CreateScannerJWTWithOptions(context.Background(), signer, ScannerJWTOptions{
+		Audience: "alerts-api",
+		Scopes:   []string{"alerts:write", "batches:write"},
+	}, map[string]interface{}{"batch": "batch-ref"})
+	r.NoError(err)
+
+	opts := ScannerJWTVerifyOptions{
+		Audience:       "alerts-api",
+		Scopes:         []string{"alerts:write"},
+		RequiredClaims: map[string]interface{}{"batch": "batch-ref"},
+		ReplayStore:    NewMemoryJTIStore(),
+	}
+	validToken, err := VerifyScannerJWTWithOptions(token, opts)
+	r.NoError(err)
+	r.Equal(key.Address.Hex(), validToken.Scanner)
+
+	// replayed
+	_, err = VerifyScannerJWTWithOptions(token, opts)
+	r.ErrorIs(err, ErrTokenReplayed)
+
+	_, err = VerifyScannerJWTWithOptions(token, ScannerJWTVerifyOptions{Audience: "other-api"})
+	r.ErrorIs(err, ErrTokenAudience)
+	_, err = VerifyScannerJWTWithOptions(token, ScannerJWTVerifyOptions{Scopes: []string{"admin"}})
+	r.ErrorIs(err, ErrTokenScope)
+	_, err = VerifyScannerJWTWithOptions(token, ScannerJWTVerifyOptions{
+		RequiredClaims: map[string]interface{}{"batch": "other-ref"},
+	})
+	r.ErrorIs(err, ErrTokenClaim)
+}
+
+func TestVerifyScannerJWTWithOptions_ClockSkew(t *testing.T) {
+	r := require.New(t)
+
+	key, err := LoadKeyWithPassphrase("testkey", "Forta123")
+	r.NoError(err)
+
+	token, err := CreateScannerJWT(key, map[string]interface{}{
+		"exp": time.Now().Add(-time.Second * 5).Unix(),
+	})
+	r.NoError(err)
+
+	_, err = VerifyScannerJWTWithOptions(token, ScannerJWTVerifyOptions{})
+	r.ErrorIs(err, ErrTokenExpired)
+	_, err = VerifyScannerJWTWithOptions(token, ScannerJWTVerifyOptions{ClockSkew: time.Minute})
+	r.NoError(err)
+}
+
+func TestScannerJWTMiddleware(t *testing.T) {
+	r := require.New(t)
+
+	key, err := LoadKeyWithPassphrase("testkey", "Forta123")
+	r.NoError(err)
+	token, err := CreateScannerJWT(key, nil)
+	r.NoError(err)
+
+	var address string
+	handler := NewScannerJWTMiddleware(ScannerJWTVerifyOptions{ReplayStore: NewMemoryJTIStore()})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			address = apiutils.GetAddress(r.Context())
+		}),
+	)
+
+	serve := func(authHeader string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if authHeader != "" {
+			req.Header.Set("Authorization", authHeader)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	r.Equal(http.StatusUnauthorized, serve(""))
+	r.Equal(http.StatusOK, serve("Bearer "+token))
+	r.Equal(key.Address.Hex(), address)
+	r.Equal(http.StatusUnauthorized, serve("Bearer "+token))
+}
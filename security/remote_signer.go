@@ -0,0 +1,210 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/forta-network/forta-core-go/utils/httpclient"
+)
+
+// Remote signer protocol:
+//
+//	GET  /address  -> {"address": "0x..."}
+//	POST /sign     {"address": "0x...", "hash": "0x..."} -> {"signature": "0x..."}
+//
+// The signature is in the [R || S || V] format where V is 0 or 1. The errors are
+// responded with a non-200 status and {"error": "..."}.
+const (
+	remoteSignerAddressPath = "/address"
+	remoteSignerSignPath    = "/sign"
+
+	defaultRemoteSignerTimeout = 10 * time.Second
+)
+
+// RemoteSignerAddressResponse is the response of the address endpoint.
+type RemoteSignerAddressResponse struct {
+	Address string `json:"address"`
+}
+
+// RemoteSignerSignRequest is the request of the sign endpoint.
+type RemoteSignerSignRequest struct {
+	Address string `json:"address"`
+	Hash    string `json:"hash"`
+}
+
+// RemoteSignerSignResponse is the response of the sign endpoint.
+type RemoteSignerSignResponse struct {
+	Signature string `json:"signature"`
+}
+
+// RemoteSignerErrorResponse is the error response of the endpoints.
+type RemoteSignerErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// RemoteSignerConfig contains the remote signer settings.
+type RemoteSignerConfig struct {
+	URL       string
+	AuthToken string
+	Timeout   time.Duration
+}
+
+// remoteSigner requests the signatures from an external signer over HTTP.
+type remoteSigner struct {
+	cfg     RemoteSignerConfig
+	address common.Address
+}
+
+// NewRemoteSigner creates a signer which gets the signatures from a remote signer.
+func NewRemoteSigner(ctx context.Context, cfg RemoteSignerConfig) (*remoteSigner, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("remote signer url is not set")
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	// every request is bounded even if the caller's context is not
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultRemoteSignerTimeout
+	}
+	rs := &remoteSigner{cfg: cfg}
+
+	var resp RemoteSignerAddressResponse
+	if err := rs.do(ctx, http.MethodGet, remoteSignerAddressPath, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get the remote signer address: %v", err)
+	}
+	if !common.IsHexAddress(resp.Address) {
+		return nil, fmt.Errorf("invalid remote signer address: %s", resp.Address)
+	}
+	rs.address = common.HexToAddress(resp.Address)
+	return rs, nil
+}
+
+// Address implements the Signer interface.
+func (rs *remoteSigner) Address() common.Address {
+	return rs.address
+}
+
+// SignHash implements the Signer interface.
+func (rs *remoteSigner) SignHash(ctx context.Context, hash []byte) ([]byte, error) {
+	var resp RemoteSignerSignResponse
+	err := rs.do(ctx, http.MethodPost, remoteSignerSignPath, &RemoteSignerSignRequest{
+		Address: rs.address.Hex(),
+		Hash:    hexutil.Encode(hash),
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with the remote signer: %v", err)
+	}
+	sig, err := hexutil.Decode(resp.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the remote signature: %v", err)
+	}
+	// never trust a signature which does not belong to the signer
+	if err := verifyHashSignature(hash, sig, rs.address); err != nil {
+		return nil, fmt.Errorf("failed to verify the remote signature: %w", err)
+	}
+	return sig, nil
+}
+
+func (rs *remoteSigner) do(ctx context.Context, method, path string, reqBody, respBody interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, rs.cfg.Timeout)
+	defer cancel()
+
+	var body bytes.Buffer
+	if reqBody != nil {
+		if err := json.NewEncoder(&body).Encode(reqBody); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rs.cfg.URL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if rs.cfg.AuthToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", rs.cfg.AuthToken))
+	}
+
+	resp, err := httpclient.Default.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp RemoteSignerErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("remote signer responded with %d: %s", resp.StatusCode, errResp.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(respBody)
+}
+
+// NewRemoteSignerHandler creates an HTTP handler which serves the remote signer protocol
+// by using given signer. If the auth token is not empty, the requests must include it.
+func NewRemoteSignerHandler(signer Signer, authToken string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(remoteSignerAddressPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeRemoteSignerError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		writeRemoteSignerResponse(w, &RemoteSignerAddressResponse{Address: signer.Address().Hex()})
+	})
+	mux.HandleFunc(remoteSignerSignPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeRemoteSignerError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		var req RemoteSignerSignRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeRemoteSignerError(w, http.StatusBadRequest, err)
+			return
+		}
+		if !strings.EqualFold(req.Address, signer.Address().Hex()) {
+			writeRemoteSignerError(w, http.StatusBadRequest, fmt.Errorf("unknown address: %s", req.Address))
+			return
+		}
+		hash, err := hexutil.Decode(req.Hash)
+		if err != nil || len(hash) != common.HashLength {
+			writeRemoteSignerError(w, http.StatusBadRequest, errors.New("hash must be 32 bytes"))
+			return
+		}
+		sig, err := signer.SignHash(r.Context(), hash)
+		if err != nil {
+			writeRemoteSignerError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeRemoteSignerResponse(w, &RemoteSignerSignResponse{Signature: hexutil.Encode(sig)})
+	})
+
+	if authToken == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expected := []byte(fmt.Sprintf("Bearer %s", authToken))
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			writeRemoteSignerError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeRemoteSignerResponse(w http.ResponseWriter, resp interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func writeRemoteSignerError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(&RemoteSignerErrorResponse{Error: err.Error()})
+}
@@ -0,0 +1,77 @@
+package security
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signer signs hashes with a private key which does not need to be in the process memory.
+type Signer interface {
+	// Address returns the address of the signer key.
+	Address() common.Address
+	// SignHash signs a 32-byte hash and returns the signature in the [R || S || V] format
+	// where V is 0 or 1. The signers which sign remotely give up when the context is done.
+	SignHash(ctx context.Context, hash []byte) ([]byte, error)
+}
+
+// keySigner signs with a decrypted keystore key.
+type keySigner struct {
+	key *keystore.Key
+}
+
+// NewKeySigner creates a signer which signs with given keystore key.
+func NewKeySigner(key *keystore.Key) Signer {
+	return &keySigner{key: key}
+}
+
+// Address implements the Signer interface.
+func (ks *keySigner) Address() common.Address {
+	return ks.key.Address
+}
+
+// SignHash implements the Signer interface.
+func (ks *keySigner) SignHash(ctx context.Context, hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, ks.key.PrivateKey)
+}
+
+// verifyHashSignature makes sure that the signature of the hash belongs to the address.
+func verifyHashSignature(hash, sig []byte, address common.Address) error {
+	pubKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return err
+	}
+	if crypto.PubkeyToAddress(*pubKey) != address {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// NewTransactOptsWithSigner creates new opts which sign the transactions with the signer.
+// The transactions are signed for given chain ID, or with the Homestead signer if it is nil.
+// The signing is given up when the context is done.
+func NewTransactOptsWithSigner(ctx context.Context, signer Signer, chainID *big.Int) *bind.TransactOpts {
+	var txSigner types.Signer = types.HomesteadSigner{}
+	if chainID != nil {
+		txSigner = types.LatestSignerForChainID(chainID)
+	}
+	return &bind.TransactOpts{
+		From:    signer.Address(),
+		Context: ctx,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != signer.Address() {
+				return nil, bind.ErrNotAuthorized
+			}
+			sig, err := signer.SignHash(ctx, txSigner.Hash(tx).Bytes())
+			if err != nil {
+				return nil, err
+			}
+			return tx.WithSignature(txSigner, sig)
+		},
+	}
+}
@@ -0,0 +1,65 @@
+package security
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteSigner(t *testing.T) {
+	r := require.New(t)
+
+	key, err := LoadKeyWithPassphrase("testkey", "Forta123")
+	r.NoError(err)
+	srv := httptest.NewServer(NewRemoteSignerHandler(NewKeySigner(key), "token"))
+	defer srv.Close()
+
+	ctx := context.Background()
+	_, err = NewRemoteSigner(ctx, RemoteSignerConfig{URL: srv.URL})
+	r.Error(err)
+
+	rs, err := NewRemoteSigner(ctx, RemoteSignerConfig{URL: srv.URL, AuthToken: "token"})
+	r.NoError(err)
+	r.Equal(signer, rs.Address().Hex())
+
+	res, err := SignStringWithSigner(ctx, rs, ref)
+	r.NoError(err)
+	r.Equal(signature, res.Signature)
+	r.Equal(signer, res.Signer)
+
+	token, err := CreateScannerJWTWithSigner(ctx, rs, nil)
+	r.NoError(err)
+	validToken, err := VerifyScannerJWT(token)
+	r.NoError(err)
+	r.Equal(signer, validToken.Scanner)
+}
+
+// forgingSigner reports an address but signs with another key.
+type forgingSigner struct {
+	Signer
+	key *ecdsa.PrivateKey
+}
+
+func (fs *forgingSigner) SignHash(ctx context.Context, hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, fs.key)
+}
+
+func TestRemoteSigner_WrongSignature(t *testing.T) {
+	r := require.New(t)
+
+	key, err := LoadKeyWithPassphrase("testkey", "Forta123")
+	r.NoError(err)
+	otherKey, err := crypto.GenerateKey()
+	r.NoError(err)
+	srv := httptest.NewServer(NewRemoteSignerHandler(&forgingSigner{Signer: NewKeySigner(key), key: otherKey}, ""))
+	defer srv.Close()
+
+	rs, err := NewRemoteSigner(context.Background(), RemoteSignerConfig{URL: srv.URL})
+	r.NoError(err)
+	_, err = SignStringWithSigner(context.Background(), rs, ref)
+	r.ErrorIs(err, ErrInvalidSignature)
+}
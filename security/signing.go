@@ -2,6 +2,7 @@ package security
 
 import (
 	"bufio"
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -86,8 +87,13 @@ func alertHash(alert *protocol.Alert) common.Hash {
 
 // SignAlert signs the alert using the alertID and deterministicly formatted Metadata
 func SignAlert(key *keystore.Key, alert *protocol.Alert) (*protocol.SignedAlert, error) {
+	return SignAlertWithSigner(context.Background(), NewKeySigner(key), alert)
+}
+
+// SignAlertWithSigner signs the alert like SignAlert by using the signer.
+func SignAlertWithSigner(ctx context.Context, signer Signer, alert *protocol.Alert) (*protocol.SignedAlert, error) {
 	hash := alertHash(alert)
-	signature, err := SignBytes(key, hash.Bytes())
+	signature, err := SignBytesWithSigner(ctx, signer, hash.Bytes())
 	if err != nil {
 		return nil, err
 	}
@@ -109,8 +115,13 @@ func VerifyAlertSignature(sa *protocol.SignedAlert) error {
 }
 
 func SignBytes(key *keystore.Key, b []byte) (*protocol.Signature, error) {
+	return SignBytesWithSigner(context.Background(), NewKeySigner(key), b)
+}
+
+// SignBytesWithSigner signs the hash of the bytes by using the signer.
+func SignBytesWithSigner(ctx context.Context, signer Signer, b []byte) (*protocol.Signature, error) {
 	hash := crypto.Keccak256(b)
-	sig, err := crypto.Sign(hash, key.PrivateKey)
+	sig, err := signer.SignHash(ctx, hash)
 
 	if err != nil {
 		return nil, err
@@ -119,7 +130,7 @@ func SignBytes(key *keystore.Key, b []byte) (*protocol.Signature, error) {
 	return &protocol.Signature{
 		Signature: fmt.Sprintf("0x%s", hex.EncodeToString(sig)),
 		Algorithm: "ECDSA",
-		Signer:    key.Address.Hex(),
+		Signer:    signer.Address().Hex(),
 	}, nil
 }
 
@@ -127,6 +138,11 @@ func SignString(key *keystore.Key, input string) (*protocol.Signature, error) {
 	return SignBytes(key, []byte(input))
 }
 
+// SignStringWithSigner signs the hash of the string by using the signer.
+func SignStringWithSigner(ctx context.Context, signer Signer, input string) (*protocol.Signature, error) {
+	return SignBytesWithSigner(ctx, signer, []byte(input))
+}
+
 func SignerAddressFromSignature(message []byte, sigHex string) (string, error) {
 	sigHex = strings.ReplaceAll(sigHex, "0x", "")
 	signature, err := hex.DecodeString(sigHex)
@@ -170,13 +186,17 @@ func VerifySignature(message []byte, signerAddress string, sigHex string) error
 	return nil
 }
 
-func signPayload(key *keystore.Key, payloadType protocol.SignedPayload_PayloadType, msg proto.Message) (*protocol.SignedPayload, error) {
-	encoded, err := encoding.EncodeGzippedProto(msg)
+func signPayload(ctx context.Context, signer Signer, payloadType protocol.SignedPayload_PayloadType, msg proto.Message) (*protocol.SignedPayload, error) {
+	return signPayloadWithCodec(ctx, signer, encoding.CodecGzip, payloadType, msg)
+}
+
+func signPayloadWithCodec(ctx context.Context, signer Signer, codecName string, payloadType protocol.SignedPayload_PayloadType, msg proto.Message) (*protocol.SignedPayload, error) {
+	encoded, err := encoding.EncodeProto(codecName, msg)
 	if err != nil {
 		return nil, err
 	}
 
-	signature, err := SignString(key, encoded)
+	signature, err := SignStringWithSigner(ctx, signer, encoded)
 	if err != nil {
 		return nil, err
 	}
@@ -191,17 +211,37 @@ func signPayload(key *keystore.Key, payloadType protocol.SignedPayload_PayloadTy
 
 // SignBatch will sign an alert batch and return a SignedAlertBatch
 func SignBatch(key *keystore.Key, payload *protocol.AlertBatch) (*protocol.SignedPayload, error) {
-	return signPayload(key, protocol.SignedPayload_BATCH, payload)
+	return SignBatchWithSigner(context.Background(), NewKeySigner(key), payload)
+}
+
+// SignBatchWithSigner will sign an alert batch by using the signer
+func SignBatchWithSigner(ctx context.Context, signer Signer, payload *protocol.AlertBatch) (*protocol.SignedPayload, error) {
+	return signPayload(ctx, signer, protocol.SignedPayload_BATCH, payload)
+}
+
+// SignBatchWithCodec will sign an alert batch which is encoded with the codec
+func SignBatchWithCodec(ctx context.Context, signer Signer, codecName string, payload *protocol.AlertBatch) (*protocol.SignedPayload, error) {
+	return signPayloadWithCodec(ctx, signer, codecName, protocol.SignedPayload_BATCH, payload)
 }
 
 // SignBatchSummary will sign an alert batch summary
 func SignBatchSummary(key *keystore.Key, payload *protocol.BatchSummary) (*protocol.SignedPayload, error) {
-	return signPayload(key, protocol.SignedPayload_BATCH_SUMMARY, payload)
+	return SignBatchSummaryWithSigner(context.Background(), NewKeySigner(key), payload)
+}
+
+// SignBatchSummaryWithSigner will sign an alert batch summary by using the signer
+func SignBatchSummaryWithSigner(ctx context.Context, signer Signer, payload *protocol.BatchSummary) (*protocol.SignedPayload, error) {
+	return signPayload(ctx, signer, protocol.SignedPayload_BATCH_SUMMARY, payload)
 }
 
 // SignBatchReceipt will sign a batch receipt
 func SignBatchReceipt(key *keystore.Key, payload *protocol.BatchReceipt) (*protocol.SignedPayload, error) {
-	return signPayload(key, protocol.SignedPayload_BATCH_RECEIPT, payload)
+	return SignBatchReceiptWithSigner(context.Background(), NewKeySigner(key), payload)
+}
+
+// SignBatchReceiptWithSigner will sign a batch receipt by using the signer
+func SignBatchReceiptWithSigner(ctx context.Context, signer Signer, payload *protocol.BatchReceipt) (*protocol.SignedPayload, error) {
+	return signPayload(ctx, signer, protocol.SignedPayload_BATCH_RECEIPT, payload)
 }
 
 // VerifySignedPayload will return an error if the signature fails to validate
@@ -0,0 +1,40 @@
+package agentharness
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/forta-network/forta-core-go/encoding"
+	"github.com/forta-network/forta-core-go/protocol"
+)
+
+// LoadFixture reads the recorded blocks from a file. The file can contain the JSON
+// of the blocks or the blocks encoded with the encoding package.
+func LoadFixture(path string) (*protocol.BlocksData, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %v", err)
+	}
+	var data protocol.BlocksData
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = protojson.Unmarshal(trimmed, &data)
+	} else {
+		err = encoding.DecodeProto(string(bytes.TrimSpace(b)), &data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fixture: %v", err)
+	}
+	return &data, nil
+}
+
+// SaveFixture writes the blocks to a file by encoding with the codec.
+func SaveFixture(path, codecName string, data *protocol.BlocksData) error {
+	encoded, err := encoding.EncodeProto(codecName, data)
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %v", err)
+	}
+	return os.WriteFile(path, []byte(encoded), 0644)
+}
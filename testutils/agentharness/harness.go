@@ -0,0 +1,320 @@
+package agentharness
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/forta-network/forta-core-go/domain"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/protocol/alerthash"
+)
+
+const defaultRequestTimeout = time.Second * 30
+
+// Config contains the harness settings.
+type Config struct {
+	// Endpoint is the gRPC address of the bot like "localhost:50051".
+	Endpoint string
+	// BotID and BotImage are used in the initialization and in the alert hashes.
+	BotID    string
+	BotImage string
+	ShardID  int32
+	// RequestTimeout is the max duration of each bot request.
+	RequestTimeout time.Duration
+	DialOptions    []grpc.DialOption
+}
+
+// Harness drives a bot through the agent gRPC service.
+type Harness struct {
+	cfg    Config
+	conn   *grpc.ClientConn
+	client protocol.AgentClient
+}
+
+// New connects to the bot endpoint. The harness should be closed after use.
+func New(ctx context.Context, cfg Config) (*Harness, error) {
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, cfg.DialOptions...)
+	conn, err := grpc.DialContext(ctx, cfg.Endpoint, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to bot: %v", err)
+	}
+	h := NewWithClient(protocol.NewAgentClient(conn), cfg)
+	h.conn = conn
+	return h, nil
+}
+
+// NewWithClient creates a harness which uses the client.
+func NewWithClient(client protocol.AgentClient, cfg Config) *Harness {
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = defaultRequestTimeout
+	}
+	return &Harness{cfg: cfg, client: client}
+}
+
+// Close closes the connection to the bot.
+func (h *Harness) Close() error {
+	if h.conn == nil {
+		return nil
+	}
+	return h.conn.Close()
+}
+
+// Initialize initializes the bot and fails if the bot responds with an error.
+func (h *Harness) Initialize(ctx context.Context) (*protocol.InitializeResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.RequestTimeout)
+	defer cancel()
+
+	resp, err := h.client.Initialize(ctx, &protocol.InitializeRequest{
+		AgentId: h.cfg.BotID,
+		ShardId: h.cfg.ShardID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize bot: %v", err)
+	}
+	if resp.Status == protocol.ResponseStatus_ERROR {
+		return resp, fmt.Errorf("bot failed to initialize: %s", strings.Join(responseErrors(resp.Status, resp.Errors), ", "))
+	}
+	return resp, nil
+}
+
+// Run sends the block and the transaction events of the recorded blocks to the bot
+// in the recorded order. The request failures are collected in the report.
+func (h *Harness) Run(ctx context.Context, data *protocol.BlocksData) (*Report, error) {
+	report := &Report{}
+	for _, bd := range data.Blocks {
+		blockEvt, err := domain.BlockEventFromBlockData(bd)
+		if err != nil {
+			return report, err
+		}
+		// use the block time so that the events are the same in every run
+		blockEvt.Timestamps.Feed = blockEvt.Timestamps.Block
+		blockNumber, err := hexutil.DecodeUint64(blockEvt.Block.Number)
+		if err != nil {
+			return report, fmt.Errorf("invalid block number: %v", err)
+		}
+
+		blockMsg, err := blockEvt.ToMessage()
+		if err != nil {
+			return report, fmt.Errorf("failed to create block event: %v", err)
+		}
+		report.Evaluations = append(report.Evaluations, h.evaluateBlock(ctx, blockNumber, blockMsg))
+
+		for i := range blockEvt.Block.Transactions {
+			tx := blockEvt.Block.Transactions[i]
+			// the recorded contract deployments have empty recipients
+			if tx.To != nil && len(*tx.To) == 0 {
+				tx.To = nil
+			}
+			txEvt := &domain.TransactionEvent{
+				BlockEvt:    blockEvt,
+				Transaction: &tx,
+				Timestamps:  blockEvt.Timestamps,
+			}
+			txMsg, err := txEvt.ToMessage()
+			if err != nil {
+				return report, fmt.Errorf("failed to create tx event: %v", err)
+			}
+			report.Evaluations = append(report.Evaluations, h.evaluateTx(ctx, blockNumber, txMsg))
+		}
+
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (h *Harness) evaluateBlock(ctx context.Context, blockNumber uint64, event *protocol.BlockEvent) *Evaluation {
+	ev := &Evaluation{
+		RequestID:   fmt.Sprintf("block-%d", blockNumber),
+		BlockNumber: blockNumber,
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.RequestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := h.client.EvaluateBlock(ctx, &protocol.EvaluateBlockRequest{
+		RequestId: ev.RequestID,
+		Event:     event,
+		ShardId:   h.cfg.ShardID,
+	})
+	ev.Latency = time.Since(start)
+	if err != nil {
+		ev.Errors = append(ev.Errors, err.Error())
+		return ev
+	}
+	ev.Errors = append(ev.Errors, responseErrors(resp.Status, resp.Errors)...)
+	for _, finding := range resp.Findings {
+		ev.Findings = append(ev.Findings, &Finding{
+			Finding: finding,
+			AlertHash: alerthash.ForBlockAlert(&alerthash.Inputs{
+				BlockEvent: event,
+				Finding:    finding,
+				BotInfo:    h.botInfo(),
+			}),
+		})
+	}
+	return ev
+}
+
+func (h *Harness) evaluateTx(ctx context.Context, blockNumber uint64, event *protocol.TransactionEvent) *Evaluation {
+	ev := &Evaluation{
+		RequestID:   fmt.Sprintf("tx-%s", event.Transaction.Hash),
+		BlockNumber: blockNumber,
+		TxHash:      event.Transaction.Hash,
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.cfg.RequestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := h.client.EvaluateTx(ctx, &protocol.EvaluateTxRequest{
+		RequestId: ev.RequestID,
+		Event:     event,
+		ShardId:   h.cfg.ShardID,
+	})
+	ev.Latency = time.Since(start)
+	if err != nil {
+		ev.Errors = append(ev.Errors, err.Error())
+		return ev
+	}
+	ev.Errors = append(ev.Errors, responseErrors(resp.Status, resp.Errors)...)
+	for _, finding := range resp.Findings {
+		ev.Findings = append(ev.Findings, &Finding{
+			Finding: finding,
+			AlertHash: alerthash.ForTransactionAlert(&alerthash.Inputs{
+				TransactionEvent: event,
+				Finding:          finding,
+				BotInfo:          h.botInfo(),
+			}),
+		})
+	}
+	return ev
+}
+
+func (h *Harness) botInfo() alerthash.BotInfo {
+	return alerthash.BotInfo{BotImage: h.cfg.BotImage, BotID: h.cfg.BotID}
+}
+
+func responseErrors(status protocol.ResponseStatus, respErrs []*protocol.Error) []string {
+	var errs []string
+	for _, respErr := range respErrs {
+		errs = append(errs, respErr.Message)
+	}
+	if status == protocol.ResponseStatus_ERROR && len(errs) == 0 {
+		errs = append(errs, "bot responded with error status")
+	}
+	return errs
+}
+
+// Finding is a bot finding with its alert hash.
+type Finding struct {
+	*protocol.Finding
+	AlertHash string
+}
+
+// Evaluation is the result of a bot request.
+type Evaluation struct {
+	RequestID   string
+	BlockNumber uint64
+	// TxHash is empty for the block evaluations.
+	TxHash   string
+	Latency  time.Duration
+	Findings []*Finding
+	Errors   []string
+}
+
+// Report contains the evaluations of a run in the request order.
+type Report struct {
+	Evaluations []*Evaluation
+}
+
+// Findings returns all findings in the request order.
+func (r *Report) Findings() []*Finding {
+	var findings []*Finding
+	for _, ev := range r.Evaluations {
+		findings = append(findings, ev.Findings...)
+	}
+	return findings
+}
+
+// Errors returns all errors prefixed with the request IDs.
+func (r *Report) Errors() []string {
+	var errs []string
+	for _, ev := range r.Evaluations {
+		for _, err := range ev.Errors {
+			errs = append(errs, fmt.Sprintf("%s: %s", ev.RequestID, err))
+		}
+	}
+	return errs
+}
+
+// MaxLatency returns the latency of the slowest request.
+func (r *Report) MaxLatency() time.Duration {
+	var max time.Duration
+	for _, ev := range r.Evaluations {
+		if ev.Latency > max {
+			max = ev.Latency
+		}
+	}
+	return max
+}
+
+// AverageLatency returns the average request latency.
+func (r *Report) AverageLatency() time.Duration {
+	if len(r.Evaluations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, ev := range r.Evaluations {
+		total += ev.Latency
+	}
+	return total / time.Duration(len(r.Evaluations))
+}
+
+// AlertHashes returns the sorted alert hashes of the findings.
+func (r *Report) AlertHashes() []string {
+	var hashes []string
+	for _, finding := range r.Findings() {
+		hashes = append(hashes, finding.AlertHash)
+	}
+	sort.Strings(hashes)
+	return hashes
+}
+
+// CheckAlertHashes compares the alert hashes of the findings with the expected ones
+// and fails on the missing, the unexpected and the duplicate alert hashes.
+func (r *Report) CheckAlertHashes(expected ...string) error {
+	expectedSet := make(map[string]bool)
+	for _, hash := range expected {
+		expectedSet[hash] = true
+	}
+
+	var problems []string
+	seen := make(map[string]bool)
+	for _, hash := range r.AlertHashes() {
+		if seen[hash] {
+			problems = append(problems, fmt.Sprintf("duplicate alert hash %s", hash))
+			continue
+		}
+		seen[hash] = true
+		if !expectedSet[hash] {
+			problems = append(problems, fmt.Sprintf("unexpected alert hash %s", hash))
+		}
+	}
+	for _, hash := range expected {
+		if !seen[hash] {
+			problems = append(problems, fmt.Sprintf("missing alert hash %s", hash))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("alert hash check failed: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package agentharness
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/forta-network/forta-core-go/encoding"
+	"github.com/forta-network/forta-core-go/protocol"
+	"github.com/forta-network/forta-core-go/protocol/alerthash"
+)
+
+const (
+	testBotID    = "0xbot"
+	testBotImage = "bafybot"
+	testBadTx    = "0x03"
+
+	testTransferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	testAddressTopic  = "0x00000000000000000000000000000000000000000000000000000000000000aa"
+)
+
+type testBot struct {
+	protocol.UnimplementedAgentServer
+	initialized bool
+	txEvents    []*protocol.TransactionEvent
+	mu          sync.Mutex
+}
+
+func (bot *testBot) Initialize(ctx context.Context, req *protocol.InitializeRequest) (*protocol.InitializeResponse, error) {
+	bot.initialized = req.AgentId == testBotID
+	return &protocol.InitializeResponse{Status: protocol.ResponseStatus_SUCCESS}, nil
+}
+
+func (bot *testBot) EvaluateBlock(ctx context.Context, req *protocol.EvaluateBlockRequest) (*protocol.EvaluateBlockResponse, error) {
+	return &protocol.EvaluateBlockResponse{
+		Status:   protocol.ResponseStatus_SUCCESS,
+		Findings: []*protocol.Finding{{AlertId: "BLOCK", Name: req.Event.BlockHash}},
+	}, nil
+}
+
+func (bot *testBot) EvaluateTx(ctx context.Context, req *protocol.EvaluateTxRequest) (*protocol.EvaluateTxResponse, error) {
+	bot.mu.Lock()
+	bot.txEvents = append(bot.txEvents, req.Event)
+	bot.mu.Unlock()
+
+	if req.Event.Transaction.Hash == testBadTx {
+		return &protocol.EvaluateTxResponse{
+			Status: protocol.ResponseStatus_ERROR,
+			Errors: []*protocol.Error{{Message: "bad tx"}},
+		}, nil
+	}
+	resp := &protocol.EvaluateTxResponse{Status: protocol.ResponseStatus_SUCCESS}
+	if len(req.Event.Logs) > 0 {
+		resp.Findings = append(resp.Findings, &protocol.Finding{AlertId: "LOG", Name: req.Event.Transaction.Hash})
+	}
+	return resp, nil
+}
+
+func startTestBot(t *testing.T) (*testBot, string) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	bot := &testBot{}
+	server := grpc.NewServer()
+	protocol.RegisterAgentServer(server, bot)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return bot, lis.Addr().String()
+}
+
+func testBlocksData() *protocol.BlocksData {
+	return &protocol.BlocksData{
+		Blocks: []*protocol.BlockData{
+			{
+				ChainID: 1,
+				Block: &protocol.BlockWithTransactions{
+					Hash:      "0xb1",
+					Number:    "0x1",
+					Timestamp: "0x5",
+					Transactions: []*protocol.Transaction{
+						{Hash: "0x01", From: "0xf1", To: "0xt1", Nonce: "0x0"},
+						{Hash: "0x02", From: "0xf2", To: "0xt2", Nonce: "0x0"},
+					},
+				},
+				Logs: []*protocol.LogEntry{
+					{
+						Address:         "0xT1",
+						Topics:          []string{testTransferTopic, testAddressTopic},
+						TransactionHash: "0x01",
+						BlockNumber:     "0x1",
+						LogIndex:        "0x0",
+					},
+				},
+			},
+			{
+				ChainID: 1,
+				Block: &protocol.BlockWithTransactions{
+					Hash:      "0xb2",
+					Number:    "0x2",
+					Timestamp: "0x6",
+					Transactions: []*protocol.Transaction{
+						{Hash: testBadTx, From: "0xf3", To: "0xt3", Nonce: "0x0"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestFixture(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	data := testBlocksData()
+
+	encodedPath := filepath.Join(dir, "blocks.dat")
+	r.NoError(SaveFixture(encodedPath, encoding.CodecZstd, data))
+	loaded, err := LoadFixture(encodedPath)
+	r.NoError(err)
+	r.Len(loaded.Blocks, 2)
+	r.Equal("0xb2", loaded.Blocks[1].Block.Hash)
+
+	b, err := protojson.Marshal(data)
+	r.NoError(err)
+	jsonPath := filepath.Join(dir, "blocks.json")
+	r.NoError(os.WriteFile(jsonPath, b, 0644))
+	loaded, err = LoadFixture(jsonPath)
+	r.NoError(err)
+	r.Len(loaded.Blocks[0].Block.Transactions, 2)
+}
+
+func TestHarness_Run(t *testing.T) {
+	r := require.New(t)
+
+	bot, endpoint := startTestBot(t)
+	ctx := context.Background()
+	h, err := New(ctx, Config{Endpoint: endpoint, BotID: testBotID, BotImage: testBotImage})
+	r.NoError(err)
+	defer h.Close()
+
+	_, err = h.Initialize(ctx)
+	r.NoError(err)
+	r.True(bot.initialized)
+
+	report, err := h.Run(ctx, testBlocksData())
+	r.NoError(err)
+	r.Len(report.Evaluations, 5)
+	r.Equal("block-1", report.Evaluations[0].RequestID)
+	r.Equal("tx-0x01", report.Evaluations[1].RequestID)
+	r.Equal(uint64(2), report.Evaluations[4].BlockNumber)
+	r.Equal(testBadTx, report.Evaluations[4].TxHash)
+
+	// the bot receives the recorded logs
+	r.Len(bot.txEvents, 3)
+	txEvt := bot.txEvents[0]
+	r.Equal("0x01", txEvt.Transaction.Hash)
+	r.Len(txEvt.Logs, 1)
+	r.Equal("0xt1", txEvt.Logs[0].Address)
+	r.Equal([]string{testTransferTopic, testAddressTopic}, txEvt.Logs[0].Topics)
+	r.Empty(bot.txEvents[1].Logs)
+
+	botInfo := alerthash.BotInfo{BotImage: testBotImage, BotID: testBotID}
+	expectedHashes := []string{
+		alerthash.ForBlockAlert(&alerthash.Inputs{
+			BlockEvent: &protocol.BlockEvent{BlockHash: "0xb1", Network: &protocol.BlockEvent_Network{ChainId: "0x1"}},
+			Finding:    &protocol.Finding{AlertId: "BLOCK", Name: "0xb1"},
+			BotInfo:    botInfo,
+		}),
+		alerthash.ForTransactionAlert(&alerthash.Inputs{
+			TransactionEvent: &protocol.TransactionEvent{
+				Transaction: &protocol.TransactionEvent_EthTransaction{Hash: "0x01"},
+				Network:     &protocol.TransactionEvent_Network{ChainId: "0x1"},
+				TxAddresses: map[string]bool{
+					"0xf1": true,
+					"0xt1": true,
+					"0x00000000000000000000000000000000000000aa": true,
+				},
+			},
+			Finding: &protocol.Finding{AlertId: "LOG", Name: "0x01"},
+			BotInfo: botInfo,
+		}),
+		alerthash.ForBlockAlert(&alerthash.Inputs{
+			BlockEvent: &protocol.BlockEvent{BlockHash: "0xb2", Network: &protocol.BlockEvent_Network{ChainId: "0x1"}},
+			Finding:    &protocol.Finding{AlertId: "BLOCK", Name: "0xb2"},
+			BotInfo:    botInfo,
+		}),
+	}
+
+	findings := report.Findings()
+	r.Len(findings, 3)
+	for i, finding := range findings {
+		r.Equal(expectedHashes[i], finding.AlertHash)
+	}
+	r.Equal("0xb1", findings[0].Name)
+	r.Equal("0x01", findings[1].Name)
+	r.Equal("0xb2", findings[2].Name)
+	r.Equal([]string{"tx-0x03: bad tx"}, report.Errors())
+	r.GreaterOrEqual(report.MaxLatency(), report.AverageLatency())
+	r.NoError(report.CheckAlertHashes(expectedHashes...))
+
+	// the alert hashes are the same in every run
+	hashes := report.AlertHashes()
+	r.Len(hashes, 3)
+	report, err = h.Run(ctx, testBlocksData())
+	r.NoError(err)
+	r.NoError(report.CheckAlertHashes(hashes...))
+	r.Error(report.CheckAlertHashes(hashes[1:]...))
+	r.Error(report.CheckAlertHashes(append(hashes, "0x1234")...))
+}
@@ -0,0 +1,15 @@
+package testsigner
+
+import (
+	"net/http/httptest"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+
+	"github.com/forta-network/forta-core-go/security"
+)
+
+// NewServer starts a local remote signer which signs with given key. The server
+// should be closed after use.
+func NewServer(key *keystore.Key, authToken string) *httptest.Server {
+	return httptest.NewServer(security.NewRemoteSignerHandler(security.NewKeySigner(key), authToken))
+}